@@ -1,30 +1,56 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/spf13/cobra"
+	"github.com/storm-blue/rubick/pkg/common"
 	"github.com/storm-blue/rubick/pkg/config"
 	"github.com/storm-blue/rubick/pkg/engine/scripts"
 	"github.com/storm-blue/rubick/pkg/modifier/action"
+	"github.com/storm-blue/rubick/pkg/modifier/conditions"
 	"github.com/storm-blue/rubick/pkg/modifier/objects"
+	"github.com/storm-blue/rubick/pkg/source"
 	"github.com/storm-blue/rubick/pkg/utils"
 	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"syscall"
 	"time"
 )
 
 const (
 	TimeFormat = "20060102-150405"
+
+	onErrorUsage = "条件计算出错时的处理方式: abort(不执行该动作并输出错误), false(视为false), log(视为false并输出错误)"
 )
 
 var (
 	kubeconfig       *string
+	exportContexts   *[]string
 	namespaces       *[]string
 	resource         *string
+	exportAll        *bool
+	includes         *[]string
+	excludes         *[]string
+	clusterScoped    *bool
+	exportWorkers    *int
+	exportQPS        *float32
 	yamlFile         *string
 	scriptsFile      *string
 	exportOutputFile *string
 	modifyOutputFile *string
+	skipInvalid      *bool
+	keepEmpty        *bool
+	modifyOnError    *string
 	execOutputFile   *string
+	execFrom         *string
+	execSkipInvalid  *bool
+	execContext      *string
+	execWorkers      *int
+	execQPS          *float32
+	execOnError      *string
 	configFile       *string
 
 	rootCmd = &cobra.Command{
@@ -50,22 +76,74 @@ Rubick to export and clean up the corresponding resources.
 	exportCmd = &cobra.Command{
 		Use:   "export",
 		Short: "导出k8s资源",
-		Long:  `将指定的资源从目标k8s集群中导出到当前目录`,
+		Long: `将指定的资源从目标k8s集群中导出到当前目录，
+指定多个--context时会从多个集群导出，每个资源的__cluster字段为其所在集群的context。
+使用--all时会通过API discovery导出所有可以list的资源，默认排除: events, endpoints,
+endpointslices.discovery.k8s.io, metrics.k8s.io，可以通过--include和--exclude调整。
+资源会通过--workers个并发请求获取，每个集群的请求速率不超过--qps，导出文件中资源的顺序是固定的，
+按Ctrl-C可以取消导出`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if *exportAll == (*resource != "") {
+				return fmt.Errorf("either --resource or --all is required")
+			}
 			fmt.Println("processing...")
 
-			resources, err := utils.GetResources(*kubeconfig, *namespaces, *resource)
+			contexts := *exportContexts
+			if len(contexts) == 0 {
+				contexts = []string{""}
+			}
+
+			// a task gets resources of a resource type from a cluster
+			type task struct {
+				source       utils.ResourceSource
+				kubeContext  string
+				resourceType string
+			}
+			var tasks []task
+			// requests to all clusters are limited by --workers together
+			requests := common.NewSemaphore(*exportWorkers)
+			for _, kubeContext := range contexts {
+				resourceSource, err := utils.NewResourceSource(utils.ClusterOptions{
+					Kubeconfig: *kubeconfig, Context: kubeContext, QPS: *exportQPS, Workers: *exportWorkers, Requests: requests,
+				})
+				if err != nil {
+					return err
+				}
+				resourceTypes := []string{*resource}
+				if *exportAll {
+					resourceTypes, err = source.DiscoverResourceTypes(cmd.Context(), resourceSource, source.DiscoveryOptions{
+						Include: *includes, Exclude: *excludes, ClusterScoped: *clusterScoped,
+					})
+					if err != nil {
+						return err
+					}
+				}
+
+				for _, resourceType := range resourceTypes {
+					tasks = append(tasks, task{source: resourceSource, kubeContext: kubeContext, resourceType: resourceType})
+				}
+			}
+
+			results, err := common.Parallel(cmd.Context(), *exportWorkers, len(tasks), func(ctx context.Context, i int) ([]objects.StructuredObject, error) {
+				resources, err := tasks[i].source.GetResources(ctx, *namespaces, tasks[i].resourceType)
+				if err != nil {
+					return nil, err
+				}
+				return resources, source.TagCluster(resources, tasks[i].kubeContext)
+			})
 			if err != nil {
 				return err
 			}
 
-			yamls, err := objects.ToYAMLs(resources)
+			yamls, err := objects.ToYAMLs(slices.Concat(results...))
 			if err != nil {
 				return err
 			}
 
 			outputFileName := *exportOutputFile
-			if outputFileName == "" {
+			if outputFileName == "" && *exportAll {
+				outputFileName = fmt.Sprintf("all-exported-%v.yaml", time.Now().Format(TimeFormat))
+			} else if outputFileName == "" {
 				outputFileName = fmt.Sprintf("%vs-exported-%v.yaml", *resource, time.Now().Format(TimeFormat))
 			}
 
@@ -85,20 +163,40 @@ Rubick to export and clean up the corresponding resources.
 	modifyCmd = &cobra.Command{
 		Use:   "modify",
 		Short: "修改YAML文件",
-		Long:  `通过自定义清洗规则脚本，对指定的YAML文件进行修改`,
+		Long: `通过自定义清洗规则脚本，对指定的YAML文件进行修改，
+文件可以是YAML/JSON文件、目录（递归读取所有*.yaml、*.yml、*.json文件）、zip/tar压缩包，或者"-"表示标准输入`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			actionContext, err := newActionContext(*modifyOnError)
+			if err != nil {
+				return err
+			}
 			fmt.Println("processing...")
 
-			yamlBytes, err := os.ReadFile(*yamlFile)
+			scriptsBytes, err := os.ReadFile(*scriptsFile)
 			if err != nil {
 				return err
 			}
-			scriptsBytes, err := os.ReadFile(*scriptsFile)
+
+			fileSource, err := source.Open(*yamlFile, objects.YAMLsOptions{SkipInvalid: *skipInvalid, KeepEmpty: *keepEmpty})
+			if err != nil {
+				return err
+			}
+			for _, documentError := range fileSource.Skipped {
+				fmt.Printf("skip invalid %v\n", documentError)
+			}
+
+			_objects, err := fileSource.GetResources(cmd.Context(), "")
 			if err != nil {
 				return err
 			}
 
-			yaml, err := scripts.ExecYAMLs(action.NewContext(nil), string(yamlBytes), string(scriptsBytes))
+			__objects, err := scripts.ExecObjects(actionContext, _objects, string(scriptsBytes))
+			if err != nil {
+				return err
+			}
+			printActionErrors(actionContext)
+
+			yaml, err := objects.ToYAMLs(__objects)
 			if err != nil {
 				return err
 			}
@@ -125,14 +223,30 @@ Rubick to export and clean up the corresponding resources.
 		Use:   "exec",
 		Short: "根据配置处理资源并导出",
 		Long: `通过自定义配置文件，选择想要导出的资源，并且执行配置中的脚本进行清洗。
+默认从k8s集群中获取资源，使用--from可以从之前导出的文件、目录、zip/tar压缩包或标准输入("-")中获取资源。
+配置[__cluster__ name]时会从所有配置的集群中获取资源，每个资源的__cluster字段为其所在集群的名称，
+脚本中可以通过VALUE_OF(__cluster)区分集群。
+资源会通过--workers个并发请求获取，每个集群的请求速率不超过--qps，输出文件中资源的顺序是固定的，
+按Ctrl-C可以取消执行。
 config example:
 
 [__kubeconfig__]
 ${HOME}/.kube/config
 
+# or named clusters
+# [__cluster__ prod]
+# kubeconfig = ${HOME}/.kube/config
+# context = prod
+# namespace = default
+
 [deployment]
 */redis
 
+# all resource types discovered from cluster
+# [*]
+# exclude = events, endpoints, metrics.k8s.io
+# java-dev/*
+
 [service]
 java-dev/*
 java-qa1/*
@@ -155,6 +269,10 @@ IF (VALUE_OF(kind)=="Service" && EXISTS(metadata.labels.(github.io/app))) THEN S
 IF NOT_EXISTS(metadata.labels.(github.io/app)) THEN SET_WITH_VALUE_OF(metadata.labels.(github.io/app), metadata.name)
 `,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			actionContext, err := newActionContext(*execOnError)
+			if err != nil {
+				return err
+			}
 			fmt.Println("processing...")
 
 			configBytes, err := os.ReadFile(*configFile)
@@ -167,30 +285,108 @@ IF NOT_EXISTS(metadata.labels.(github.io/app)) THEN SET_WITH_VALUE_OF(metadata.l
 				outputFileName = fmt.Sprintf("executed-%v.yaml", time.Now().Format(TimeFormat))
 			}
 
-			kubeconfig, _scripts, resources, err := config.ParseConfig(string(configBytes))
+			c, err := config.Parse(string(configBytes))
 			if err != nil {
 				return err
 			}
 
-			var _objects []objects.StructuredObject
-
-			for resource, matcher := range resources {
-				__objects, err := utils.GetResources(kubeconfig, nil, resource)
+			var sources []source.Source
+			// requests to all clusters are limited by --workers together
+			requests := common.NewSemaphore(*execWorkers)
+			switch {
+			case *execFrom != "":
+				fileSource, err := source.Open(*execFrom, objects.YAMLsOptions{SkipInvalid: *execSkipInvalid})
+				if err != nil {
+					return err
+				}
+				for _, documentError := range fileSource.Skipped {
+					fmt.Printf("skip invalid %v\n", documentError)
+				}
+				sources = append(sources, fileSource)
+			case len(c.Clusters) > 0:
+				if *execContext != "" {
+					return fmt.Errorf("--context can not be used with [%s] sections", config.ClusterHead)
+				}
+				for _, cluster := range c.Clusters {
+					clusterSource, err := source.NewClusterSource(cluster.Name, utils.ClusterOptions{
+						Kubeconfig: cluster.Kubeconfig, Context: cluster.Context, Namespace: cluster.Namespace,
+						QPS: *execQPS, Workers: *execWorkers, Requests: requests,
+					})
+					if err != nil {
+						return err
+					}
+					sources = append(sources, clusterSource)
+				}
+			default:
+				clusterSource, err := source.NewClusterSource(*execContext, utils.ClusterOptions{
+					Kubeconfig: c.Kubeconfig, Context: *execContext, QPS: *execQPS, Workers: *execWorkers, Requests: requests,
+				})
 				if err != nil {
 					return err
 				}
+				sources = append(sources, clusterSource)
+			}
 
+			// resource types are sorted for a stable output, [*] is always the last of a source
+			var resourceTypes []string
+			for resource := range c.Resources {
+				if resource != config.AllResourcesHead {
+					resourceTypes = append(resourceTypes, resource)
+				}
+			}
+			sort.Strings(resourceTypes)
+			if c.AllResources != nil {
+				resourceTypes = append(resourceTypes, config.AllResourcesHead)
+			}
+
+			// a task gets resources of a resource type from a source, results are matched in the order of tasks
+			type task struct {
+				source       source.Source
+				resourceType string
+			}
+			var tasks []task
+			for _, resourceSource := range sources {
+				for _, resourceType := range resourceTypes {
+					tasks = append(tasks, task{source: resourceSource, resourceType: resourceType})
+				}
+			}
+
+			results, err := common.Parallel(cmd.Context(), *execWorkers, len(tasks), func(ctx context.Context, i int) ([]objects.StructuredObject, error) {
+				if tasks[i].resourceType == config.AllResourcesHead {
+					return tasks[i].source.GetAllResources(ctx, source.DiscoveryOptions{
+						Include: c.AllResources.Include, Exclude: c.AllResources.Exclude, ClusterScoped: c.AllResources.ClusterScoped,
+					})
+				}
+				return tasks[i].source.GetResources(ctx, tasks[i].resourceType)
+			})
+			if err != nil {
+				return err
+			}
+
+			var _objects []objects.StructuredObject
+			// resources of [*] which are got by other sections are skipped
+			got := map[string]bool{}
+
+			for i, __objects := range results {
+				allResources := tasks[i].resourceType == config.AllResourcesHead
+				matcher := c.Resources[tasks[i].resourceType]
 				for _, __object := range __objects {
+					key := source.ResourceKey(__object)
+					if allResources && got[key] {
+						continue
+					}
 					if matcher.Match(__object) {
 						_objects = append(_objects, __object)
+						got[key] = true
 					}
 				}
 			}
 
-			__objects, err := scripts.ExecObjects(action.NewContext(nil), _objects, _scripts)
+			__objects, err := scripts.ExecObjects(actionContext, _objects, c.Scripts)
 			if err != nil {
 				return err
 			}
+			printActionErrors(actionContext)
 
 			yaml := ""
 
@@ -215,26 +411,55 @@ IF NOT_EXISTS(metadata.labels.(github.io/app)) THEN SET_WITH_VALUE_OF(metadata.l
 	}
 )
 
-// Execute executes the root command.
-func Execute() error {
-	return rootCmd.Execute()
+// logKeys are the keys of objects printed with the errors of actions
+var logKeys = []string{"kind", "metadata.namespace", "metadata.name"}
+
+// newActionContext create the context of scripts, onError is the error policy of conditions
+func newActionContext(onError string) (action.Context, error) {
+	policy, err := conditions.ParseErrorPolicy(onError)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --on-error: %w", err)
+	}
+	return action.NewContextWithErrorPolicy(logKeys, policy), nil
+}
+
+// printActionErrors print the errors of actions logged in context
+func printActionErrors(ctx action.Context) {
+	for _, log := range ctx.Logs() {
+		if log.Err() == nil {
+			continue
+		}
+		var values []interface{}
+		for _, key := range logKeys {
+			values = append(values, log.GetKey(key))
+		}
+		fmt.Printf("error of %v %v/%v: %v, action: %v\n", append(values, log.Err(), log.Action())...)
+	}
+}
+
+// Execute executes the root command, ctx is passed to the commands for cancellation.
+func Execute(ctx context.Context) error {
+	return rootCmd.ExecuteContext(ctx)
 }
 
 func init() {
 	// exporter
 	kubeconfig = exportCmd.Flags().String("kubeconfig", "", "导出的目标集群连接配置, 默认值为: ${HOME}/.kube/config")
+	exportContexts = exportCmd.Flags().StringArray("context", nil, "kubeconfig中的context, 可以指定多个, 默认为当前context")
 	namespaces = exportCmd.Flags().StringArrayP("namespace", "n", nil, "要导出资源的命名空间, 默认所有命名空间")
 	resource = exportCmd.Flags().StringP("resource", "r", "", "要导出的资源类型")
-	err := exportCmd.MarkFlagRequired("resource")
-	if err != nil {
-		panic(err)
-	}
+	exportAll = exportCmd.Flags().Bool("all", false, "导出所有可以list的资源类型")
+	includes = exportCmd.Flags().StringSlice("include", nil, "--all时要导出的资源类型或group, 如: deployments.apps,services")
+	excludes = exportCmd.Flags().StringSlice("exclude", nil, "--all时不导出的资源类型或group, 如: events,metrics.k8s.io")
+	clusterScoped = exportCmd.Flags().Bool("cluster-scoped", false, "--all时同时导出集群级别的资源, 如: namespaces, clusterroles")
+	exportWorkers = exportCmd.Flags().Int("workers", 4, "所有集群同时进行的请求数量")
+	exportQPS = exportCmd.Flags().Float32("qps", 20, "每个集群每秒最多的请求数量, 0表示使用默认值")
 	exportOutputFile = exportCmd.Flags().StringP("output", "o", "", "指定输出的文件路径")
 	rootCmd.AddCommand(exportCmd)
 
 	// modify
-	yamlFile = modifyCmd.Flags().StringP("file", "f", "", "要修改的文件路径")
-	err = modifyCmd.MarkFlagRequired("file")
+	yamlFile = modifyCmd.Flags().StringP("file", "f", "", "要修改的文件路径, 可以是文件、目录、zip/tar压缩包, \"-\"表示标准输入")
+	err := modifyCmd.MarkFlagRequired("file")
 	if err != nil {
 		panic(err)
 	}
@@ -244,6 +469,9 @@ func init() {
 		panic(err)
 	}
 	modifyOutputFile = modifyCmd.Flags().StringP("output", "o", "", "指定输出的文件路径")
+	skipInvalid = modifyCmd.Flags().Bool("skip-invalid", false, "跳过格式错误的YAML文档并输出错误信息, 默认遇到错误时退出")
+	keepEmpty = modifyCmd.Flags().Bool("keep-empty", false, "保留空文档和只有注释的文档")
+	modifyOnError = modifyCmd.Flags().String("on-error", string(conditions.ErrorPolicyAbort), onErrorUsage)
 	rootCmd.AddCommand(modifyCmd)
 
 	// exec
@@ -253,9 +481,18 @@ func init() {
 		panic(err)
 	}
 	execOutputFile = execCmd.Flags().StringP("output", "o", "", "指定输出的文件路径")
+	execContext = execCmd.Flags().String("context", "", "[__kubeconfig__]中的context, 默认为当前context")
+	execFrom = execCmd.Flags().String("from", "", "从文件、目录、zip/tar压缩包或标准输入(\"-\")中获取资源, 默认从k8s集群中获取")
+	execSkipInvalid = execCmd.Flags().Bool("skip-invalid", false, "使用--from时跳过格式错误的YAML文档并输出错误信息, 默认遇到错误时退出")
+	execWorkers = execCmd.Flags().Int("workers", 4, "所有集群同时进行的请求数量")
+	execQPS = execCmd.Flags().Float32("qps", 20, "每个集群每秒最多的请求数量, 0表示使用默认值")
+	execOnError = execCmd.Flags().String("on-error", string(conditions.ErrorPolicyAbort), onErrorUsage)
 	rootCmd.AddCommand(execCmd)
 }
 
 func main() {
-	_ = Execute()
+	// Ctrl-C cancels the requests to clusters
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	_ = Execute(ctx)
 }
@@ -0,0 +1,91 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Parallel call do for index 0 to n-1 by at most workers goroutines, results are returned in the order of
+// index regardless of the completion order. The context passed to do is canceled on the first error, and
+// the error is returned.
+func Parallel[T any](ctx context.Context, workers, n int, do func(ctx context.Context, i int) (T, error)) ([]T, error) {
+	if workers <= 0 {
+		workers = 1
+	}
+	doCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]T, n)
+	errs := make([]error, n)
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < min(workers, n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if results[i], errs[i] = do(doCtx, i); errs[i] != nil {
+					cancel()
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := 0; i < n; i++ {
+		select {
+		case indexes <- i:
+		case <-doCtx.Done():
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	// errors caused by canceling the other tasks are not the cause
+	var canceled error
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+		if canceled == nil {
+			canceled = err
+		}
+	}
+	if canceled != nil {
+		return nil, canceled
+	}
+	return results, nil
+}
+
+// Semaphore limits the number of concurrent operations, such as requests to clusters. A Semaphore can be shared by
+// nested Parallel calls, since only the operations hold it, not the tasks waiting for their sub tasks.
+type Semaphore struct {
+	slots chan struct{}
+}
+
+// NewSemaphore create a semaphore of n slots, at least 1.
+func NewSemaphore(n int) *Semaphore {
+	return &Semaphore{slots: make(chan struct{}, max(1, n))}
+}
+
+// Do call f after a slot is acquired, the slot is released when f returns. A nil Semaphore has no limit.
+func (s *Semaphore) Do(ctx context.Context, f func() error) error {
+	if s == nil {
+		return f()
+	}
+	select {
+	case s.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-s.slots }()
+	return f()
+}
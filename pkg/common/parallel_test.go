@@ -0,0 +1,132 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParallel(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers int
+		n       int
+		failAt  int
+		want    []int
+		wantErr bool
+	}{
+		{
+			name:    "TEST1",
+			workers: 3,
+			n:       10,
+			failAt:  -1,
+			want:    []int{0, 1, 4, 9, 16, 25, 36, 49, 64, 81},
+		},
+		{
+			name:    "TEST_NO_WORKERS",
+			workers: 0,
+			n:       3,
+			failAt:  -1,
+			want:    []int{0, 1, 4},
+		},
+		{
+			name:    "TEST_EMPTY",
+			workers: 3,
+			n:       0,
+			failAt:  -1,
+			want:    []int{},
+		},
+		{
+			name:    "TEST_ERROR",
+			workers: 3,
+			n:       10,
+			failAt:  5,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parallel(context.Background(), tt.workers, tt.n, func(ctx context.Context, i int) (int, error) {
+				if i == tt.failAt {
+					return 0, errors.New("failed")
+				}
+				// later tasks complete first
+				select {
+				case <-time.After(time.Duration(tt.n-i) * time.Millisecond):
+				case <-ctx.Done():
+					return 0, ctx.Err()
+				}
+				return i * i, nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parallel() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				if err.Error() != "failed" {
+					t.Errorf("Parallel() error = %v, want failed", err)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parallel() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParallel_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var started atomic.Int32
+	_, err := Parallel(ctx, 2, 100, func(ctx context.Context, i int) (int, error) {
+		if started.Add(1) == 2 {
+			cancel()
+		}
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Parallel() error = %v, want context canceled", err)
+	}
+	if n := started.Load(); n > 4 {
+		t.Errorf("Parallel() started %v tasks after canceled", n)
+	}
+}
+
+func TestSemaphore(t *testing.T) {
+	semaphore := NewSemaphore(2)
+	var running, maxRunning atomic.Int32
+	// nested tasks share the semaphore, only the operations of leaves hold it
+	_, err := Parallel(context.Background(), 4, 4, func(ctx context.Context, i int) ([]int, error) {
+		return Parallel(ctx, 4, 4, func(ctx context.Context, j int) (int, error) {
+			return j, semaphore.Do(ctx, func() error {
+				n := running.Add(1)
+				for m := maxRunning.Load(); n > m && !maxRunning.CompareAndSwap(m, n); m = maxRunning.Load() {
+				}
+				time.Sleep(time.Millisecond)
+				running.Add(-1)
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		t.Fatalf("Parallel() error = %v", err)
+	}
+	if n := maxRunning.Load(); n != 2 {
+		t.Errorf("Semaphore max running = %v, want 2", n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	full := NewSemaphore(1)
+	full.slots <- struct{}{}
+	if err := full.Do(ctx, func() error { return nil }); !errors.Is(err, context.Canceled) {
+		t.Errorf("Do() error = %v, want %v", err, context.Canceled)
+	}
+	if err := (*Semaphore)(nil).Do(ctx, func() error { return nil }); err != nil {
+		t.Errorf("Do() of nil semaphore error = %v", err)
+	}
+}
@@ -6,18 +6,75 @@ import (
 	"github.com/storm-blue/rubick/pkg/engine/scripts"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
 const (
 	KubeconfigHead = "__kubeconfig__"
 	ScriptsHead    = "__scripts__"
+	// ClusterHead is the head of named cluster like: [__cluster__ prod]
+	ClusterHead = "__cluster__"
+	// AllResourcesHead is the head of all resource types which are discovered from cluster
+	AllResourcesHead = "*"
 )
 
+// Config is the config of exec.
+type Config struct {
+	// Kubeconfig is the kubeconfig of the default cluster, which is used when there are no named clusters
+	Kubeconfig string
+	// Clusters are the named clusters, resources are got from all of them
+	Clusters []Cluster
+	Scripts  string
+	// Resources are the matchers of resource types, the matcher of [*] is keyed by AllResourcesHead
+	Resources map[string]match.Matcher
+	// AllResources are the options of [*], it is nil if there is no [*]
+	AllResources *AllResources
+}
+
+// AllResources are the options of [*] like:
+//
+//	[*]
+//	include = apps, services
+//	exclude = events, metrics.k8s.io
+//	cluster-scoped = true
+//	java-dev/*
+//
+// all resources are matched if there are no resource expressions.
+type AllResources struct {
+	Include       []string
+	Exclude       []string
+	ClusterScoped bool
+}
+
+// Cluster is a named cluster like:
+//
+//	[__cluster__ prod]
+//	kubeconfig = /root/.kube/config
+//	context = prod
+//	namespace = default
+type Cluster struct {
+	Name       string
+	Kubeconfig string
+	Context    string
+	// Namespace is the namespace to get resources from, default: all namespaces
+	Namespace string
+}
+
 func ParseConfig(config string) (kubeconfig string, _scripts string, resourceMatchers map[string]match.Matcher, err error) {
+	c, err := Parse(config)
+	if err != nil {
+		return "", "", nil, err
+	}
+	return c.Kubeconfig, c.Scripts, c.Resources, nil
+}
+
+func Parse(config string) (*Config, error) {
 	lines := strings.Split(config, "\n")
 
+	c := &Config{}
 	var head string
+	var cluster *Cluster
 	resources := map[string][]string{}
 	for _, line := range lines {
 		line = strings.Trim(line, " ")
@@ -34,9 +91,25 @@ func ParseConfig(config string) (kubeconfig string, _scripts string, resourceMat
 
 		if isHead(line) {
 			head = parseHead(line)
-			if head != KubeconfigHead && head != ScriptsHead {
+			if name, ok := parseClusterHead(head); ok {
+				if !isValidClusterName(name) {
+					return nil, fmt.Errorf("invalid cluster name: %s", name)
+				}
+				for _, other := range c.Clusters {
+					if other.Name == name {
+						return nil, fmt.Errorf("duplicated cluster: %s", name)
+					}
+				}
+				c.Clusters = append(c.Clusters, Cluster{Name: name})
+				cluster = &c.Clusters[len(c.Clusters)-1]
+				head = ClusterHead
+			} else if head == AllResourcesHead {
+				if c.AllResources == nil {
+					c.AllResources = &AllResources{}
+				}
+			} else if head != KubeconfigHead && head != ScriptsHead {
 				if !isValidResourceTypeString(head) {
-					return "", "", nil, fmt.Errorf("invalid resource type: %s", head)
+					return nil, fmt.Errorf("invalid resource type: %s", head)
 				}
 			}
 			continue
@@ -44,32 +117,45 @@ func ParseConfig(config string) (kubeconfig string, _scripts string, resourceMat
 
 		if head == KubeconfigHead {
 			if !isValidPath(line) {
-				return "", "", nil, fmt.Errorf("invalid kubeconfig path: %s", kubeconfig)
+				return nil, fmt.Errorf("invalid kubeconfig path: %s", line)
+			}
+			c.Kubeconfig = os.ExpandEnv(line)
+		} else if head == ClusterHead {
+			if err := parseClusterLine(cluster, line); err != nil {
+				return nil, fmt.Errorf("invalid cluster %s: %v", cluster.Name, err)
+			}
+		} else if head == AllResourcesHead && strings.Contains(line, "=") {
+			if err := parseAllResourcesLine(c.AllResources, line); err != nil {
+				return nil, fmt.Errorf("invalid [%s]: %v", AllResourcesHead, err)
 			}
-			kubeconfig = line
 		} else if head == ScriptsHead {
-			if _scripts == "" {
-				_scripts = line
+			if c.Scripts == "" {
+				c.Scripts = line
 			} else {
-				_scripts = _scripts + "\n" + line
+				c.Scripts = c.Scripts + "\n" + line
 			}
 		} else {
 			if !isValidResourceExpression(line) {
-				return "", "", nil, fmt.Errorf("invalid resource expression: %s", line)
+				return nil, fmt.Errorf("invalid resource expression: %s", line)
 			}
 			resources[head] = append(resources[head], line)
 		}
 	}
 
-	if err = scripts.ValidateScripts(_scripts); err != nil {
-		return "", "", nil, fmt.Errorf("validate scripts failed: %v", err)
+	if err := scripts.ValidateScripts(c.Scripts); err != nil {
+		return nil, fmt.Errorf("validate scripts failed: %v", err)
 	}
 
-	if resourceMatchers, err = buildMatchers(resources); err != nil {
-		return "", "", nil, fmt.Errorf("build matcher failed: %v", err)
+	if c.AllResources != nil && len(resources[AllResourcesHead]) == 0 {
+		resources[AllResourcesHead] = []string{"*/*"}
 	}
 
-	return
+	var err error
+	if c.Resources, err = buildMatchers(resources); err != nil {
+		return nil, fmt.Errorf("build matcher failed: %v", err)
+	}
+
+	return c, nil
 }
 
 func ParseConfigFile(fileName string) (kubeconfig string, scripts string, matchers map[string]match.Matcher, err error) {
@@ -92,6 +178,78 @@ func parseHead(line string) string {
 	return strings.TrimSpace(head)
 }
 
+// parseClusterHead parse head like: __cluster__ prod
+func parseClusterHead(head string) (name string, ok bool) {
+	fields := strings.Fields(head)
+	if len(fields) == 0 || fields[0] != ClusterHead {
+		return "", false
+	}
+	if len(fields) != 2 {
+		return strings.TrimSpace(strings.TrimPrefix(head, ClusterHead)), true
+	}
+	return fields[1], true
+}
+
+var clusterNameRegex = regexp.MustCompile("^[a-zA-Z0-9_.\\-]+$")
+
+func isValidClusterName(name string) bool {
+	return clusterNameRegex.MatchString(name)
+}
+
+// parseClusterLine parse line of cluster section like: context = prod
+func parseClusterLine(cluster *Cluster, line string) error {
+	key, value, found := strings.Cut(line, "=")
+	if !found {
+		return fmt.Errorf("expect 'key = value': %s", line)
+	}
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+	switch key {
+	case "kubeconfig":
+		if !isValidPath(value) {
+			return fmt.Errorf("invalid kubeconfig path: %s", value)
+		}
+		cluster.Kubeconfig = os.ExpandEnv(value)
+	case "context":
+		cluster.Context = value
+	case "namespace":
+		cluster.Namespace = value
+	default:
+		return fmt.Errorf("unknown key: %s", key)
+	}
+	return nil
+}
+
+// parseAllResourcesLine parse option line of [*] like: exclude = events, metrics.k8s.io
+func parseAllResourcesLine(allResources *AllResources, line string) error {
+	key, value, _ := strings.Cut(line, "=")
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+	switch key {
+	case "include":
+		allResources.Include = append(allResources.Include, splitList(value)...)
+	case "exclude":
+		allResources.Exclude = append(allResources.Exclude, splitList(value)...)
+	case "cluster-scoped":
+		clusterScoped, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid cluster-scoped: %s", value)
+		}
+		allResources.ClusterScoped = clusterScoped
+	default:
+		return fmt.Errorf("unknown key: %s", key)
+	}
+	return nil
+}
+
+func splitList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
 var resourceTypeRegex = regexp.MustCompile("^[a-zA-Z0-9.\\-]+$")
 
 func isValidResourceTypeString(s string) bool {
@@ -138,6 +138,136 @@ DELETE(status)`,
 	}
 }
 
+func TestParse_Clusters(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       string
+		wantClusters []Cluster
+		wantErr      bool
+	}{
+		{
+			name: "TEST1",
+			config: `
+[__cluster__ prod]
+kubeconfig = /.kube/prod
+context = prod-admin
+namespace = default
+
+[__cluster__  dev]
+# default kubeconfig and context
+namespace=java-dev
+
+[service]
+*/*
+`,
+			wantClusters: []Cluster{
+				{Name: "prod", Kubeconfig: "/.kube/prod", Context: "prod-admin", Namespace: "default"},
+				{Name: "dev", Namespace: "java-dev"},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "TEST_NO_NAME",
+			config:  "[__cluster__]\ncontext = prod\n",
+			wantErr: true,
+		},
+		{
+			name:    "TEST_DUPLICATED",
+			config:  "[__cluster__ prod]\n[__cluster__ prod]\n",
+			wantErr: true,
+		},
+		{
+			name:    "TEST_UNKNOWN_KEY",
+			config:  "[__cluster__ prod]\nuser = admin\n",
+			wantErr: true,
+		},
+		{
+			name:    "TEST_INVALID_LINE",
+			config:  "[__cluster__ prod]\n/.kube/config\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && !reflect.DeepEqual(got.Clusters, tt.wantClusters) {
+				t.Errorf("Parse() gotClusters = %v, want %v", got.Clusters, tt.wantClusters)
+			}
+		})
+	}
+}
+
+func TestParse_AllResources(t *testing.T) {
+	tests := []struct {
+		name             string
+		config           string
+		wantAllResources *AllResources
+		wantMatcher      match.Matcher
+		wantErr          bool
+	}{
+		{
+			name: "TEST1",
+			config: `
+[*]
+include = apps, services
+exclude = events,metrics.k8s.io
+exclude = endpoints
+cluster-scoped = true
+java-dev/*
+`,
+			wantAllResources: &AllResources{
+				Include:       []string{"apps", "services"},
+				Exclude:       []string{"events", "metrics.k8s.io", "endpoints"},
+				ClusterScoped: true,
+			},
+			wantMatcher: match.NewOrMather(
+				match.NewAndMatcher(
+					match.NewStringMatcher("java-dev", "metadata.namespace"),
+					match.NewStringMatcher("*", "metadata.name"),
+				),
+			),
+		},
+		{
+			name:             "TEST_ALL",
+			config:           "[*]\n",
+			wantAllResources: &AllResources{},
+			wantMatcher: match.NewOrMather(
+				match.NewAndMatcher(
+					match.NewStringMatcher("*", "metadata.namespace"),
+					match.NewStringMatcher("*", "metadata.name"),
+				),
+			),
+		},
+		{
+			name:    "TEST_INVALID_OPTION",
+			config:  "[*]\ncluster-scoped = yes please\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(got.AllResources, tt.wantAllResources) {
+				t.Errorf("Parse() gotAllResources = %v, want %v", got.AllResources, tt.wantAllResources)
+			}
+			if !reflect.DeepEqual(got.Resources[AllResourcesHead], tt.wantMatcher) {
+				t.Errorf("Parse() gotMatcher = %v, want %v", got.Resources[AllResourcesHead], tt.wantMatcher)
+			}
+		})
+	}
+}
+
 func Test_isValidResourceTypeString(t *testing.T) {
 	tests := []struct {
 		name string
@@ -0,0 +1,179 @@
+package scripts
+
+import "fmt"
+
+// Pos is a position in scripts, line and column both start from 1.
+type Pos struct {
+	Line   int
+	Column int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("line %d, column %d", p.Line, p.Column)
+}
+
+type Node interface {
+	Position() Pos
+}
+
+// Script is the root of a parsed scripts.
+type Script struct {
+	Statements []Statement
+}
+
+type Statement interface {
+	Node
+	statement()
+}
+
+// ActionStatement like:
+// IF [ condition ] THEN [ action ]
+// [ action ]
+type ActionStatement struct {
+	Pos       Pos
+	Condition Expr // nil if statement has no condition
+	Action    *CallExpr
+}
+
+// IfStatement is a block like:
+//
+//	IF [ condition ] THEN
+//	    [ statements ]
+//	ELSE IF [ condition ] THEN
+//	    [ statements ]
+//	ELSE
+//	    [ statements ]
+//	END
+type IfStatement struct {
+	Pos      Pos
+	Branches []*Branch
+	Else     []Statement // nil if block has no ELSE branch
+}
+
+// Branch is "IF ... THEN" or "ELSE IF ... THEN" branch of IfStatement.
+type Branch struct {
+	Pos       Pos
+	Condition Expr
+	Body      []Statement
+}
+
+// LetStatement define a variable like: LET app = VALUE_OF(metadata.labels.app)
+type LetStatement struct {
+	Pos   Pos
+	Name  string
+	Value Expr
+}
+
+func (s *ActionStatement) Position() Pos { return s.Pos }
+func (s *IfStatement) Position() Pos     { return s.Pos }
+func (s *LetStatement) Position() Pos    { return s.Pos }
+
+func (s *ActionStatement) statement() {}
+func (s *IfStatement) statement()     {}
+func (s *LetStatement) statement()    {}
+
+type Expr interface {
+	Node
+	expr()
+}
+
+// CallExpr like: VALUE_OF(metadata.name), SET(metadata.name, "app")
+type CallExpr struct {
+	Pos    Pos
+	Method string
+	Args   []Expr
+}
+
+// StringLiteral like: "app", Value is unquoted and escapes are resolved.
+type StringLiteral struct {
+	Pos   Pos
+	Value string
+}
+
+// WordLiteral is an unquoted word like: metadata.labels.(github.io/app), spec.ports[0], 80, app
+type WordLiteral struct {
+	Pos   Pos
+	Value string
+}
+
+// BoolLiteral is unquoted true or false.
+type BoolLiteral struct {
+	Pos   Pos
+	Value bool
+}
+
+// NullLiteral is unquoted null.
+type NullLiteral struct {
+	Pos Pos
+}
+
+// BinaryExpr like: VALUE_OF(kind) == "Service", EXISTS(a) && EXISTS(b), VALUE_OF(spec.replicas) * 2,
+// VALUE_OF(metadata.namespace) IN ("a", "b")
+type BinaryExpr struct {
+	Pos      Pos
+	Operator string
+	Left     Expr
+	Right    Expr
+}
+
+// UnaryExpr like: !EXISTS(a), NOT (EXISTS(a) && EXISTS(b)), Operator is always "!"
+type UnaryExpr struct {
+	Pos      Pos
+	Operator string
+	Operand  Expr
+}
+
+// Variable is a reference of variable like: $app
+type Variable struct {
+	Pos  Pos
+	Name string
+}
+
+// TemplateLiteral is a string with interpolations like: "${app}-svc",
+// Parts are StringLiteral and Variable in order.
+type TemplateLiteral struct {
+	Pos   Pos
+	Parts []Expr
+}
+
+// ListExpr is a list of values like: ("a", "b"), it is the right side of IN and NOT_IN.
+type ListExpr struct {
+	Pos   Pos
+	Items []Expr
+}
+
+func (e *CallExpr) Position() Pos        { return e.Pos }
+func (e *StringLiteral) Position() Pos   { return e.Pos }
+func (e *WordLiteral) Position() Pos     { return e.Pos }
+func (e *BoolLiteral) Position() Pos     { return e.Pos }
+func (e *NullLiteral) Position() Pos     { return e.Pos }
+func (e *BinaryExpr) Position() Pos      { return e.Pos }
+func (e *UnaryExpr) Position() Pos       { return e.Pos }
+func (e *Variable) Position() Pos        { return e.Pos }
+func (e *TemplateLiteral) Position() Pos { return e.Pos }
+func (e *ListExpr) Position() Pos        { return e.Pos }
+
+func (e *CallExpr) expr()        {}
+func (e *StringLiteral) expr()   {}
+func (e *WordLiteral) expr()     {}
+func (e *BoolLiteral) expr()     {}
+func (e *NullLiteral) expr()     {}
+func (e *BinaryExpr) expr()      {}
+func (e *UnaryExpr) expr()       {}
+func (e *Variable) expr()        {}
+func (e *TemplateLiteral) expr() {}
+func (e *ListExpr) expr()        {}
+
+// ParseError is returned when scripts can not be parsed, Pos points to the offending token.
+type ParseError struct {
+	Pos     Pos
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Pos, e.Message)
+}
+
+func errorAt(pos Pos, format string, args ...interface{}) error {
+	return &ParseError{Pos: pos, Message: fmt.Sprintf(format, args...)}
+}
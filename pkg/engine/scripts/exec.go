@@ -3,6 +3,7 @@ package scripts
 import (
 	"fmt"
 	"github.com/storm-blue/rubick/pkg/modifier/action"
+	"github.com/storm-blue/rubick/pkg/modifier/conditions"
 	"github.com/storm-blue/rubick/pkg/modifier/objects"
 	"strings"
 )
@@ -40,7 +41,7 @@ func ExecObject(ctx action.Context, object objects.StructuredObject, scripts str
 	}
 
 	for _, _action := range actions {
-		_action.DoAction(ctx, object)
+		doAction(ctx, object, _action)
 	}
 
 	return nil
@@ -55,8 +56,13 @@ func ExecObjects(ctx action.Context, _objects []objects.StructuredObject, script
 	var result []objects.StructuredObject
 
 	for _, _object := range _objects {
+		if _object.Metadata().Empty() {
+			// empty documents kept by objects.YAMLsOptions.KeepEmpty are written back as they were
+			result = append(result, _object)
+			continue
+		}
 		for _, _action := range actions {
-			_action.DoAction(ctx, _object)
+			doAction(ctx, _object, _action)
 		}
 		if !_object.Metadata().Removed() {
 			result = append(result, _object)
@@ -66,6 +72,19 @@ func ExecObjects(ctx action.Context, _objects []objects.StructuredObject, script
 	return result, nil
 }
 
+// doAction do action on object, errors of filter index conditions in keys (like: a[?(b > 1)]) are handled by the
+// error policy of ctx like conditions of IF, the element does not match if the error is skipped.
+func doAction(ctx action.Context, object objects.StructuredObject, _action action.Action) {
+	_action.DoAction(ctx, objects.WithOptions(object, objects.KeyOptions{
+		Filter: CompileFilter,
+		OnFilterError: func(err error) (bool, error) {
+			return false, conditions.HandleError(err, ctx.ErrorPolicy(), func(err error) {
+				ctx.Log(object, _action, err)
+			})
+		},
+	}))
+}
+
 func ValidateScripts(scripts string) error {
 	if _, err := ParseScripts(scripts); err != nil {
 		return err
@@ -74,26 +93,43 @@ func ValidateScripts(scripts string) error {
 }
 
 func ParseScripts(scripts string) ([]action.Action, error) {
-	var actions []action.Action
-	for _, line := range strings.Split(scripts, "\n") {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		if IsComment(line) {
-			continue
-		}
+	script, err := Parse(scripts)
+	if err != nil {
+		return nil, wrapParseScriptsError(scripts, err)
+	}
 
-		_action, err := ParseAction(line)
+	var actions []action.Action
+	for _, statement := range script.Statements {
+		_action, err := compileStatement(statement)
 		if err != nil {
-			return nil, fmt.Errorf("parse scripts line error: \nscripts = [ %s ] \nerr = %v", line, err)
+			return nil, wrapParseScriptsError(scripts, err)
 		}
 		actions = append(actions, _action)
 	}
 	return actions, nil
 }
 
+// wrapParseScriptsError add the offending line of scripts to error, and point out the offending column like:
+//
+//	scripts = [ IF VALUE_OF(kind) = "Service" THEN DELETE(spec.clusterIP) ]
+//	                              ^
+func wrapParseScriptsError(scripts string, err error) error {
+	parseError, ok := err.(*ParseError)
+	if !ok {
+		return fmt.Errorf("parse scripts line error: \nerr = %v", err)
+	}
+
+	lines := strings.Split(scripts, "\n")
+	if parseError.Pos.Line > len(lines) {
+		return fmt.Errorf("parse scripts line error: \nerr = %v", err)
+	}
+
+	line := []rune(strings.TrimRight(lines[parseError.Pos.Line-1], " \t\r"))
+	indent := len(line) - len([]rune(strings.TrimLeft(string(line), " \t")))
+	pointer := strings.Repeat(" ", len("scripts = [ ")+parseError.Pos.Column-1-indent) + "^"
+	return fmt.Errorf("parse scripts line error: \nscripts = [ %s ] \n%s \nerr = %v", string(line[indent:]), pointer, err)
+}
+
 func IsComment(line string) bool {
 	return strings.HasPrefix(line, "#")
 }
@@ -2,6 +2,9 @@ package scripts
 
 import (
 	"github.com/storm-blue/rubick/pkg/modifier/action"
+	"github.com/storm-blue/rubick/pkg/modifier/conditions"
+	"github.com/storm-blue/rubick/pkg/modifier/objects"
+	"reflect"
 	"testing"
 )
 
@@ -99,6 +102,659 @@ spec:
 `,
 			wantErr: false,
 		},
+		{
+			name: "TEST_IF_BLOCK",
+			args: args{
+				ctx: action.NewContext(nil),
+				yaml: `kind: Service
+metadata:
+  name: app
+spec:
+  clusterIP: 10.100.72.127
+`,
+				scripts: `
+IF VALUE_OF(kind) == "Deployment" THEN
+  SET(metadata.labels.type, "deployment")
+ELSE IF VALUE_OF(kind) == "Service" THEN
+  SET(metadata.labels.type, "service")
+  IF EXISTS(spec.clusterIP) THEN
+    DELETE(spec.clusterIP)
+  END
+ELSE
+  REMOVE()
+END
+`,
+			},
+			want: `kind: Service
+metadata:
+  name: app
+  labels:
+    type: service
+spec: {}
+`,
+			wantErr: false,
+		},
+		{
+			name: "TEST_VARIABLES",
+			args: args{
+				ctx: action.NewContext(nil),
+				yaml: `kind: Service
+metadata:
+  labels:
+    app: nginx
+  name: app
+`,
+				scripts: `
+LET app = VALUE_OF(metadata.labels.app)
+LET kind = "svc"
+SET(metadata.name, "${app}-svc")
+IF VALUE_OF(metadata.name) == "${app}-svc" THEN SET(metadata.labels.origin, $app)
+IF HAS_PREFIX(metadata.name, $app) && HAS_SUFFIX(metadata.name, "-${kind}") THEN SET(metadata.labels.prefix, true)
+REPLACE_PART(metadata.name, $app, "web")
+IF HAS_PREFIX(metadata.name, $app) THEN SET(metadata.labels.prefix, false)
+LET n = 2
+IF LENGTH_OF(metadata.labels) > $n THEN SET(metadata.labels.many, true)
+IF LENGTH_OF(metadata.labels) > $kind THEN SET(metadata.labels.invalid, true)
+`,
+			},
+			want: `kind: Service
+metadata:
+  labels:
+    app: nginx
+    origin: nginx
+    prefix: true
+    many: true
+  name: web-svc
+`,
+			wantErr: false,
+		},
+		{
+			name: "TEST_EXPRESSIONS",
+			args: args{
+				ctx: action.NewContext(nil),
+				yaml: `kind: Deployment
+metadata:
+  name: app
+  namespace: test
+spec:
+  hosts:
+  - a.example.com
+  - b.example.com
+  replicas: 3
+`,
+				scripts: `
+SET(metadata.name, CONCAT(VALUE_OF(metadata.namespace), "-", VALUE_OF(metadata.name)))
+SET(metadata.labels.kind, LOWER(SUBSTR(VALUE_OF(kind), 0, 6)))
+SET(metadata.labels.env, UPPER(REPLACE(VALUE_OF(metadata.namespace), "test", "prod")))
+SET(spec.replicas, VALUE_OF(spec.replicas) * 2 + 1)
+SET(spec.cpu, (VALUE_OF(spec.replicas) - 1) / 2)
+SET(spec.hosts, JOIN(SPLIT(JOIN(VALUE_OF(spec.hosts), ","), ","), ";"))
+IF VALUE_OF(spec.cpu) == VALUE_OF(spec.replicas) / 2 - 0.5 THEN SET(spec.half, "yes")
+`,
+			},
+			want: `kind: Deployment
+metadata:
+  name: test-app
+  namespace: test
+  labels:
+    kind: deploy
+    env: PROD
+spec:
+  hosts: a.example.com;b.example.com
+  replicas: 7
+  cpu: 3
+  half: "yes"
+`,
+			wantErr: false,
+		},
+		{
+			name: "TEST_REGEX",
+			args: args{
+				ctx: action.NewContext(nil),
+				yaml: `kind: Deployment
+metadata:
+  name: app-v12
+spec:
+  image: registry.example.com/app:1.0.3
+`,
+				scripts: `
+IF MATCHES(metadata.name, "^app-v\\d+$") THEN REGEX_REPLACE(metadata.name, "^app-v(\\d+)$", "app-$1")
+REGEX_REPLACE(spec.image, "^[^/]+/(.+)$", "mirror.local/$1")
+IF MATCHES(metadata.name, "^web") THEN REMOVE()
+`,
+			},
+			want: `kind: Deployment
+metadata:
+  name: app-12
+spec:
+  image: mirror.local/app:1.0.3
+`,
+			wantErr: false,
+		},
+		{
+			name: "TEST_INVALID_REGEX",
+			args: args{
+				ctx:     action.NewContext(nil),
+				yaml:    `kind: Deployment`,
+				scripts: `REGEX_REPLACE(kind, "(", "a")`,
+			},
+			wantErr: true,
+		},
+		{
+			name: "TEST_MEMBERSHIP",
+			args: args{
+				ctx: action.NewContext(nil),
+				yaml: `kind: Service
+metadata:
+  name: app
+  namespace: prod
+spec:
+  ports:
+  - name: http
+    port: 8080
+`,
+				scripts: `
+IF VALUE_OF(metadata.namespace) IN ("test", "prod") THEN SET(metadata.labels.env, "online")
+IF VALUE_OF(metadata.namespace) NOT_IN ("test", "prod") THEN REMOVE()
+IF CONTAINS(metadata.labels, env) && CONTAINS(metadata.name, "ap") THEN SET(metadata.labels.checked, "yes")
+IF EXISTS(spec.ports[port=8080]) THEN SET(spec.ports[port=8080].port, 80)
+`,
+			},
+			want: `kind: Service
+metadata:
+  name: app
+  namespace: prod
+  labels:
+    env: online
+    checked: "yes"
+spec:
+  ports:
+  - name: http
+    port: 80
+`,
+			wantErr: false,
+		},
+		{
+			name: "TEST_TYPES",
+			args: args{
+				ctx: action.NewContext(nil),
+				yaml: `kind: Service
+metadata:
+  name: app
+spec:
+  enabled: true
+  host: null
+  port: "80"
+`,
+				scripts: `
+IF IS_STRING(spec.port) THEN SET(spec.port, 80)
+IF IS_NULL(spec.host) THEN DELETE(spec.host)
+IF TYPE_OF(spec.missing) == "missing" && !IS_NULL(spec.missing) THEN SET(spec.missing, "set")
+IF IS_BOOL(spec.enabled) && VALUE_OF(spec.enabled) == true THEN SET(metadata.labels.enabled, "yes")
+IF IS_NUMBER(spec.port) && IS_MAP(spec) && !IS_ARRAY(spec) THEN SET(metadata.labels.checked, "yes")
+`,
+			},
+			want: `kind: Service
+metadata:
+  name: app
+  labels:
+    enabled: "yes"
+    checked: "yes"
+spec:
+  enabled: true
+  port: 80
+  missing: set
+`,
+			wantErr: false,
+		},
+		{
+			name: "TEST_BOOL_AND_NULL_LITERALS",
+			args: args{
+				ctx: action.NewContext(nil),
+				yaml: `kind: CronJob
+metadata:
+  name: "null"
+spec:
+  paused: false
+  schedule: "true"
+`,
+				scripts: `
+IF VALUE_OF(spec.paused) == false THEN SET(spec.suspend, true)
+IF VALUE_OF(metadata.name) != null THEN SET(metadata.labels.named, "true")
+IF VALUE_OF(spec.missing) == null THEN SET(spec.selector, null)
+IF VALUE_OF(spec.schedule) IN (false, null, "true") THEN SET(spec.schedule, "* * * * *")
+`,
+			},
+			want: `kind: CronJob
+metadata:
+  name: "null"
+  labels:
+    named: "true"
+spec:
+  paused: false
+  schedule: "* * * * *"
+  suspend: true
+  selector: null
+`,
+			wantErr: false,
+		},
+		{
+			name: "TEST_RENAME_COPY_MOVE",
+			args: args{
+				ctx: action.NewContext(nil),
+				yaml: `kind: Deployment
+metadata:
+  labels:
+    app: nginx
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+      - image: nginx
+        name: a
+      - image: busybox
+        name: b
+`,
+				scripts: `
+RENAME(metadata.labels.app, "app.kubernetes.io/name")
+COPY(metadata.labels, spec.selector.matchLabels)
+SET(spec.selector.matchLabels.copied, "yes")
+MOVE(spec.template.spec.containers[name=b], spec.template.spec.initContainers[++])
+RENAME(spec.template.spec.containers[*].image, "img")
+MOVE(metadata.missing, metadata.other)
+`,
+			},
+			want: `kind: Deployment
+metadata:
+  labels:
+    app.kubernetes.io/name: nginx
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+      - img: nginx
+        name: a
+      initContainers:
+      - image: busybox
+        name: b
+  selector:
+    matchLabels:
+      app.kubernetes.io/name: nginx
+      copied: "yes"
+`,
+			wantErr: false,
+		},
+		{
+			name: "TEST_MERGE",
+			args: args{
+				ctx: action.NewContext(nil),
+				yaml: `kind: Deployment
+metadata:
+  labels:
+    app: nginx
+spec:
+  template:
+    spec:
+      containers:
+      - image: nginx
+        name: a
+      - image: busybox
+        name: b
+      tolerations:
+      - key: a
+`,
+				scripts: `
+MERGE(metadata.labels, "{\"team\": \"infra\", \"app\": \"web\"}")
+MERGE(metadata.annotations, "owner: infra")
+MERGE(spec.template.spec.tolerations, "[{key: b}]", "append")
+MERGE_FILE(spec.template.spec, "testdata/merge.yaml", "merge")
+`,
+			},
+			want: `kind: Deployment
+metadata:
+  labels:
+    app: web
+    team: infra
+  annotations:
+    owner: infra
+spec:
+  template:
+    spec:
+      containers:
+      - image: nginx:1.25
+        name: a
+      - image: busybox
+        name: b
+      - name: sidecar
+        image: envoy
+      tolerations:
+      - key: a
+      - key: b
+`,
+			wantErr: false,
+		},
+		{
+			name: "TEST_MERGE_INVALID_STRATEGY",
+			args: args{
+				ctx:     action.NewContext(nil),
+				yaml:    `kind: Deployment`,
+				scripts: `MERGE(metadata.labels, "{a: b}", "patch")`,
+			},
+			wantErr: true,
+		},
+		{
+			name: "TEST_MERGE_FILE_NOT_FOUND",
+			args: args{
+				ctx:     action.NewContext(nil),
+				yaml:    `kind: Deployment`,
+				scripts: `MERGE_FILE(metadata.labels, "testdata/missing.yaml")`,
+			},
+			wantErr: true,
+		},
+		{
+			name: "TEST_ARRAYS",
+			args: args{
+				ctx: action.NewContext(nil),
+				yaml: `kind: Deployment
+spec:
+  ports:
+  - name: https
+    port: 443
+  - name: http
+    port: 80
+  - name: metrics
+  template:
+    spec:
+      containers:
+      - args:
+        - --port=80
+        env:
+        - name: DEBUG
+          value: "true"
+        - name: MODE
+          value: prod
+        - name: MODE
+          value: dev
+        name: a
+      - env:
+        - name: DEBUG
+        name: b
+`,
+				scripts: `
+LET level = "info"
+APPEND(spec.template.spec.containers[*].args, CONCAT("--log=", $level))
+PREPEND(spec.template.spec.containers[name=a].args, "serve")
+INSERT_AT(spec.template.spec.containers[name=a].args, 1, "--verbose")
+REMOVE_WHERE(spec.template.spec.containers[*].env, name == "DEBUG")
+UNIQUE(spec.template.spec.containers[*].env, name)
+SORT(spec.ports, port)
+`,
+			},
+			want: `kind: Deployment
+spec:
+  ports:
+  - name: http
+    port: 80
+  - name: https
+    port: 443
+  - name: metrics
+  template:
+    spec:
+      containers:
+      - args:
+        - serve
+        - --verbose
+        - --port=80
+        - --log=info
+        env:
+        - name: MODE
+          value: prod
+        name: a
+      - env: []
+        name: b
+        args:
+        - --log=info
+`,
+			wantErr: false,
+		},
+		{
+			name: "TEST_INSERT_AT_OUT_OF_RANGE",
+			args: args{
+				ctx:     action.NewContext(nil),
+				yaml:    `kind: Deployment`,
+				scripts: `INSERT_AT(spec.args, 1, "a")`,
+			},
+			want:    "kind: Deployment\n",
+			wantErr: false,
+		},
+		{
+			name: "TEST_FILTER_INDEX",
+			args: args{
+				ctx: action.NewContext(nil),
+				yaml: `kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+      - image: old.registry/nginx
+        name: a
+      - image: new.registry/busybox
+        name: b
+      - image: old.registry/envoy
+        name: c
+`,
+				scripts: `
+REGEX_REPLACE(spec.template.spec.containers[?(HAS_PREFIX(image, "old.registry/") && name == "a")].image, "^old", "new")
+SET(spec.template.spec.containers[?(HAS_PREFIX(image, "old.registry/"))].image, "new.registry/envoy")
+SET(spec.template.spec.containers[?(name IN (a, b) && image != "new.registry/busybox")].args[++], "--debug")
+IF EXISTS(spec.template.spec.containers[?(MATCHES(image, "envoy$"))]) THEN SET(metadata.labels.sidecar, "envoy")
+DELETE(spec.template.spec.containers[?(name == "b")])
+`,
+			},
+			want: `kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+      - image: new.registry/nginx
+        name: a
+        args:
+        - --debug
+      - image: new.registry/envoy
+        name: c
+metadata:
+  labels:
+    sidecar: envoy
+`,
+			wantErr: false,
+		},
+		{
+			name: "TEST_INVALID_FILTER_INDEX",
+			args: args{
+				ctx:     action.NewContext(nil),
+				yaml:    `kind: Deployment`,
+				scripts: `DELETE(spec.containers[?(FOO(image))])`,
+			},
+			wantErr: true,
+		},
+		{
+			name: "TEST_MULTI_VALUES",
+			args: args{
+				ctx: action.NewContext(nil),
+				yaml: `kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+      - args:
+        - --debug
+        - --port=80
+        image: old.registry/nginx
+        name: a
+      - image: new.registry/busybox
+        name: b
+`,
+				scripts: `
+SET(metadata.annotations.images, JOIN(VALUE_OF(spec.template.spec.containers[*].image), ","))
+IF LENGTH_OF(spec.template.spec.containers[*].name) == 2 THEN SET(metadata.annotations.count, 2)
+IF ANY(spec.template.spec.containers[*].image, HAS_PREFIX(@, "old.registry/")) THEN SET(metadata.labels.old, true)
+IF ALL(spec.template.spec.containers, EXISTS(args)) THEN SET(metadata.labels.args, true)
+IF ALL(spec.template.spec.containers[*].image, @ != "busybox") THEN REMOVE_WHERE(spec.template.spec.containers[*].args, @ == "--debug")
+REPLACE_PART(spec.template.spec.containers[*].image, "old.registry", "new.registry")
+`,
+			},
+			want: `kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+      - args:
+        - --port=80
+        image: new.registry/nginx
+        name: a
+      - image: new.registry/busybox
+        name: b
+metadata:
+  annotations:
+    images: old.registry/nginx,new.registry/busybox
+    count: 2
+  labels:
+    old: true
+`,
+			wantErr: false,
+		},
+		{
+			name: "TEST_WILDCARDS",
+			args: args{
+				ctx: action.NewContext(nil),
+				yaml: `kind: Deployment
+metadata:
+  labels:
+    app: nginx
+    tier: web
+  resourceVersion: "1"
+spec:
+  replicas: 2
+  template:
+    metadata:
+      resourceVersion: "2"
+    spec:
+      containers:
+      - image: nginx
+      initContainers:
+      - image: busybox
+`,
+				scripts: `
+DELETE(**.resourceVersion)
+SET(metadata.annotations.images, JOIN(VALUE_OF(spec..image), ","))
+SET(metadata.labels.*, "x")
+IF EXISTS(**.resourceVersion) THEN SET(metadata.annotations.version, true)
+IF EXISTS(spec.*.spec) THEN SET(spec.replicas, VALUE_OF(spec.replicas) * 2)
+`,
+			},
+			want: `kind: Deployment
+metadata:
+  labels:
+    app: x
+    tier: x
+  annotations:
+    images: nginx,busybox
+spec:
+  replicas: 4
+  template:
+    metadata: {}
+    spec:
+      containers:
+      - image: nginx
+      initContainers:
+      - image: busybox
+`,
+			wantErr: false,
+		},
+		{
+			name: "TEST_INDEXES",
+			args: args{
+				ctx: action.NewContext(nil),
+				yaml: `kind: Service
+spec:
+  ports:
+  - name: web
+    port: 80
+    protocol: TCP
+  - name: web
+    port: 80
+    protocol: UDP
+  - name: metrics
+    port: 9090
+    protocol: TCP
+`,
+				scripts: `
+SET(metadata.annotations.last, VALUE_OF(spec.ports[-1].name))
+SET(metadata.annotations.ports, JOIN(VALUE_OF(spec.ports[:2].protocol), ","))
+IF EXISTS(spec.ports[name=web,protocol=UDP]) THEN SET(spec.ports[name=web,protocol=UDP].port, 8080)
+DELETE(spec.ports[-1:])
+`,
+			},
+			want: `kind: Service
+spec:
+  ports:
+  - name: web
+    port: 80
+    protocol: TCP
+  - name: web
+    port: 8080
+    protocol: UDP
+metadata:
+  annotations:
+    last: metrics
+    ports: TCP,UDP
+`,
+			wantErr: false,
+		},
+		{
+			name: "TEST_QUOTED_KEYS",
+			args: args{
+				ctx: action.NewContext(nil),
+				yaml: `kind: Service
+metadata:
+  annotations:
+    prometheus.io/scrape: "true"
+    app.kubernetes.io/owner: team a
+spec:
+  ports:
+  - name: a, b
+    port: 80
+`,
+				scripts: `
+IF VALUE_OF(metadata.annotations["prometheus.io/scrape"]) == "true" THEN SET(metadata.annotations['prometheus.io/port'], "9090")
+RENAME(metadata.annotations."app.kubernetes.io/owner", "owner: name")
+SET(spec.ports[name="a, b"].port, 8080)
+`,
+			},
+			want: `kind: Service
+metadata:
+  annotations:
+    prometheus.io/scrape: "true"
+    'owner: name': team a
+    prometheus.io/port: "9090"
+spec:
+  ports:
+  - name: a, b
+    port: 8080
+`,
+			wantErr: false,
+		},
+		{
+			name: "TEST_UNKNOWN_FUNCTION",
+			args: args{
+				ctx:     action.NewContext(nil),
+				yaml:    `kind: Deployment`,
+				scripts: `SET(metadata.name, FOO(VALUE_OF(kind)))`,
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -113,3 +769,72 @@ spec:
 		})
 	}
 }
+
+func TestExecYAML_FilterErrorPolicy(t *testing.T) {
+	// condition of the first element returns error because "a" is not map
+	yaml := "l:\n- a: x\n- a:\n    c: ok\n"
+	scripts := `SET(l[?(a.c == "ok")].b, 1)`
+
+	tests := []struct {
+		name     string
+		policy   conditions.ErrorPolicy
+		want     string
+		wantLogs []bool
+	}{
+		{
+			name:     "TEST_ABORT",
+			policy:   conditions.ErrorPolicyAbort,
+			want:     "l:\n- a: x\n- a:\n    c: ok\n",
+			wantLogs: []bool{true},
+		},
+		{
+			name:   "TEST_FALSE",
+			policy: conditions.ErrorPolicyFalse,
+			want:   "l:\n- a: x\n- a:\n    c: ok\n  b: 1\n",
+		},
+		{
+			name:     "TEST_LOG",
+			policy:   conditions.ErrorPolicyLog,
+			want:     "l:\n- a: x\n- a:\n    c: ok\n  b: 1\n",
+			wantLogs: []bool{true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := action.NewContextWithErrorPolicy(nil, tt.policy)
+			got, err := ExecYAML(ctx, yaml, scripts)
+			if err != nil {
+				t.Fatalf("ExecYAML() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ExecYAML() got = %v, want %v", got, tt.want)
+			}
+			var logs []bool
+			for _, log := range ctx.Logs() {
+				logs = append(logs, log.Err() != nil)
+			}
+			if !reflect.DeepEqual(logs, tt.wantLogs) {
+				t.Errorf("ExecYAML() logs = %v, want %v", logs, tt.wantLogs)
+			}
+		})
+	}
+}
+
+func TestExecObjects_KeepEmpty(t *testing.T) {
+	multiYaml := "---\n# comment only\n---\na: b\n---\n"
+	_objects, _, err := objects.FromYAMLsWithOptions(multiYaml, objects.YAMLsOptions{KeepEmpty: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	__objects, err := ExecObjects(action.NewContext(nil), _objects, `SET(metadata.labels.x, "1")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := objects.ToYAMLs(__objects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "---\n# comment only\n---\na: b\nmetadata:\n  labels:\n    x: \"1\"\n---\n"; got != want {
+		t.Errorf("ExecObjects() got = %q, want %q", got, want)
+	}
+}
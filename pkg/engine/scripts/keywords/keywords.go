@@ -2,30 +2,74 @@ package keywords
 
 import (
 	"fmt"
-	"github.com/storm-blue/rubick/pkg/common"
+	"github.com/storm-blue/rubick/pkg/modifier/action"
 	"github.com/storm-blue/rubick/pkg/modifier/conditions"
 	"github.com/storm-blue/rubick/pkg/modifier/objects"
+	"os"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 )
 
 //goland:noinspection ALL
 const (
-	IF           = "IF"
-	THEN         = "THEN"
-	VALUE_OF     = "VALUE_OF"
-	LENGTH_OF    = "LENGTH_OF"
-	EXISTS       = "EXISTS"
-	NOT_EXISTS   = "NOT_EXISTS"
-	HAS_PREFIX   = "HAS_PREFIX"
-	HAS_SUFFIX   = "HAS_SUFFIX"
-	DELETE       = "DELETE"
-	SET          = "SET"
-	REPLACE_PART = "REPLACE_PART"
-	TRIM_PREFIX  = "TRIM_PREFIX"
-	TRIM_SUFFIX  = "TRIM_SUFFIX"
-	PRINT        = "PRINT"
-	REMOVE       = "REMOVE"
+	IF            = "IF"
+	THEN          = "THEN"
+	ELSE          = "ELSE"
+	END           = "END"
+	NOT           = "NOT"
+	LET           = "LET"
+	TRUE          = "true"
+	FALSE         = "false"
+	NULL          = "null"
+	VALUE_OF      = "VALUE_OF"
+	LENGTH_OF     = "LENGTH_OF"
+	EXISTS        = "EXISTS"
+	NOT_EXISTS    = "NOT_EXISTS"
+	HAS_PREFIX    = "HAS_PREFIX"
+	HAS_SUFFIX    = "HAS_SUFFIX"
+	MATCHES       = "MATCHES"
+	CONTAINS      = "CONTAINS"
+	IN            = "IN"
+	TYPE_OF       = "TYPE_OF"
+	IS_STRING     = "IS_STRING"
+	IS_NUMBER     = "IS_NUMBER"
+	IS_BOOL       = "IS_BOOL"
+	IS_ARRAY      = "IS_ARRAY"
+	IS_MAP        = "IS_MAP"
+	IS_NULL       = "IS_NULL"
+	NOT_IN        = "NOT_IN"
+	ANY           = "ANY"
+	ALL           = "ALL"
+	DELETE        = "DELETE"
+	SET           = "SET"
+	REPLACE_PART  = "REPLACE_PART"
+	REGEX_REPLACE = "REGEX_REPLACE"
+	RENAME        = "RENAME"
+	COPY          = "COPY"
+	MOVE          = "MOVE"
+	MERGE         = "MERGE"
+	MERGE_FILE    = "MERGE_FILE"
+	APPEND        = "APPEND"
+	PREPEND       = "PREPEND"
+	INSERT_AT     = "INSERT_AT"
+	REMOVE_WHERE  = "REMOVE_WHERE"
+	SORT          = "SORT"
+	UNIQUE        = "UNIQUE"
+	TRIM_PREFIX   = "TRIM_PREFIX"
+	TRIM_SUFFIX   = "TRIM_SUFFIX"
+	PRINT         = "PRINT"
+	REMOVE        = "REMOVE"
+
+	// value functions
+	CONCAT  = "CONCAT"
+	UPPER   = "UPPER"
+	LOWER   = "LOWER"
+	SUBSTR  = "SUBSTR"
+	REPLACE = "REPLACE"
+	SPLIT   = "SPLIT"
+	JOIN    = "JOIN"
 
 	// operators
 	OPERATOR_EQ = "=="
@@ -38,69 +82,196 @@ const (
 	OPERATOR_AND = "&&"
 	OPERATOR_OR  = "||"
 	OPERATOR_NOT = "!"
+
+	OPERATOR_ASSIGN = "="
+
+	OPERATOR_ADD = "+"
+	OPERATOR_SUB = "-"
+	OPERATOR_MUL = "*"
+	OPERATOR_DIV = "/"
+	OPERATOR_MOD = "%"
 )
 
+// Argument of keyword methods, which has been parsed by scripts parser.
+type Argument struct {
+	// Value is the content of literal, quotation marks are removed and escapes are resolved.
+	// For Valuable argument, it is the source text like: $app
+	Value string
+	// Quoted is true if literal is a string like "...".
+	Quoted bool
+	// Valuable is not nil if argument is calculated from object, like: $app, "${app}-svc"
+	Valuable conditions.Valuable
+}
+
 //goland:noinspection ALL
 var (
 	RELATIONAL_OPERATORS = []string{OPERATOR_EQ, OPERATOR_NE, OPERATOR_LE, OPERATOR_GE, OPERATOR_LT, OPERATOR_GT}
+	MEMBERSHIP_OPERATORS = []string{IN, NOT_IN}
 	LOGICAL_OPERATORS    = []string{OPERATOR_AND, OPERATOR_OR, OPERATOR_NOT}
+	ADDITIVE_OPERATORS   = []string{OPERATOR_ADD, OPERATOR_SUB}
+	MULTIPLY_OPERATORS   = []string{OPERATOR_MUL, OPERATOR_DIV, OPERATOR_MOD}
+
+	// CONDITION_ARGUMENTS are the indexes of arguments which are parsed as conditions, like: REMOVE_WHERE(env, name == "DEBUG")
+	CONDITION_ARGUMENTS = map[string]int{REMOVE_WHERE: 1, ANY: 1, ALL: 1}
+
+	VALUE_FUNCTIONS = map[string]func(args ...action.Valuable) (action.Valuable, error){
+		CONCAT: func(args ...action.Valuable) (action.Valuable, error) {
+			if len(args) < 1 {
+				return nil, fmt.Errorf("invalid '%s' function: number of parameters must be at least 1", CONCAT)
+			}
+			return action.Concat(args...), nil
+		},
+		UPPER: func(args ...action.Valuable) (action.Valuable, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("invalid '%s' function: number of parameters must be 1", UPPER)
+			}
+			return action.Upper(args[0]), nil
+		},
+		LOWER: func(args ...action.Valuable) (action.Valuable, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("invalid '%s' function: number of parameters must be 1", LOWER)
+			}
+			return action.Lower(args[0]), nil
+		},
+		SUBSTR: func(args ...action.Valuable) (action.Valuable, error) {
+			switch len(args) {
+			case 2:
+				return action.Substr(args[0], args[1], nil), nil
+			case 3:
+				return action.Substr(args[0], args[1], args[2]), nil
+			default:
+				return nil, fmt.Errorf("invalid '%s' function: number of parameters must be 2 or 3", SUBSTR)
+			}
+		},
+		REPLACE: func(args ...action.Valuable) (action.Valuable, error) {
+			if len(args) != 3 {
+				return nil, fmt.Errorf("invalid '%s' function: number of parameters must be 3", REPLACE)
+			}
+			return action.Replace(args[0], args[1], args[2]), nil
+		},
+		SPLIT: func(args ...action.Valuable) (action.Valuable, error) {
+			if len(args) != 2 {
+				return nil, fmt.Errorf("invalid '%s' function: number of parameters must be 2", SPLIT)
+			}
+			return action.Split(args[0], args[1]), nil
+		},
+		JOIN: func(args ...action.Valuable) (action.Valuable, error) {
+			if len(args) != 2 {
+				return nil, fmt.Errorf("invalid '%s' function: number of parameters must be 2", JOIN)
+			}
+			return action.Join(args[0], args[1]), nil
+		},
+	}
 
-	SINGLE_WORDS_SIMPLE_CONDITION_METHODS = map[string]func(args ...string) (conditions.Condition, error){
-		EXISTS: func(args ...string) (conditions.Condition, error) {
+	SINGLE_WORDS_SIMPLE_CONDITION_METHODS = map[string]func(args ...Argument) (conditions.Condition, error){
+		EXISTS: func(args ...Argument) (conditions.Condition, error) {
 			if len(args) != 1 {
 				return nil, fmt.Errorf("invalid '%s' condition: number of parameters must be 1", EXISTS)
 			}
-			key := common.UnwrapQuotaIfNeeded(args[0])
-			if !objects.IsValidKey(key) {
-				return nil, fmt.Errorf("invalid '%s' condition: key is invalid: %s", EXISTS, key)
+			key, err := keyOf(EXISTS, args[0])
+			if err != nil {
+				return nil, err
 			}
 			return conditions.New().Exists(key), nil
 		},
-		NOT_EXISTS: func(args ...string) (conditions.Condition, error) {
+		NOT_EXISTS: func(args ...Argument) (conditions.Condition, error) {
 			if len(args) != 1 {
 				return nil, fmt.Errorf("invalid '%s' condition: number of parameters must be 1", NOT_EXISTS)
 			}
-			key := common.UnwrapQuotaIfNeeded(args[0])
-			if !objects.IsValidKey(key) {
-				return nil, fmt.Errorf("invalid '%s' condition: key is invalid: %s", NOT_EXISTS, key)
+			key, err := keyOf(NOT_EXISTS, args[0])
+			if err != nil {
+				return nil, err
 			}
 			return conditions.New().Not(conditions.New().Exists(key)), nil
 		},
-		HAS_PREFIX: func(args ...string) (conditions.Condition, error) {
+		HAS_PREFIX: func(args ...Argument) (conditions.Condition, error) {
 			if len(args) != 2 {
 				return nil, fmt.Errorf("invalid '%s' condition: number of parameters must be 2", HAS_PREFIX)
 			}
-			key := common.UnwrapQuotaIfNeeded(args[0])
-			if !objects.IsValidKey(key) {
-				return nil, fmt.Errorf("invalid '%s' condition: key is invalid: %s", HAS_PREFIX, key)
+			key, err := keyOf(HAS_PREFIX, args[0])
+			if err != nil {
+				return nil, err
 			}
-			prefix := common.UnwrapQuotaIfNeeded(args[1])
-			return conditions.New().HasPrefix(key, prefix), nil
+			return conditions.New().HasPrefix(key, valueOf(args[1])), nil
 		},
-		HAS_SUFFIX: func(args ...string) (conditions.Condition, error) {
+		HAS_SUFFIX: func(args ...Argument) (conditions.Condition, error) {
 			if len(args) != 2 {
 				return nil, fmt.Errorf("invalid '%s' condition: number of parameters must be 2", HAS_SUFFIX)
 			}
-			key := common.UnwrapQuotaIfNeeded(args[0])
-			if !objects.IsValidKey(key) {
-				return nil, fmt.Errorf("invalid '%s' condition: key is invalid: %s", HAS_SUFFIX, key)
+			key, err := keyOf(HAS_SUFFIX, args[0])
+			if err != nil {
+				return nil, err
+			}
+			return conditions.New().HasSuffix(key, valueOf(args[1])), nil
+		},
+		MATCHES: func(args ...Argument) (conditions.Condition, error) {
+			if len(args) != 2 {
+				return nil, fmt.Errorf("invalid '%s' condition: number of parameters must be 2", MATCHES)
+			}
+			key, err := keyOf(MATCHES, args[0])
+			if err != nil {
+				return nil, err
+			}
+			regex, err := CompileRegex(MATCHES, args[1])
+			if err != nil {
+				return nil, err
 			}
-			suffix := common.UnwrapQuotaIfNeeded(args[1])
-			return conditions.New().HasSuffix(key, suffix), nil
+			return conditions.New().Matches(key, regex), nil
+		},
+		IS_STRING: isTypeMethod(IS_STRING, conditions.TypeString),
+		IS_NUMBER: isTypeMethod(IS_NUMBER, conditions.TypeNumber),
+		IS_BOOL:   isTypeMethod(IS_BOOL, conditions.TypeBool),
+		IS_ARRAY:  isTypeMethod(IS_ARRAY, conditions.TypeArray),
+		IS_MAP:    isTypeMethod(IS_MAP, conditions.TypeMap),
+		IS_NULL:   isTypeMethod(IS_NULL, conditions.TypeNull),
+		CONTAINS: func(args ...Argument) (conditions.Condition, error) {
+			if len(args) != 2 {
+				return nil, fmt.Errorf("invalid '%s' condition: number of parameters must be 2", CONTAINS)
+			}
+			key, err := keyOf(CONTAINS, args[0])
+			if err != nil {
+				return nil, err
+			}
+			return conditions.New().Contains(key, valueOf(args[1])), nil
 		},
 	}
 
-	RELATIONAL_SIMPLE_CONDITION_METHODS = map[string]func(operator string, rightValue string, args ...string) (conditions.Condition, error){
-		VALUE_OF: func(operator string, rightValue string, args ...string) (conditions.Condition, error) {
+	MEMBERSHIP_CONDITION_METHODS = map[string]func(operator string, values []Argument, args ...Argument) (conditions.Condition, error){
+		VALUE_OF: func(operator string, values []Argument, args ...Argument) (conditions.Condition, error) {
 			if len(args) != 1 {
 				return nil, fmt.Errorf("invalid '%s' condition: number of parameters must be 1", VALUE_OF)
 			}
-			key := common.UnwrapQuotaIfNeeded(args[0])
-			if !objects.IsValidKey(key) {
-				return nil, fmt.Errorf("invalid '%s' condition: key is invalid: %s", VALUE_OF, key)
+			key, err := keyOf(VALUE_OF, args[0])
+			if err != nil {
+				return nil, err
 			}
 
-			value := common.UnwrapQuotaIfNeeded(rightValue)
+			var _values []interface{}
+			for _, value := range values {
+				_values = append(_values, valueOf(value))
+			}
+			switch operator {
+			case IN:
+				return conditions.New().ValueOf(key).In(_values...), nil
+			case NOT_IN:
+				return conditions.New().ValueOf(key).NotIn(_values...), nil
+			default:
+				return nil, fmt.Errorf("invalid '%s' condition: invalid membership operator: %s", VALUE_OF, operator)
+			}
+		},
+	}
+
+	RELATIONAL_SIMPLE_CONDITION_METHODS = map[string]func(operator string, rightValue Argument, args ...Argument) (conditions.Condition, error){
+		VALUE_OF: func(operator string, rightValue Argument, args ...Argument) (conditions.Condition, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("invalid '%s' condition: number of parameters must be 1", VALUE_OF)
+			}
+			key, err := keyOf(VALUE_OF, args[0])
+			if err != nil {
+				return nil, err
+			}
+
+			value := valueOf(rightValue)
 			switch operator {
 			case OPERATOR_EQ:
 				return conditions.New().ValueOf(key).EqualTo(value), nil
@@ -118,22 +289,47 @@ var (
 				return nil, fmt.Errorf("invalid '%s' condition: invalid relational operator: %s", VALUE_OF, operator)
 			}
 		},
-		LENGTH_OF: func(operator string, rightValue string, args ...string) (conditions.Condition, error) {
+		TYPE_OF: func(operator string, rightValue Argument, args ...Argument) (conditions.Condition, error) {
 			if len(args) != 1 {
-				return nil, fmt.Errorf("invalid '%s' condition: number of parameters must be 1", LENGTH_OF)
+				return nil, fmt.Errorf("invalid '%s' condition: number of parameters must be 1", TYPE_OF)
 			}
-			key := common.UnwrapQuotaIfNeeded(args[0])
-			if !objects.IsValidKey(key) {
-				return nil, fmt.Errorf("invalid '%s' condition: key is invalid: %s", LENGTH_OF, key)
+			key, err := keyOf(TYPE_OF, args[0])
+			if err != nil {
+				return nil, err
 			}
 
-			rightValue = strings.TrimSpace(rightValue)
-			_value, err := strconv.ParseInt(rightValue, 10, 64)
+			valueType := rightValue.Value
+			if rightValue.Valuable != nil || !slices.Contains(conditions.Types, valueType) {
+				return nil, fmt.Errorf("invalid '%s' condition: invalid type: %s, type must be one of: %s",
+					TYPE_OF, valueType, strings.Join(conditions.Types, ", "))
+			}
+			switch operator {
+			case OPERATOR_EQ:
+				return conditions.New().TypeOf(key).EqualTo(valueType), nil
+			case OPERATOR_NE:
+				return conditions.New().TypeOf(key).NotEqual(valueType), nil
+			default:
+				return nil, fmt.Errorf("invalid '%s' condition: invalid relational operator: %s", TYPE_OF, operator)
+			}
+		},
+		LENGTH_OF: func(operator string, rightValue Argument, args ...Argument) (conditions.Condition, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("invalid '%s' condition: number of parameters must be 1", LENGTH_OF)
+			}
+			key, err := keyOf(LENGTH_OF, args[0])
 			if err != nil {
-				return nil, fmt.Errorf("invalid '%s' condition: invalid value: %s", LENGTH_OF, rightValue)
+				return nil, err
 			}
 
-			value := int(_value)
+			// variables are resolved and checked when condition is calculated
+			var value interface{} = rightValue.Valuable
+			if rightValue.Valuable == nil {
+				_value, err := strconv.ParseInt(strings.TrimSpace(rightValue.Value), 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid '%s' condition: invalid value: %s", LENGTH_OF, rightValue.Value)
+				}
+				value = int(_value)
+			}
 			switch operator {
 			case OPERATOR_EQ:
 				return conditions.New().LengthOf(key).EqualTo(value), nil
@@ -153,3 +349,81 @@ var (
 		},
 	}
 )
+
+// keyOf get the key from argument of condition method, key must be a literal.
+func keyOf(method string, arg Argument) (string, error) {
+	if arg.Valuable != nil || !objects.IsValidKey(arg.Value) {
+		return "", fmt.Errorf("invalid '%s' condition: key is invalid: %s", method, arg.Value)
+	}
+	return arg.Value, nil
+}
+
+// isTypeMethod create method like: IS_STRING(key)
+func isTypeMethod(method string, valueType string) func(args ...Argument) (conditions.Condition, error) {
+	return func(args ...Argument) (conditions.Condition, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("invalid '%s' condition: number of parameters must be 1", method)
+		}
+		key, err := keyOf(method, args[0])
+		if err != nil {
+			return nil, err
+		}
+		return conditions.New().TypeOf(key).EqualTo(valueType), nil
+	}
+}
+
+// valueOf get the value of argument which is compared with object values.
+func valueOf(arg Argument) interface{} {
+	if arg.Valuable != nil {
+		return arg.Valuable
+	}
+	return arg.Value
+}
+
+// CompileRegex compile the regex argument of method, regex must be a literal.
+func CompileRegex(method string, arg Argument) (*regexp.Regexp, error) {
+	if arg.Valuable != nil {
+		return nil, fmt.Errorf("invalid '%s' expression: regex must be literal: %s", method, arg.Value)
+	}
+	regex, err := regexp.Compile(arg.Value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid '%s' expression: invalid regex: %v", method, err)
+	}
+	return regex, nil
+}
+
+// ParseMergeValue parse the inline yaml or json of MERGE, like: MERGE(metadata.labels, "{app: nginx}")
+func ParseMergeValue(method string, arg Argument) (*objects.Fragment, error) {
+	if arg.Valuable != nil {
+		return nil, fmt.Errorf("invalid '%s' expression: value must be literal: %s", method, arg.Value)
+	}
+	return unmarshalMergeValue(method, []byte(arg.Value))
+}
+
+// ReadMergeFile read the yaml or json file of MERGE_FILE, like: MERGE_FILE(metadata.labels, "labels.yaml")
+func ReadMergeFile(method string, arg Argument) (*objects.Fragment, error) {
+	if arg.Valuable != nil || arg.Value == "" {
+		return nil, fmt.Errorf("invalid '%s' expression: file path must be a non-empty literal: %s", method, arg.Value)
+	}
+	bs, err := os.ReadFile(arg.Value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid '%s' expression: %v", method, err)
+	}
+	return unmarshalMergeValue(method, bs)
+}
+
+func unmarshalMergeValue(method string, bs []byte) (*objects.Fragment, error) {
+	fragment, err := objects.ParseFragment(bs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid '%s' expression: invalid yaml: %v", method, err)
+	}
+	return fragment, nil
+}
+
+// ListStrategyOf parse the list strategy of MERGE and MERGE_FILE, must be one of objects.ListStrategies.
+func ListStrategyOf(method string, arg Argument) (objects.ListStrategy, error) {
+	if arg.Valuable == nil && slices.Contains(objects.ListStrategies, objects.ListStrategy(arg.Value)) {
+		return objects.ListStrategy(arg.Value), nil
+	}
+	return "", fmt.Errorf("invalid '%s' expression: list strategy must be one of %v: %s", method, objects.ListStrategies, arg.Value)
+}
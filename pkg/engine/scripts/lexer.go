@@ -0,0 +1,398 @@
+package scripts
+
+import (
+	"strings"
+	"unicode"
+)
+
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	tokenNewline
+	tokenWord
+	tokenString
+	tokenLeftParen
+	tokenRightParen
+	tokenComma
+	tokenOperator
+	tokenVariable
+)
+
+func (t tokenType) String() string {
+	switch t {
+	case tokenEOF:
+		return "end of scripts"
+	case tokenNewline:
+		return "end of line"
+	case tokenWord:
+		return "word"
+	case tokenString:
+		return "string"
+	case tokenLeftParen:
+		return "'('"
+	case tokenRightParen:
+		return "')'"
+	case tokenComma:
+		return "','"
+	case tokenOperator:
+		return "operator"
+	case tokenVariable:
+		return "variable"
+	default:
+		return "unknown token"
+	}
+}
+
+type token struct {
+	typ tokenType
+	// text is the source text of token
+	text string
+	// value is the unquoted and unescaped content of string token, name of variable token,
+	// same as text for other tokens
+	value string
+	// parts is not nil if string token contains interpolations like: "${app}-svc"
+	parts []templatePart
+	pos   Pos
+}
+
+// templatePart is a part of interpolated string, either a plain string or a variable
+type templatePart struct {
+	variable bool
+	value    string
+	pos      Pos
+}
+
+func (t token) String() string {
+	switch t.typ {
+	case tokenEOF, tokenNewline:
+		return t.typ.String()
+	default:
+		return "'" + t.text + "'"
+	}
+}
+
+// operators sorted by length, longer operators must be matched first
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "=", "+", "*", "%"}
+
+// wordOperators are characters of word, they are operators only when they are standalone words like: a - b
+var wordOperators = []string{"-", "/"}
+
+type lexer struct {
+	input  []rune
+	offset int
+	line   int
+	column int
+	// lineStart is true if no token has been read in current line
+	lineStart bool
+}
+
+func tokenize(scripts string) ([]token, error) {
+	l := &lexer{input: []rune(scripts), line: 1, column: 1, lineStart: true}
+
+	var tokens []token
+	for {
+		t, err := l.nextToken()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+		if t.typ == tokenEOF {
+			return tokens, nil
+		}
+	}
+}
+
+func (l *lexer) pos() Pos {
+	return Pos{Line: l.line, Column: l.column}
+}
+
+func (l *lexer) peek(n int) rune {
+	if l.offset+n >= len(l.input) {
+		return 0
+	}
+	return l.input[l.offset+n]
+}
+
+func (l *lexer) eof() bool {
+	return l.offset >= len(l.input)
+}
+
+func (l *lexer) advance() rune {
+	r := l.input[l.offset]
+	l.offset++
+	if r == '\n' {
+		l.line++
+		l.column = 1
+	} else {
+		l.column++
+	}
+	return r
+}
+
+func (l *lexer) nextToken() (token, error) {
+	l.skipSpacesAndComments()
+
+	pos := l.pos()
+	if l.eof() {
+		return token{typ: tokenEOF, pos: pos}, nil
+	}
+
+	r := l.peek(0)
+	if r == '\n' {
+		l.advance()
+		l.lineStart = true
+		return token{typ: tokenNewline, text: "\n", value: "\n", pos: pos}, nil
+	}
+	l.lineStart = false
+
+	switch {
+	case r == '(':
+		l.advance()
+		return token{typ: tokenLeftParen, text: "(", value: "(", pos: pos}, nil
+	case r == ')':
+		l.advance()
+		return token{typ: tokenRightParen, text: ")", value: ")", pos: pos}, nil
+	case r == ',':
+		l.advance()
+		return token{typ: tokenComma, text: ",", value: ",", pos: pos}, nil
+	case r == '"':
+		return l.readString()
+	case r == '$':
+		return l.readVariable()
+	case isWordChar(r), l.hasPrefix("*.") || l.hasPrefix("**."), r == '[', r == '\'':
+		return l.readWord()
+	}
+
+	for _, operator := range operators {
+		if l.hasPrefix(operator) {
+			for range operator {
+				l.advance()
+			}
+			return token{typ: tokenOperator, text: operator, value: operator, pos: pos}, nil
+		}
+	}
+
+	return token{}, errorAt(pos, "unexpected character '%c'", r)
+}
+
+func (l *lexer) hasPrefix(s string) bool {
+	for i, r := range []rune(s) {
+		if l.peek(i) != r {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *lexer) skipSpacesAndComments() {
+	for !l.eof() {
+		r := l.peek(0)
+		if r == '#' && l.lineStart {
+			for !l.eof() && l.peek(0) != '\n' {
+				l.advance()
+			}
+		} else if r != '\n' && unicode.IsSpace(r) {
+			l.advance()
+		} else {
+			return
+		}
+	}
+}
+
+// readString read string like: "abc", "a \"b\" c", "${app}-svc"
+func (l *lexer) readString() (token, error) {
+	pos := l.pos()
+	start := l.offset
+	l.advance()
+
+	var parts []templatePart
+	partPos := l.pos()
+	value := strings.Builder{}
+	part := strings.Builder{}
+	for {
+		if l.eof() || l.peek(0) == '\n' {
+			return token{}, errorAt(pos, "unterminated string")
+		}
+		r := l.advance()
+		switch {
+		case r == '"':
+			t := token{typ: tokenString, text: string(l.input[start:l.offset]), value: value.String(), pos: pos}
+			if parts != nil {
+				if part.Len() > 0 {
+					parts = append(parts, templatePart{value: part.String(), pos: partPos})
+				}
+				t.parts = parts
+			}
+			return t, nil
+		case r == '\\':
+			if l.eof() {
+				return token{}, errorAt(pos, "unterminated string")
+			}
+			r = unescape(l.advance())
+			value.WriteRune(r)
+			part.WriteRune(r)
+		case r == '$' && l.peek(0) == '{':
+			variablePos := Pos{Line: l.line, Column: l.column - 1}
+			l.advance()
+			name := strings.Builder{}
+			for !l.eof() && isVariableChar(l.peek(0)) {
+				name.WriteRune(l.advance())
+			}
+			if l.peek(0) != '}' || !isValidVariableName(name.String()) {
+				return token{}, errorAt(variablePos, "invalid variable interpolation, expected '${name}'")
+			}
+			l.advance()
+
+			if part.Len() > 0 {
+				parts = append(parts, templatePart{value: part.String(), pos: partPos})
+				part.Reset()
+			}
+			parts = append(parts, templatePart{variable: true, value: name.String(), pos: variablePos})
+			partPos = l.pos()
+			value.WriteString("${" + name.String() + "}")
+		default:
+			value.WriteRune(r)
+			part.WriteRune(r)
+		}
+	}
+}
+
+// readVariable read variable like: $app
+func (l *lexer) readVariable() (token, error) {
+	pos := l.pos()
+	start := l.offset
+	l.advance()
+	for !l.eof() && isVariableChar(l.peek(0)) {
+		l.advance()
+	}
+
+	text := string(l.input[start:l.offset])
+	if !isValidVariableName(text[1:]) {
+		return token{}, errorAt(pos, "invalid variable name '%s'", text)
+	}
+	return token{typ: tokenVariable, text: text, value: text[1:], pos: pos}, nil
+}
+
+func unescape(r rune) rune {
+	switch r {
+	case 'n':
+		return '\n'
+	case 't':
+		return '\t'
+	case 'r':
+		return '\r'
+	default:
+		return r
+	}
+}
+
+// readWord read a word, like:
+// metadata.labels.(github.io/app)
+// metadata.annotations["prometheus.io/scrape"]
+// metadata.annotations.'a b'
+// spec.ports[port=8080].port
+// 3.14
+func (l *lexer) readWord() (token, error) {
+	pos := l.pos()
+	start := l.offset
+
+	for !l.eof() {
+		r := l.peek(0)
+		if isWordChar(r) {
+			l.advance()
+		} else if r == '*' && (l.offset == start || l.input[l.offset-1] == '.' || l.input[l.offset-1] == '*') {
+			// wildcards in keys like: metadata.labels.*, **.namespace
+			l.advance()
+		} else if r == '[' {
+			if err := l.skipPair('[', ']'); err != nil {
+				return token{}, err
+			}
+		} else if (r == '"' || r == '\'') && (l.offset == start || l.input[l.offset-1] == '.') {
+			// quoted segments in keys like: metadata.annotations."a b"
+			if err := l.skipQuoted(l.pos(), l.advance()); err != nil {
+				return token{}, err
+			}
+		} else if r == '(' && l.offset > start && l.input[l.offset-1] == '.' {
+			if err := l.skipPair('(', ')'); err != nil {
+				return token{}, err
+			}
+		} else {
+			break
+		}
+	}
+
+	text := string(l.input[start:l.offset])
+	for _, operator := range wordOperators {
+		if text == operator {
+			return token{typ: tokenOperator, text: text, value: text, pos: pos}, nil
+		}
+	}
+	return token{typ: tokenWord, text: text, value: text, pos: pos}, nil
+}
+
+// skipPair skip a balanced pair like '[...]' or '(...)' inside a word, quoted content is skipped as a whole.
+func (l *lexer) skipPair(left, right rune) error {
+	pos := l.pos()
+	depth := 0
+	for !l.eof() && l.peek(0) != '\n' {
+		r := l.advance()
+		switch r {
+		case left:
+			depth++
+		case right:
+			depth--
+			if depth == 0 {
+				return nil
+			}
+		case '"', '\'':
+			if err := l.skipQuoted(pos, r); err != nil {
+				return err
+			}
+		}
+	}
+	return errorAt(pos, "can not find corresponding '%c'", right)
+}
+
+// skipQuoted skip a quoted content like "a \"b\"" or 'a.b' inside a word, the left quote is already skipped.
+func (l *lexer) skipQuoted(pos Pos, quote rune) error {
+	for !l.eof() && l.peek(0) != quote && l.peek(0) != '\n' {
+		if l.advance() == '\\' && !l.eof() {
+			l.advance()
+		}
+	}
+	if l.eof() || l.peek(0) == '\n' {
+		return errorAt(pos, "unterminated string")
+	}
+	l.advance()
+	return nil
+}
+
+func isWordChar(r rune) bool {
+	if unicode.IsLetter(r) || unicode.IsDigit(r) {
+		return true
+	}
+	switch r {
+	case '_', '.', '/', '-', '@':
+		return true
+	default:
+		return false
+	}
+}
+
+func isVariableChar(r rune) bool {
+	return r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
+
+// isValidVariableName check name like: app, _app, app_1
+func isValidVariableName(name string) bool {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+	for _, r := range name {
+		if !isVariableChar(r) {
+			return false
+		}
+	}
+	return true
+}
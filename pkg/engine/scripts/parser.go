@@ -0,0 +1,521 @@
+package scripts
+
+import (
+	"github.com/storm-blue/rubick/pkg/engine/scripts/keywords"
+)
+
+// Parse scripts to AST, grammar of scripts:
+//
+//	script        = { statement NEWLINE }
+//	statement     = [ "IF" condition "THEN" ] call | if_block | let
+//	let           = "LET" NAME "=" value
+//	if_block      = "IF" condition "THEN" NEWLINE { statement NEWLINE }
+//	                { "ELSE" "IF" condition "THEN" NEWLINE { statement NEWLINE } }
+//	                [ "ELSE" NEWLINE { statement NEWLINE } ]
+//	                "END"
+//	condition     = and_condition { "||" and_condition }
+//	and_condition = not_condition { "&&" not_condition }
+//	not_condition = ( "!" | "NOT" ) not_condition | operand
+//	operand       = "(" condition ")" | call [ relational_operator value | ( "IN" | "NOT_IN" ) list ]
+//	list          = "(" [ value { "," value } ] ")"
+//	value         = term { ( "+" | "-" ) term }
+//	term          = factor { ( "*" | "/" | "%" ) factor }
+//	factor        = STRING | WORD | VARIABLE | "true" | "false" | "null" | call | "(" value ")"
+//	call          = WORD "(" [ value { "," value } ] ")"
+//
+// "!" has higher precedence than "&&", and "&&" has higher precedence than "||".
+// VARIABLE is like $app, and STRING may contain interpolations like "${app}-svc".
+// "-" and "/" are parts of WORD like metadata.labels.app-name, so they must be separated by spaces as operators.
+func Parse(scripts string) (*Script, error) {
+	tokens, err := tokenize(scripts)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	return p.parseScript()
+}
+
+type parser struct {
+	tokens  []token
+	current int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.current]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.current]
+	if t.typ != tokenEOF {
+		p.current++
+	}
+	return t
+}
+
+func (p *parser) expect(typ tokenType) (token, error) {
+	t := p.peek()
+	if t.typ != typ {
+		return token{}, errorAt(t.pos, "expected %v, got %v", typ, t)
+	}
+	return p.next(), nil
+}
+
+func (p *parser) isKeyword(keyword string) bool {
+	t := p.peek()
+	return t.typ == tokenWord && t.text == keyword
+}
+
+func (p *parser) isOperator(operators ...string) bool {
+	t := p.peek()
+	if t.typ != tokenOperator {
+		return false
+	}
+	for _, operator := range operators {
+		if t.text == operator {
+			return true
+		}
+	}
+	return false
+}
+
+// isStatementKeyword returns true if the next token is a keyword which can not be used as key.
+func (p *parser) isStatementKeyword() bool {
+	return p.isKeyword(keywords.IF) || p.isKeyword(keywords.THEN) || p.isKeyword(keywords.ELSE) ||
+		p.isKeyword(keywords.END) || p.isKeyword(keywords.LET)
+}
+
+func (p *parser) skipNewlines() {
+	for p.peek().typ == tokenNewline {
+		p.next()
+	}
+}
+
+func (p *parser) parseScript() (*Script, error) {
+	script := &Script{}
+	for {
+		p.skipNewlines()
+		if p.peek().typ == tokenEOF {
+			return script, nil
+		}
+		if p.isKeyword(keywords.ELSE) || p.isKeyword(keywords.END) {
+			t := p.peek()
+			return nil, errorAt(t.pos, "unexpected %v without '%s'", t, keywords.IF)
+		}
+
+		statement, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		script.Statements = append(script.Statements, statement)
+	}
+}
+
+// parseStatement parse a statement and the end of line after it
+func (p *parser) parseStatement() (Statement, error) {
+	pos := p.peek().pos
+
+	if p.isKeyword(keywords.LET) {
+		return p.parseLet()
+	}
+
+	var condition Expr
+	if p.isKeyword(keywords.IF) {
+		p.next()
+		var err error
+		if condition, err = p.parseIfHead(); err != nil {
+			return nil, err
+		}
+		if p.peek().typ == tokenNewline {
+			return p.parseIfBlock(pos, condition)
+		}
+	}
+
+	call, err := p.parseCall()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectEndOfLine(); err != nil {
+		return nil, err
+	}
+	return &ActionStatement{Pos: pos, Condition: condition, Action: call}, nil
+}
+
+// parseLet parse "LET name = value" and the end of line after it
+func (p *parser) parseLet() (*LetStatement, error) {
+	pos := p.next().pos
+
+	name, err := p.expect(tokenWord)
+	if err != nil {
+		return nil, err
+	}
+	if !isValidVariableName(name.text) {
+		return nil, errorAt(name.pos, "invalid variable name %v", name)
+	}
+	if !p.isOperator(keywords.OPERATOR_ASSIGN) {
+		t := p.peek()
+		return nil, errorAt(t.pos, "expected '%s', got %v", keywords.OPERATOR_ASSIGN, t)
+	}
+	p.next()
+
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectEndOfLine(); err != nil {
+		return nil, err
+	}
+	return &LetStatement{Pos: pos, Name: name.text, Value: value}, nil
+}
+
+// parseIfHead parse "[ condition ] THEN" after "IF"
+func (p *parser) parseIfHead() (Expr, error) {
+	condition, err := p.parseCondition()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isKeyword(keywords.THEN) {
+		t := p.peek()
+		return nil, errorAt(t.pos, "expected '%s', got %v", keywords.THEN, t)
+	}
+	p.next()
+	return condition, nil
+}
+
+func (p *parser) parseIfBlock(pos Pos, condition Expr) (*IfStatement, error) {
+	statement := &IfStatement{Pos: pos}
+	branch := &Branch{Pos: pos, Condition: condition}
+
+	for {
+		if err := p.expectEndOfLine(); err != nil {
+			return nil, err
+		}
+		body, err := p.parseBlockBody()
+		if err != nil {
+			return nil, err
+		}
+		branch.Body = body
+		statement.Branches = append(statement.Branches, branch)
+
+		if p.isKeyword(keywords.END) {
+			p.next()
+			return statement, p.expectEndOfLine()
+		}
+
+		// ELSE
+		elsePos := p.next().pos
+		if !p.isKeyword(keywords.IF) {
+			break
+		}
+		p.next()
+		condition, err := p.parseIfHead()
+		if err != nil {
+			return nil, err
+		}
+		branch = &Branch{Pos: elsePos, Condition: condition}
+	}
+
+	if err := p.expectEndOfLine(); err != nil {
+		return nil, err
+	}
+	body, err := p.parseBlockBody()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isKeyword(keywords.END) {
+		t := p.peek()
+		return nil, errorAt(t.pos, "expected '%s', got %v", keywords.END, t)
+	}
+	p.next()
+	statement.Else = body
+	if statement.Else == nil {
+		statement.Else = []Statement{}
+	}
+	return statement, p.expectEndOfLine()
+}
+
+// parseBlockBody parse statements until "ELSE" or "END"
+func (p *parser) parseBlockBody() ([]Statement, error) {
+	var statements []Statement
+	for {
+		p.skipNewlines()
+		if p.isKeyword(keywords.ELSE) || p.isKeyword(keywords.END) {
+			return statements, nil
+		}
+		if t := p.peek(); t.typ == tokenEOF {
+			return nil, errorAt(t.pos, "expected '%s', got %v", keywords.END, t)
+		}
+
+		statement, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, statement)
+	}
+}
+
+func (p *parser) expectEndOfLine() error {
+	t := p.peek()
+	switch t.typ {
+	case tokenEOF:
+		return nil
+	case tokenNewline:
+		p.next()
+		return nil
+	default:
+		return errorAt(t.pos, "expected end of line, got %v", t)
+	}
+}
+
+func (p *parser) parseCondition() (Expr, error) {
+	left, err := p.parseAndCondition()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOperator(keywords.OPERATOR_OR) {
+		operator := p.next()
+		right, err := p.parseAndCondition()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Pos: operator.pos, Operator: operator.text, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAndCondition() (Expr, error) {
+	left, err := p.parseNotCondition()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOperator(keywords.OPERATOR_AND) {
+		operator := p.next()
+		right, err := p.parseNotCondition()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Pos: operator.pos, Operator: operator.text, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNotCondition() (Expr, error) {
+	if p.isOperator(keywords.OPERATOR_NOT) || p.isKeyword(keywords.NOT) {
+		operator := p.next()
+		operand, err := p.parseNotCondition()
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryExpr{Pos: operator.pos, Operator: keywords.OPERATOR_NOT, Operand: operand}, nil
+	}
+	return p.parseOperand()
+}
+
+func (p *parser) parseOperand() (Expr, error) {
+	if p.peek().typ == tokenLeftParen {
+		p.next()
+		condition, err := p.parseCondition()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenRightParen); err != nil {
+			return nil, err
+		}
+		return condition, nil
+	}
+
+	var call Expr
+	if t := p.peek(); t.typ == tokenWord && p.tokens[p.current+1].typ != tokenLeftParen && !p.isStatementKeyword() {
+		// "key == value" is short for "VALUE_OF(key) == value"
+		p.next()
+		call = &CallExpr{Pos: t.pos, Method: keywords.VALUE_OF, Args: []Expr{&WordLiteral{Pos: t.pos, Value: t.value}}}
+		if !p.isOperator(keywords.RELATIONAL_OPERATORS...) && !p.isKeyword(keywords.IN) && !p.isKeyword(keywords.NOT_IN) {
+			return nil, errorAt(p.peek().pos, "expected relational operator after %v, got %v", t, p.peek())
+		}
+	} else {
+		var err error
+		if call, err = p.parseCall(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.isOperator(keywords.RELATIONAL_OPERATORS...) {
+		operator := p.next()
+		right, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		return &BinaryExpr{Pos: operator.pos, Operator: operator.text, Left: call, Right: right}, nil
+	}
+
+	if p.isKeyword(keywords.IN) || p.isKeyword(keywords.NOT_IN) {
+		operator := p.next()
+		right, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return &BinaryExpr{Pos: operator.pos, Operator: operator.text, Left: call, Right: right}, nil
+	}
+	return call, nil
+}
+
+func (p *parser) parseList() (*ListExpr, error) {
+	left, err := p.expect(tokenLeftParen)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &ListExpr{Pos: left.pos}
+	if p.peek().typ == tokenRightParen {
+		p.next()
+		return list, nil
+	}
+
+	for {
+		item, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		list.Items = append(list.Items, item)
+
+		t := p.next()
+		switch t.typ {
+		case tokenComma:
+			continue
+		case tokenRightParen:
+			return list, nil
+		default:
+			return nil, errorAt(t.pos, "expected ',' or ')', got %v", t)
+		}
+	}
+}
+
+func (p *parser) parseValue() (Expr, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOperator(keywords.ADDITIVE_OPERATORS...) {
+		operator := p.next()
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Pos: operator.pos, Operator: operator.text, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseTerm() (Expr, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOperator(keywords.MULTIPLY_OPERATORS...) {
+		operator := p.next()
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Pos: operator.pos, Operator: operator.text, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseFactor() (Expr, error) {
+	t := p.peek()
+	switch t.typ {
+	case tokenString:
+		p.next()
+		if t.parts != nil {
+			return newTemplateLiteral(t), nil
+		}
+		return &StringLiteral{Pos: t.pos, Value: t.value}, nil
+	case tokenVariable:
+		p.next()
+		return &Variable{Pos: t.pos, Name: t.value}, nil
+	case tokenLeftParen:
+		p.next()
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenRightParen); err != nil {
+			return nil, err
+		}
+		return value, nil
+	case tokenWord:
+		if p.isKeyword(keywords.IF) || p.isKeyword(keywords.THEN) || p.isKeyword(keywords.LET) {
+			return nil, errorAt(t.pos, "expected value, got keyword %v", t)
+		}
+		if p.tokens[p.current+1].typ == tokenLeftParen {
+			return p.parseCall()
+		}
+		p.next()
+		switch t.value {
+		case keywords.TRUE:
+			return &BoolLiteral{Pos: t.pos, Value: true}, nil
+		case keywords.FALSE:
+			return &BoolLiteral{Pos: t.pos, Value: false}, nil
+		case keywords.NULL:
+			return &NullLiteral{Pos: t.pos}, nil
+		}
+		return &WordLiteral{Pos: t.pos, Value: t.value}, nil
+	default:
+		return nil, errorAt(t.pos, "expected value, got %v", t)
+	}
+}
+
+func newTemplateLiteral(t token) *TemplateLiteral {
+	template := &TemplateLiteral{Pos: t.pos}
+	for _, part := range t.parts {
+		if part.variable {
+			template.Parts = append(template.Parts, &Variable{Pos: part.pos, Name: part.value})
+		} else {
+			template.Parts = append(template.Parts, &StringLiteral{Pos: part.pos, Value: part.value})
+		}
+	}
+	return template
+}
+
+func (p *parser) parseCall() (*CallExpr, error) {
+	method, err := p.expect(tokenWord)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(tokenLeftParen); err != nil {
+		return nil, err
+	}
+
+	call := &CallExpr{Pos: method.pos, Method: method.text}
+	if p.peek().typ == tokenRightParen {
+		p.next()
+		return call, nil
+	}
+
+	for {
+		var arg Expr
+		if index, ok := keywords.CONDITION_ARGUMENTS[call.Method]; ok && index == len(call.Args) {
+			arg, err = p.parseCondition()
+		} else {
+			arg, err = p.parseValue()
+		}
+		if err != nil {
+			return nil, err
+		}
+		call.Args = append(call.Args, arg)
+
+		t := p.next()
+		switch t.typ {
+		case tokenComma:
+			continue
+		case tokenRightParen:
+			return call, nil
+		default:
+			return nil, errorAt(t.pos, "expected ',' or ')', got %v", t)
+		}
+	}
+}
@@ -0,0 +1,389 @@
+package scripts
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		scripts string
+		want    *Script
+		wantErr bool
+	}{
+		{
+			name:    "TEST_CONDITION_ACTION",
+			scripts: `IF VALUE_OF(a.b.c)==true THEN DELETE(z.x.f)`,
+			want: &Script{Statements: []Statement{
+				&ActionStatement{
+					Pos: Pos{Line: 1, Column: 1},
+					Condition: &BinaryExpr{
+						Pos:      Pos{Line: 1, Column: 19},
+						Operator: "==",
+						Left: &CallExpr{
+							Pos:    Pos{Line: 1, Column: 4},
+							Method: "VALUE_OF",
+							Args:   []Expr{&WordLiteral{Pos: Pos{Line: 1, Column: 13}, Value: "a.b.c"}},
+						},
+						Right: &BoolLiteral{Pos: Pos{Line: 1, Column: 21}, Value: true},
+					},
+					Action: &CallExpr{
+						Pos:    Pos{Line: 1, Column: 31},
+						Method: "DELETE",
+						Args:   []Expr{&WordLiteral{Pos: Pos{Line: 1, Column: 38}, Value: "z.x.f"}},
+					},
+				},
+			}},
+		},
+		{
+			name: "TEST_MULTI_LINES",
+			scripts: `
+# comment
+DELETE(metadata.annotations.(kubectl.kubernetes.io/last-applied-configuration))
+  SET(spec.ports[name="a, b"].port, "8080")
+`,
+			want: &Script{Statements: []Statement{
+				&ActionStatement{
+					Pos: Pos{Line: 3, Column: 1},
+					Action: &CallExpr{
+						Pos:    Pos{Line: 3, Column: 1},
+						Method: "DELETE",
+						Args: []Expr{
+							&WordLiteral{Pos: Pos{Line: 3, Column: 8}, Value: "metadata.annotations.(kubectl.kubernetes.io/last-applied-configuration)"},
+						},
+					},
+				},
+				&ActionStatement{
+					Pos: Pos{Line: 4, Column: 3},
+					Action: &CallExpr{
+						Pos:    Pos{Line: 4, Column: 3},
+						Method: "SET",
+						Args: []Expr{
+							&WordLiteral{Pos: Pos{Line: 4, Column: 7}, Value: `spec.ports[name="a, b"].port`},
+							&StringLiteral{Pos: Pos{Line: 4, Column: 37}, Value: "8080"},
+						},
+					},
+				},
+			}},
+		},
+		{
+			name: "TEST_QUOTED_KEYS",
+			scripts: `DELETE(metadata.annotations["prometheus.io/scrape"])
+SET(data.'a b', "x")
+DELETE(['a)b'].c)`,
+			want: &Script{Statements: []Statement{
+				&ActionStatement{
+					Pos: Pos{Line: 1, Column: 1},
+					Action: &CallExpr{
+						Pos:    Pos{Line: 1, Column: 1},
+						Method: "DELETE",
+						Args:   []Expr{&WordLiteral{Pos: Pos{Line: 1, Column: 8}, Value: `metadata.annotations["prometheus.io/scrape"]`}},
+					},
+				},
+				&ActionStatement{
+					Pos: Pos{Line: 2, Column: 1},
+					Action: &CallExpr{
+						Pos:    Pos{Line: 2, Column: 1},
+						Method: "SET",
+						Args: []Expr{
+							&WordLiteral{Pos: Pos{Line: 2, Column: 5}, Value: "data.'a b'"},
+							&StringLiteral{Pos: Pos{Line: 2, Column: 17}, Value: "x"},
+						},
+					},
+				},
+				&ActionStatement{
+					Pos: Pos{Line: 3, Column: 1},
+					Action: &CallExpr{
+						Pos:    Pos{Line: 3, Column: 1},
+						Method: "DELETE",
+						Args:   []Expr{&WordLiteral{Pos: Pos{Line: 3, Column: 8}, Value: "['a)b'].c"}},
+					},
+				},
+			}},
+		},
+		{
+			name:    "TEST_NESTED_CALL",
+			scripts: `SET(a, VALUE_OF("b"))`,
+			want: &Script{Statements: []Statement{
+				&ActionStatement{
+					Pos: Pos{Line: 1, Column: 1},
+					Action: &CallExpr{
+						Pos:    Pos{Line: 1, Column: 1},
+						Method: "SET",
+						Args: []Expr{
+							&WordLiteral{Pos: Pos{Line: 1, Column: 5}, Value: "a"},
+							&CallExpr{
+								Pos:    Pos{Line: 1, Column: 8},
+								Method: "VALUE_OF",
+								Args:   []Expr{&StringLiteral{Pos: Pos{Line: 1, Column: 17}, Value: "b"}},
+							},
+						},
+					},
+				},
+			}},
+		},
+		{
+			name: "TEST_IF_BLOCK",
+			scripts: `IF EXISTS(a) THEN
+  DELETE(a)
+ELSE IF EXISTS(b) THEN
+ELSE
+  # comment
+  IF EXISTS(c) THEN DELETE(c)
+END`,
+			want: &Script{Statements: []Statement{
+				&IfStatement{
+					Pos: Pos{Line: 1, Column: 1},
+					Branches: []*Branch{
+						{
+							Pos:       Pos{Line: 1, Column: 1},
+							Condition: &CallExpr{Pos: Pos{Line: 1, Column: 4}, Method: "EXISTS", Args: []Expr{&WordLiteral{Pos: Pos{Line: 1, Column: 11}, Value: "a"}}},
+							Body: []Statement{
+								&ActionStatement{
+									Pos:    Pos{Line: 2, Column: 3},
+									Action: &CallExpr{Pos: Pos{Line: 2, Column: 3}, Method: "DELETE", Args: []Expr{&WordLiteral{Pos: Pos{Line: 2, Column: 10}, Value: "a"}}},
+								},
+							},
+						},
+						{
+							Pos:       Pos{Line: 3, Column: 1},
+							Condition: &CallExpr{Pos: Pos{Line: 3, Column: 9}, Method: "EXISTS", Args: []Expr{&WordLiteral{Pos: Pos{Line: 3, Column: 16}, Value: "b"}}},
+						},
+					},
+					Else: []Statement{
+						&ActionStatement{
+							Pos:       Pos{Line: 6, Column: 3},
+							Condition: &CallExpr{Pos: Pos{Line: 6, Column: 6}, Method: "EXISTS", Args: []Expr{&WordLiteral{Pos: Pos{Line: 6, Column: 13}, Value: "c"}}},
+							Action:    &CallExpr{Pos: Pos{Line: 6, Column: 21}, Method: "DELETE", Args: []Expr{&WordLiteral{Pos: Pos{Line: 6, Column: 28}, Value: "c"}}},
+						},
+					},
+				},
+			}},
+		},
+		{
+			name: "TEST_LET",
+			scripts: `LET app = VALUE_OF(a)
+SET(b, "${app}-svc \${c}", $app)`,
+			want: &Script{Statements: []Statement{
+				&LetStatement{
+					Pos:   Pos{Line: 1, Column: 1},
+					Name:  "app",
+					Value: &CallExpr{Pos: Pos{Line: 1, Column: 11}, Method: "VALUE_OF", Args: []Expr{&WordLiteral{Pos: Pos{Line: 1, Column: 20}, Value: "a"}}},
+				},
+				&ActionStatement{
+					Pos: Pos{Line: 2, Column: 1},
+					Action: &CallExpr{
+						Pos:    Pos{Line: 2, Column: 1},
+						Method: "SET",
+						Args: []Expr{
+							&WordLiteral{Pos: Pos{Line: 2, Column: 5}, Value: "b"},
+							&TemplateLiteral{Pos: Pos{Line: 2, Column: 8}, Parts: []Expr{
+								&Variable{Pos: Pos{Line: 2, Column: 9}, Name: "app"},
+								&StringLiteral{Pos: Pos{Line: 2, Column: 15}, Value: "-svc ${c}"},
+							}},
+							&Variable{Pos: Pos{Line: 2, Column: 28}, Name: "app"},
+						},
+					},
+				},
+			}},
+		},
+		{
+			name:    "TEST_ARITHMETIC",
+			scripts: `SET(a, (VALUE_OF(b) - 1) * 2 + 3 % 2)`,
+			want: &Script{Statements: []Statement{
+				&ActionStatement{
+					Pos: Pos{Line: 1, Column: 1},
+					Action: &CallExpr{
+						Pos:    Pos{Line: 1, Column: 1},
+						Method: "SET",
+						Args: []Expr{
+							&WordLiteral{Pos: Pos{Line: 1, Column: 5}, Value: "a"},
+							&BinaryExpr{
+								Pos:      Pos{Line: 1, Column: 30},
+								Operator: "+",
+								Left: &BinaryExpr{
+									Pos:      Pos{Line: 1, Column: 26},
+									Operator: "*",
+									Left: &BinaryExpr{
+										Pos:      Pos{Line: 1, Column: 21},
+										Operator: "-",
+										Left:     &CallExpr{Pos: Pos{Line: 1, Column: 9}, Method: "VALUE_OF", Args: []Expr{&WordLiteral{Pos: Pos{Line: 1, Column: 18}, Value: "b"}}},
+										Right:    &WordLiteral{Pos: Pos{Line: 1, Column: 23}, Value: "1"},
+									},
+									Right: &WordLiteral{Pos: Pos{Line: 1, Column: 28}, Value: "2"},
+								},
+								Right: &BinaryExpr{
+									Pos:      Pos{Line: 1, Column: 34},
+									Operator: "%",
+									Left:     &WordLiteral{Pos: Pos{Line: 1, Column: 32}, Value: "3"},
+									Right:    &WordLiteral{Pos: Pos{Line: 1, Column: 36}, Value: "2"},
+								},
+							},
+						},
+					},
+				},
+			}},
+		},
+		{
+			name:    "TEST_LITERALS",
+			scripts: `SET(a, true, false, null, "null", null.a)`,
+			want: &Script{Statements: []Statement{
+				&ActionStatement{
+					Pos: Pos{Line: 1, Column: 1},
+					Action: &CallExpr{
+						Pos:    Pos{Line: 1, Column: 1},
+						Method: "SET",
+						Args: []Expr{
+							&WordLiteral{Pos: Pos{Line: 1, Column: 5}, Value: "a"},
+							&BoolLiteral{Pos: Pos{Line: 1, Column: 8}, Value: true},
+							&BoolLiteral{Pos: Pos{Line: 1, Column: 14}, Value: false},
+							&NullLiteral{Pos: Pos{Line: 1, Column: 21}},
+							&StringLiteral{Pos: Pos{Line: 1, Column: 27}, Value: "null"},
+							&WordLiteral{Pos: Pos{Line: 1, Column: 35}, Value: "null.a"},
+						},
+					},
+				},
+			}},
+		},
+		{
+			name:    "TEST_CONDITION_ARGUMENT",
+			scripts: `REMOVE_WHERE(env, name == "DEBUG" || VALUE_OF(value) IN (a))`,
+			want: &Script{Statements: []Statement{
+				&ActionStatement{
+					Pos: Pos{Line: 1, Column: 1},
+					Action: &CallExpr{
+						Pos:    Pos{Line: 1, Column: 1},
+						Method: "REMOVE_WHERE",
+						Args: []Expr{
+							&WordLiteral{Pos: Pos{Line: 1, Column: 14}, Value: "env"},
+							&BinaryExpr{
+								Pos:      Pos{Line: 1, Column: 35},
+								Operator: "||",
+								Left: &BinaryExpr{
+									Pos:      Pos{Line: 1, Column: 24},
+									Operator: "==",
+									Left:     &CallExpr{Pos: Pos{Line: 1, Column: 19}, Method: "VALUE_OF", Args: []Expr{&WordLiteral{Pos: Pos{Line: 1, Column: 19}, Value: "name"}}},
+									Right:    &StringLiteral{Pos: Pos{Line: 1, Column: 27}, Value: "DEBUG"},
+								},
+								Right: &BinaryExpr{
+									Pos:      Pos{Line: 1, Column: 54},
+									Operator: "IN",
+									Left:     &CallExpr{Pos: Pos{Line: 1, Column: 38}, Method: "VALUE_OF", Args: []Expr{&WordLiteral{Pos: Pos{Line: 1, Column: 47}, Value: "value"}}},
+									Right:    &ListExpr{Pos: Pos{Line: 1, Column: 57}, Items: []Expr{&WordLiteral{Pos: Pos{Line: 1, Column: 58}, Value: "a"}}},
+								},
+							},
+						},
+					},
+				},
+			}},
+		},
+		{
+			name:    "TEST_KEY_WITHOUT_OPERATOR",
+			scripts: `IF a THEN DELETE(b)`,
+			wantErr: true,
+		},
+		{
+			name:    "TEST_LET_WITHOUT_VALUE",
+			scripts: `LET app =`,
+			wantErr: true,
+		},
+		{
+			name:    "TEST_INVALID_INTERPOLATION",
+			scripts: `SET(a, "${a-b}")`,
+			wantErr: true,
+		},
+		{
+			name: "TEST_IF_BLOCK_WITHOUT_END",
+			scripts: `IF EXISTS(a) THEN
+  DELETE(a)
+`,
+			wantErr: true,
+		},
+		{
+			name: "TEST_END_WITHOUT_IF",
+			scripts: `DELETE(a)
+END`,
+			wantErr: true,
+		},
+		{
+			name:    "TEST_MISSING_ACTION",
+			scripts: `IF VALUE_OF(a.b.c)==true THEN`,
+			wantErr: true,
+		},
+		{
+			name:    "TEST_MISSING_THEN",
+			scripts: `IF VALUE_OF(a.b.c)==true DELETE(a)`,
+			wantErr: true,
+		},
+		{
+			name:    "TEST_TWO_ACTIONS_IN_LINE",
+			scripts: `DELETE(a) DELETE(b)`,
+			wantErr: true,
+		},
+		{
+			name:    "TEST_UNCLOSED_BRACKET",
+			scripts: `DELETE(a[0)`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.scripts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_ErrorPosition(t *testing.T) {
+	tests := []struct {
+		name    string
+		scripts string
+		want    Pos
+	}{
+		{
+			name:    "TEST_UNEXPECTED_CHARACTER",
+			scripts: "DELETE(a)\nIF VALUE_OF(a) = 1 THEN DELETE(b)",
+			want:    Pos{Line: 2, Column: 16},
+		},
+		{
+			name:    "TEST_UNTERMINATED_STRING",
+			scripts: `SET(a, "b)`,
+			want:    Pos{Line: 1, Column: 8},
+		},
+		{
+			name:    "TEST_EXTRA_PARENTHESES",
+			scripts: `IF (EXISTS(a))) THEN DELETE(b)`,
+			want:    Pos{Line: 1, Column: 15},
+		},
+		{
+			name:    "TEST_MISSING_OPERAND",
+			scripts: `IF EXISTS(a) && || EXISTS(b) THEN DELETE(c)`,
+			want:    Pos{Line: 1, Column: 17},
+		},
+		{
+			name:    "TEST_KEYWORD_AS_VALUE",
+			scripts: `IF VALUE_OF(a) == THEN DELETE(c)`,
+			want:    Pos{Line: 1, Column: 19},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.scripts)
+			parseError, ok := err.(*ParseError)
+			if !ok {
+				t.Errorf("Parse() error = %v, want *ParseError", err)
+				return
+			}
+			if parseError.Pos != tt.want {
+				t.Errorf("Parse() error position = %v, want %v", parseError.Pos, tt.want)
+			}
+		})
+	}
+}
@@ -2,7 +2,6 @@ package scripts
 
 import (
 	"fmt"
-	"github.com/storm-blue/rubick/pkg/common"
 	"github.com/storm-blue/rubick/pkg/engine/scripts/keywords"
 	"github.com/storm-blue/rubick/pkg/modifier/action"
 	"github.com/storm-blue/rubick/pkg/modifier/conditions"
@@ -11,6 +10,19 @@ import (
 	"strings"
 )
 
+// CompileFilter compile the condition of filter index in keys, like: spec.containers[?(HAS_PREFIX(image, "old/"))],
+// it is the objects.KeyOptions.Filter of objects which are accessed by scripts, see doAction.
+func CompileFilter(expression string) (objects.ElementFilter, error) {
+	condition, err := parseCondition(expression)
+	if err != nil {
+		return nil, err
+	}
+	if condition == nil {
+		return nil, fmt.Errorf("empty filter condition")
+	}
+	return condition.Calculate, nil
+}
+
 // ParseAction
 // IF ... THEN ...
 func ParseAction(expression string) (action.Action, error) {
@@ -19,431 +31,636 @@ func ParseAction(expression string) (action.Action, error) {
 		return nil, fmt.Errorf("empty action expression")
 	}
 
-	conditionPart, pureActionPart, err := splitExpression(expression)
+	script, err := Parse(expression)
 	if err != nil {
 		return nil, err
 	}
-	condition, err := parseCondition(conditionPart)
+	if len(script.Statements) != 1 {
+		return nil, fmt.Errorf("invalid action expression: expected 1 statement, got %d: %s", len(script.Statements), expression)
+	}
+	return compileStatement(script.Statements[0])
+}
+
+func compileStatement(statement Statement) (action.Action, error) {
+	switch s := statement.(type) {
+	case *ActionStatement:
+		return compileActionStatement(s)
+	case *IfStatement:
+		return compileIfStatement(s)
+	case *LetStatement:
+		value, err := compileValuable(s.Value)
+		if err != nil {
+			return nil, err
+		}
+		return action.NewLetAction(s.Name, value), nil
+	default:
+		return nil, errorAt(statement.Position(), "invalid statement")
+	}
+}
+
+func compileActionStatement(statement *ActionStatement) (action.Action, error) {
+	pureAction, err := compilePureAction(statement.Action)
 	if err != nil {
 		return nil, err
 	}
-	pureAction, err := parsePureAction(pureActionPart)
+	if statement.Condition == nil {
+		return pureAction, nil
+	}
+
+	condition, err := compileCondition(statement.Condition)
 	if err != nil {
 		return nil, err
 	}
+	return action.NewConditionAction(condition, pureAction), nil
+}
 
-	if condition == nil {
-		return pureAction, nil
-	} else {
-		return action.NewConditionAction(condition, pureAction), nil
+// compileIfStatement compile "IF A THEN ... ELSE IF B THEN ... ELSE ... END" to nested actions like:
+// IF A THEN ... ELSE (IF B THEN ... ELSE ...)
+func compileIfStatement(statement *IfStatement) (action.Action, error) {
+	var elseAction action.Action
+	if statement.Else != nil {
+		var err error
+		if elseAction, err = compileStatements(statement.Else); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := len(statement.Branches) - 1; i >= 0; i-- {
+		branch := statement.Branches[i]
+		condition, err := compileCondition(branch.Condition)
+		if err != nil {
+			return nil, err
+		}
+		body, err := compileStatements(branch.Body)
+		if err != nil {
+			return nil, err
+		}
+		elseAction = action.NewConditionElseAction(condition, body, elseAction)
 	}
+	return elseAction, nil
 }
 
-// parsePureAction like:
-// DELETE(...)
-// SET(..., "...")
-func parsePureAction(expression string) (action.Action, error) {
-	method, args, err := splitMethodExpression(expression)
-	if err != nil {
-		return nil, err
+func compileStatements(statements []Statement) (action.Action, error) {
+	var actions []action.Action
+	for _, statement := range statements {
+		_action, err := compileStatement(statement)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, _action)
 	}
+	return action.NewBlockAction(actions...), nil
+}
+
+func compilePureAction(call *CallExpr) (action.Action, error) {
+	args := call.Args
 
-	switch method {
+	switch call.Method {
 	case keywords.DELETE:
 		if len(args) != 1 {
-			return nil, fmt.Errorf("invalid '%s' expression: number of parameters must be 1: %s", keywords.DELETE, expression)
+			return nil, errorAt(call.Pos, "invalid '%s' expression: number of parameters must be 1", keywords.DELETE)
 		}
-		key := common.UnwrapQuotaIfNeeded(args[0])
-		if !objects.IsValidKey(key) {
-			return nil, fmt.Errorf("invalid '%s' expression: key is invalid: %s", keywords.DELETE, expression)
+		key, err := compileKey(call, args[0])
+		if err != nil {
+			return nil, err
 		}
 		return action.NewDeleteAction(key), nil
 	case keywords.SET:
 		if len(args) != 2 {
-			return nil, fmt.Errorf("invalid '%s' expression: number of parameters must be 2: %s", keywords.SET, expression)
+			return nil, errorAt(call.Pos, "invalid '%s' expression: number of parameters must be 2", keywords.SET)
 		}
-		key := common.UnwrapQuotaIfNeeded(args[0])
-		if !objects.IsValidKey(key) {
-			return nil, fmt.Errorf("invalid '%s' expression: key is invalid: %s", keywords.SET, expression)
+		key, err := compileKey(call, args[0])
+		if err != nil {
+			return nil, err
 		}
-		value, err := preprocessArgument(args[1])
+		value, err := compileValuable(args[1])
 		if err != nil {
-			return nil, fmt.Errorf("invalid '%s' expression: second parameter is invalid: %s", keywords.SET, expression)
+			return nil, err
 		}
 		return action.NewSetAction(key, value), nil
 	case keywords.REPLACE_PART:
 		if len(args) != 3 {
-			return nil, fmt.Errorf("invalid '%s' expression: number of parameters must be 3: %s", keywords.REPLACE_PART, expression)
+			return nil, errorAt(call.Pos, "invalid '%s' expression: number of parameters must be 3", keywords.REPLACE_PART)
+		}
+		key, err := compileKey(call, args[0])
+		if err != nil {
+			return nil, err
 		}
-		key := common.UnwrapQuotaIfNeeded(args[0])
-		if !objects.IsValidKey(key) {
-			return nil, fmt.Errorf("invalid '%s' expression: key is invalid: %s", keywords.REPLACE_PART, expression)
+		old, err := compileStringValuable(args[1])
+		if err != nil {
+			return nil, err
+		}
+		_new, err := compileStringValuable(args[2])
+		if err != nil {
+			return nil, err
+		}
+		return action.NewReplacePartAction(key, old, _new), nil
+	case keywords.REGEX_REPLACE:
+		if len(args) != 3 {
+			return nil, errorAt(call.Pos, "invalid '%s' expression: number of parameters must be 3", keywords.REGEX_REPLACE)
+		}
+		key, err := compileKey(call, args[0])
+		if err != nil {
+			return nil, err
+		}
+		pattern, err := compileArgument(args[1])
+		if err != nil {
+			return nil, err
+		}
+		regex, err := keywords.CompileRegex(keywords.REGEX_REPLACE, pattern)
+		if err != nil {
+			return nil, errorAt(args[1].Position(), "%v", err)
+		}
+		replacement, err := compileStringValuable(args[2])
+		if err != nil {
+			return nil, err
 		}
-		return action.NewReplacePartAction(key, common.UnwrapQuotaIfNeeded(args[1]), common.UnwrapQuotaIfNeeded(args[2])), nil
+		return action.NewRegexReplaceAction(key, regex, replacement), nil
 	case keywords.TRIM_PREFIX:
 		if len(args) != 2 {
-			return nil, fmt.Errorf("invalid '%s' expression: number of parameters must be 2: %s", keywords.TRIM_PREFIX, expression)
+			return nil, errorAt(call.Pos, "invalid '%s' expression: number of parameters must be 2", keywords.TRIM_PREFIX)
 		}
-		key := common.UnwrapQuotaIfNeeded(args[0])
-		if !objects.IsValidKey(key) {
-			return nil, fmt.Errorf("invalid '%s' expression: key is invalid: %s", keywords.TRIM_PREFIX, expression)
+		key, err := compileKey(call, args[0])
+		if err != nil {
+			return nil, err
 		}
-		prefix, err := preprocessArgument(args[1])
+		prefix, err := compileValuable(args[1])
 		if err != nil {
-			return nil, fmt.Errorf("invalid '%s' expression: second parameter is invalid: %s", keywords.TRIM_PREFIX, expression)
+			return nil, err
 		}
 		return action.NewTrimPrefixAction(key, prefix), nil
 	case keywords.TRIM_SUFFIX:
 		if len(args) != 2 {
-			return nil, fmt.Errorf("invalid '%s' expression: number of parameters must be 2: %s", keywords.TRIM_SUFFIX, expression)
+			return nil, errorAt(call.Pos, "invalid '%s' expression: number of parameters must be 2", keywords.TRIM_SUFFIX)
 		}
-		key := common.UnwrapQuotaIfNeeded(args[0])
-		if !objects.IsValidKey(key) {
-			return nil, fmt.Errorf("invalid '%s' expression: key is invalid: %s", keywords.TRIM_SUFFIX, expression)
+		key, err := compileKey(call, args[0])
+		if err != nil {
+			return nil, err
 		}
-		suffix, err := preprocessArgument(args[1])
+		suffix, err := compileValuable(args[1])
 		if err != nil {
-			return nil, fmt.Errorf("invalid '%s' expression: second parameter is invalid: %s", keywords.TRIM_SUFFIX, expression)
+			return nil, err
 		}
 		return action.NewTrimSuffixAction(key, suffix), nil
+	case keywords.RENAME:
+		if len(args) != 2 {
+			return nil, errorAt(call.Pos, "invalid '%s' expression: number of parameters must be 2", keywords.RENAME)
+		}
+		key, err := compileKey(call, args[0])
+		if err != nil {
+			return nil, err
+		}
+		newName, err := compileArgument(args[1])
+		if err != nil {
+			return nil, err
+		}
+		if newName.Valuable != nil || newName.Value == "" {
+			return nil, errorAt(args[1].Position(), "invalid '%s' expression: new name must be a non-empty literal: %s", keywords.RENAME, newName.Value)
+		}
+		return action.NewRenameAction(key, newName.Value), nil
+	case keywords.COPY, keywords.MOVE:
+		if len(args) != 2 {
+			return nil, errorAt(call.Pos, "invalid '%s' expression: number of parameters must be 2", call.Method)
+		}
+		src, err := compileKey(call, args[0])
+		if err != nil {
+			return nil, err
+		}
+		dst, err := compileKey(call, args[1])
+		if err != nil {
+			return nil, err
+		}
+		if call.Method == keywords.COPY {
+			return action.NewCopyAction(src, dst), nil
+		}
+		return action.NewMoveAction(src, dst), nil
+	case keywords.MERGE, keywords.MERGE_FILE:
+		if len(args) != 2 && len(args) != 3 {
+			return nil, errorAt(call.Pos, "invalid '%s' expression: number of parameters must be 2 or 3", call.Method)
+		}
+		key, err := compileKey(call, args[0])
+		if err != nil {
+			return nil, err
+		}
+		argument, err := compileArgument(args[1])
+		if err != nil {
+			return nil, err
+		}
+		var fragment *objects.Fragment
+		if call.Method == keywords.MERGE {
+			fragment, err = keywords.ParseMergeValue(call.Method, argument)
+		} else {
+			fragment, err = keywords.ReadMergeFile(call.Method, argument)
+		}
+		if err != nil {
+			return nil, errorAt(args[1].Position(), "%v", err)
+		}
+		strategy := objects.ListReplace
+		if len(args) == 3 {
+			argument, err := compileArgument(args[2])
+			if err != nil {
+				return nil, err
+			}
+			if strategy, err = keywords.ListStrategyOf(call.Method, argument); err != nil {
+				return nil, errorAt(args[2].Position(), "%v", err)
+			}
+		}
+		return action.NewMergeAction(key, fragment, strategy), nil
+	case keywords.APPEND, keywords.PREPEND:
+		if len(args) != 2 {
+			return nil, errorAt(call.Pos, "invalid '%s' expression: number of parameters must be 2", call.Method)
+		}
+		key, err := compileKey(call, args[0])
+		if err != nil {
+			return nil, err
+		}
+		value, err := compileValuable(args[1])
+		if err != nil {
+			return nil, err
+		}
+		if call.Method == keywords.APPEND {
+			return action.NewAppendAction(key, value), nil
+		}
+		return action.NewPrependAction(key, value), nil
+	case keywords.INSERT_AT:
+		if len(args) != 3 {
+			return nil, errorAt(call.Pos, "invalid '%s' expression: number of parameters must be 3", keywords.INSERT_AT)
+		}
+		key, err := compileKey(call, args[0])
+		if err != nil {
+			return nil, err
+		}
+		index, err := compileValuable(args[1])
+		if err != nil {
+			return nil, err
+		}
+		value, err := compileValuable(args[2])
+		if err != nil {
+			return nil, err
+		}
+		return action.NewInsertAtAction(key, index, value), nil
+	case keywords.REMOVE_WHERE:
+		if len(args) != 2 {
+			return nil, errorAt(call.Pos, "invalid '%s' expression: number of parameters must be 2", keywords.REMOVE_WHERE)
+		}
+		key, err := compileKey(call, args[0])
+		if err != nil {
+			return nil, err
+		}
+		condition, err := compileCondition(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return action.NewRemoveWhereAction(key, condition), nil
+	case keywords.SORT, keywords.UNIQUE:
+		if len(args) != 1 && len(args) != 2 {
+			return nil, errorAt(call.Pos, "invalid '%s' expression: number of parameters must be 1 or 2", call.Method)
+		}
+		key, err := compileKey(call, args[0])
+		if err != nil {
+			return nil, err
+		}
+		field := ""
+		if len(args) == 2 {
+			if field, err = compileKey(call, args[1]); err != nil {
+				return nil, err
+			}
+		}
+		if call.Method == keywords.SORT {
+			return action.NewSortAction(key, field), nil
+		}
+		return action.NewUniqueAction(key, field), nil
 	case keywords.PRINT:
 		if len(args) != 1 {
-			return nil, fmt.Errorf("invalid '%s' expression: number of parameters must be 1: %s", keywords.PRINT, expression)
+			return nil, errorAt(call.Pos, "invalid '%s' expression: number of parameters must be 1", keywords.PRINT)
 		}
-		key := common.UnwrapQuotaIfNeeded(args[0])
-		if !objects.IsValidKey(key) {
-			return nil, fmt.Errorf("invalid '%s' expression: key is invalid: %s", keywords.PRINT, expression)
+		key, err := compileKey(call, args[0])
+		if err != nil {
+			return nil, err
 		}
 		return action.NewPrintAction(key), nil
 	case keywords.REMOVE:
 		if len(args) != 0 {
-			return nil, fmt.Errorf("invalid '%s' expression: number of parameters must be 0: %s", keywords.REMOVE, expression)
+			return nil, errorAt(call.Pos, "invalid '%s' expression: number of parameters must be 0", keywords.REMOVE)
 		}
 		return action.NewMarkRemovedAction(), nil
 	default:
-		return nil, fmt.Errorf("invalid action: %s", expression)
+		return nil, errorAt(call.Pos, "invalid action: unknown method '%s'", call.Method)
+	}
+}
+
+// compileKey compile the key argument of method, key must be a literal
+func compileKey(call *CallExpr, expr Expr) (string, error) {
+	argument, err := compileArgument(expr)
+	if err != nil {
+		return "", err
+	}
+	if argument.Valuable != nil {
+		return "", errorAt(expr.Position(), "invalid '%s' expression: key must be literal: %s", call.Method, argument.Value)
 	}
+	if !objects.IsValidKey(argument.Value) {
+		return "", errorAt(expr.Position(), "invalid '%s' expression: key is invalid: %s", call.Method, argument.Value)
+	}
+	return argument.Value, nil
 }
 
-func preprocessArgument(argument string) (action.Valuable, error) {
-	if strings.HasPrefix(argument, keywords.VALUE_OF+"(") {
-		if !strings.HasSuffix(argument, ")") {
-			return nil, fmt.Errorf("invalid argument: %s", argument)
+// compileArgument compile a literal or expression to keywords.Argument
+func compileArgument(expr Expr) (keywords.Argument, error) {
+	switch e := expr.(type) {
+	case *StringLiteral:
+		return keywords.Argument{Value: e.Value, Quoted: true}, nil
+	case *WordLiteral:
+		// conditions of filter indexes in keys are compiled when objects are accessed, they are checked here
+		// so that errors are found when scripts are parsed
+		for _, condition := range objects.FilterConditions(e.Value) {
+			if _, err := CompileFilter(condition); err != nil {
+				return keywords.Argument{}, errorAt(e.Pos, "invalid filter index: %v", err)
+			}
 		}
-		key := strings.TrimSpace(argument[len(keywords.VALUE_OF)+1 : len(argument)-1])
-		key = common.UnwrapQuotaIfNeeded(key)
+		return keywords.Argument{Value: e.Value}, nil
+	default:
+		valuable, err := compileValuable(e)
+		if err != nil {
+			return keywords.Argument{}, err
+		}
+		return keywords.Argument{Value: valuable.String(), Valuable: valuable}, nil
+	}
+}
 
-		if !objects.IsValidKey(key) {
-			return nil, fmt.Errorf("invalid argument: key is invalid: %s", argument)
+// compileValuable compile argument like:
+// VALUE_OF(...)
+// "..."
+// true
+// null
+// "${app}-svc"
+// $app
+// 80
+// CONCAT(VALUE_OF(...), "-", $app)
+// VALUE_OF(...) * 2
+func compileValuable(expr Expr) (action.Valuable, error) {
+	switch e := expr.(type) {
+	case *StringLiteral:
+		return action.Original(e.Value), nil
+	case *WordLiteral:
+		return action.Original(parseToNumberIfPossible(e.Value)), nil
+	case *BoolLiteral:
+		return action.Original(e.Value), nil
+	case *NullLiteral:
+		return action.Original(nil), nil
+	case *Variable:
+		return action.Variable(e.Name), nil
+	case *TemplateLiteral:
+		var parts []action.Valuable
+		for _, part := range e.Parts {
+			valuable, err := compileValuable(part)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, valuable)
+		}
+		return action.Concat(parts...), nil
+	case *BinaryExpr:
+		left, err := compileValuable(e.Left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := compileValuable(e.Right)
+		if err != nil {
+			return nil, err
+		}
+		return action.Arithmetic(e.Operator, left, right), nil
+	case *CallExpr:
+		if e.Method != keywords.VALUE_OF {
+			return compileValueFunction(e)
+		}
+		if len(e.Args) != 1 {
+			return nil, errorAt(e.Pos, "invalid '%s' argument: number of parameters must be 1", keywords.VALUE_OF)
+		}
+		key, err := compileKey(e, e.Args[0])
+		if err != nil {
+			return nil, err
 		}
 		return action.ValueOf(key), nil
-	} else {
-		v := parseToNumberIfPossible(argument)
-		return action.Original(v), nil
+	default:
+		return nil, errorAt(expr.Position(), "invalid argument")
 	}
 }
 
-func parseToNumberIfPossible(arg string) interface{} {
-	if isWrappedByQuota(arg) {
-		return common.UnwrapQuotaIfNeeded(arg)
+// compileValueFunction like:
+// UPPER(...)
+// SUBSTR(..., 0, 5)
+func compileValueFunction(call *CallExpr) (action.Valuable, error) {
+	f, ok := keywords.VALUE_FUNCTIONS[call.Method]
+	if !ok {
+		return nil, errorAt(call.Pos, "invalid argument: unknown method '%s'", call.Method)
 	}
-	if i, err := strconv.Atoi(arg); err == nil {
-		return i
-	}
-	if f, err := strconv.ParseFloat(arg, 64); err == nil {
-		return f
-	}
-	return arg
-}
 
-// TODO support quotation
-func splitExpression(expression string) (conditionPart string, pureActionPart string, err error) {
-	if strings.HasPrefix(expression, keywords.IF+" ") {
-		index := strings.Index(expression, " "+keywords.THEN+" ")
-		if index == -1 {
-			return "", "", fmt.Errorf("invalid scripts expression: keywords '%s' not found: %s", keywords.THEN, expression)
+	var args []action.Valuable
+	for _, arg := range call.Args {
+		valuable, err := compileValuable(arg)
+		if err != nil {
+			return nil, err
 		}
+		args = append(args, valuable)
+	}
 
-		conditionPart = strings.TrimSpace(expression[len(keywords.IF)+1 : index])
-		pureActionPart = strings.TrimSpace(expression[index+len(keywords.THEN)+2:])
-	} else {
-		pureActionPart = strings.TrimSpace(expression)
+	valuable, err := f(args...)
+	if err != nil {
+		return nil, errorAt(call.Pos, "%v", err)
 	}
-	return conditionPart, pureActionPart, nil
+	return valuable, nil
 }
 
-// TODO support quotation
-// DELETE(...)
-// SET(..., "...")
-// VALUE_OF(...)
-// HAS_PREFIX(..., "...")
-// HAS_SUFFIX(..., "...")
-func splitMethodExpression(expression string) (method string, args []string, err error) {
-	i := strings.Index(expression, "(")
-	if i == -1 || !strings.HasSuffix(expression, ")") {
-		return "", nil, fmt.Errorf("invalid action expression: %s", expression)
+// compileStringValuable is same as compileValuable, but words are always treated as string
+func compileStringValuable(expr Expr) (action.Valuable, error) {
+	if e, ok := expr.(*WordLiteral); ok {
+		return action.Original(e.Value), nil
 	}
+	return compileValuable(expr)
+}
 
-	method = expression[:i]
-	argsString := expression[i+1 : len(expression)-1]
-	argsString = strings.TrimSpace(argsString)
-
-	if argsString == "" {
-		return method, nil, nil
+func parseToNumberIfPossible(arg string) interface{} {
+	if i, err := strconv.Atoi(arg); err == nil {
+		return i
 	}
-	args = strings.Split(argsString, ",")
-
-	for x, arg := range args {
-		args[x] = strings.TrimSpace(arg)
+	if f, err := strconv.ParseFloat(arg, 64); err == nil {
+		return f
 	}
-	return method, args, nil
+	return arg
 }
 
 // parseCondition like:
 // (VALUE_OF(...) == "...")) && (VALUE_OF(...) > 0) || EXISTS(...)
 func parseCondition(expression string) (conditions.Condition, error) {
-	if expression == "" {
+	if strings.TrimSpace(expression) == "" {
 		return nil, nil
 	}
 
-	expression = common.UnwrapIfNeeded(expression)
-	if isSimpleCondition(expression) {
-		return parseSimpleCondition(expression)
-	}
-	return recursiveParseCondition(nil, "", expression)
-}
-
-func isSimpleCondition(expression string) bool {
-	if strings.HasPrefix(expression, "(") {
-		return false
-	}
-	if strings.Index(expression, keywords.OPERATOR_AND) != -1 || strings.Index(expression, keywords.OPERATOR_OR) != -1 {
-		return false
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, err
 	}
-	return true
-}
-
-func recursiveParseCondition(headCondition conditions.Condition, operator string, tail string) (conditions.Condition, error) {
-	conditionStr, _operator, rest, err := splitCombinationConditionExpression(tail)
+	p := &parser{tokens: tokens}
+	expr, err := p.parseCondition()
 	if err != nil {
 		return nil, err
 	}
+	if t := p.peek(); t.typ != tokenEOF {
+		return nil, errorAt(t.pos, "unexpected %v", t)
+	}
+	return compileCondition(expr)
+}
 
-	var nextCondition conditions.Condition
-
-	conditionStr = common.UnwrapIfNeeded(conditionStr)
-	if isSimpleCondition(conditionStr) {
-		nextCondition, err = parseSimpleCondition(conditionStr)
-		if err != nil {
-			return nil, err
+func compileCondition(expr Expr) (conditions.Condition, error) {
+	switch e := expr.(type) {
+	case *BinaryExpr:
+		switch e.Operator {
+		case keywords.OPERATOR_AND, keywords.OPERATOR_OR:
+			left, err := compileCondition(e.Left)
+			if err != nil {
+				return nil, err
+			}
+			right, err := compileCondition(e.Right)
+			if err != nil {
+				return nil, err
+			}
+			if e.Operator == keywords.OPERATOR_AND {
+				return left.And(right), nil
+			}
+			return left.Or(right), nil
+		case keywords.IN, keywords.NOT_IN:
+			return compileMembershipSimpleCondition(e)
+		default:
+			return compileRelationalSimpleCondition(e)
 		}
-	} else {
-		nextCondition, err = recursiveParseCondition(nil, "", conditionStr)
+	case *UnaryExpr:
+		condition, err := compileCondition(e.Operand)
 		if err != nil {
 			return nil, err
 		}
-	}
-
-	if headCondition == nil {
-		headCondition = nextCondition
-	} else {
-		switch operator {
-		case keywords.OPERATOR_AND:
-			headCondition = headCondition.And(nextCondition)
-		case keywords.OPERATOR_OR:
-			headCondition = headCondition.Or(nextCondition)
-		default:
-			return nil, fmt.Errorf("parse condition error: invalid logical operator: %s", operator)
+		return conditions.New().Not(condition), nil
+	case *CallExpr:
+		if e.Method == keywords.ANY || e.Method == keywords.ALL {
+			return compileQuantifierCondition(e)
 		}
+		return compileSingleWordsSimpleCondition(e)
+	default:
+		return nil, errorAt(expr.Position(), "invalid condition: expected condition method")
 	}
-
-	if _operator == "" && rest == "" {
-		return headCondition, nil
-	} else {
-		return recursiveParseCondition(headCondition, _operator, rest)
-	}
-
 }
 
-// split express like:
-// VALUE_OF(...) == "..."
-// LENGTH_OF(...) >= "..."
-func splitRelationalSimpleConditionExpression(expression string) (left, right, operator string, err error) {
-	index := -1
-	for _, _operator := range keywords.RELATIONAL_OPERATORS {
-		index = strings.Index(expression, _operator)
-		if index != -1 && !indexInQuota(index, expression) {
-			operator = _operator
-			break
-		}
+// compileQuantifierCondition compile ANY(key, condition) and ALL(key, condition)
+func compileQuantifierCondition(call *CallExpr) (conditions.Condition, error) {
+	if len(call.Args) != 2 {
+		return nil, errorAt(call.Pos, "invalid '%s' expression: number of parameters must be 2", call.Method)
 	}
-	if index == -1 {
-		return "", "", "", fmt.Errorf("invalid relational condition: relational operator not found: %s", expression)
+	key, err := compileKey(call, call.Args[0])
+	if err != nil {
+		return nil, err
 	}
-	return strings.TrimSpace(expression[:index]), strings.TrimSpace(expression[index+len(operator):]), operator, nil
-}
-
-func parseSimpleCondition(expression string) (conditions.Condition, error) {
-	i := strings.Index(expression, "(")
-	if i == -1 {
-		return nil, fmt.Errorf("invalid condition expression: must start with '%s': %s", "[ANY_METHOD](", expression)
+	condition, err := compileCondition(call.Args[1])
+	if err != nil {
+		return nil, err
 	}
-	method := expression[:i]
-	if _, ok := keywords.RELATIONAL_SIMPLE_CONDITION_METHODS[method]; ok {
-		return parseRelationalSimpleCondition(expression)
+	if call.Method == keywords.ANY {
+		return conditions.New().Any(key, condition), nil
 	}
+	return conditions.New().All(key, condition), nil
+}
 
-	if _, ok := keywords.SINGLE_WORDS_SIMPLE_CONDITION_METHODS[method]; ok {
-		return parseSingleWordsSimpleCondition(expression)
+// compileRelationalSimpleCondition like:
+// VALUE_OF(...) == "..."
+// LENGTH_OF(...) >= 3
+func compileRelationalSimpleCondition(expr *BinaryExpr) (conditions.Condition, error) {
+	call, ok := expr.Left.(*CallExpr)
+	if !ok {
+		return nil, errorAt(expr.Left.Position(), "invalid condition: expected condition method")
 	}
 
-	return nil, fmt.Errorf("invalid condition expression: unknown method '%s': %s", method, expression)
-}
+	f, ok := keywords.RELATIONAL_SIMPLE_CONDITION_METHODS[call.Method]
+	if !ok {
+		return nil, errorAt(call.Pos, "invalid condition expression: unknown method '%s'", call.Method)
+	}
 
-func parseRelationalSimpleCondition(expression string) (conditions.Condition, error) {
-	left, right, operator, err := splitRelationalSimpleConditionExpression(expression)
+	right, err := compileArgument(expr.Right)
 	if err != nil {
 		return nil, err
 	}
-
-	method, args, err := splitMethodExpression(left)
-
-	if f, ok := keywords.RELATIONAL_SIMPLE_CONDITION_METHODS[method]; !ok {
-		return nil, fmt.Errorf("invalid condition expression: unknown method '%s': %s", method, expression)
-	} else {
-		return f(operator, right, args...)
-	}
-}
-
-func parseSingleWordsSimpleCondition(expression string) (conditions.Condition, error) {
-	method, args, err := splitMethodExpression(expression)
+	args, err := compileArguments(call.Args)
 	if err != nil {
 		return nil, err
 	}
 
-	if f, ok := keywords.SINGLE_WORDS_SIMPLE_CONDITION_METHODS[method]; !ok {
-		return nil, fmt.Errorf("invalid condition expression: unknown method '%s': %s", method, expression)
-	} else {
-		return f(args...)
+	condition, err := f(expr.Operator, right, args...)
+	if err != nil {
+		return nil, errorAt(call.Pos, "%v", err)
 	}
+	return condition, nil
 }
 
-func indexInQuota(index int, expression string) bool {
-	inQuota := false
-	for i, char := range expression {
-		if char == '"' {
-			inQuota = !inQuota
-		}
-		if i == index {
-			return inQuota
-		}
+// compileMembershipSimpleCondition like:
+// VALUE_OF(...) IN ("...", "...")
+// VALUE_OF(...) NOT_IN ("...", "...")
+func compileMembershipSimpleCondition(expr *BinaryExpr) (conditions.Condition, error) {
+	call, ok := expr.Left.(*CallExpr)
+	if !ok {
+		return nil, errorAt(expr.Left.Position(), "invalid condition: expected condition method")
 	}
-	return false
-}
 
-func isWrappedByQuota(expression string) bool {
-	if len(expression) < 2 {
-		return false
-	}
-	if strings.HasPrefix(expression, "\"") && strings.HasSuffix(expression, "\"") {
-		return true
+	f, ok := keywords.MEMBERSHIP_CONDITION_METHODS[call.Method]
+	if !ok {
+		return nil, errorAt(call.Pos, "invalid condition expression: method '%s' does not support '%s'", call.Method, expr.Operator)
 	}
-	return false
-}
 
-// TODO support quotation
-// "expression0 || expression1 && expression2" => "expression0", "||", "expression1 && expression2"
-func splitCombinationConditionExpression(expression string) (conditionStr string, operator string, rest string, err error) {
-	expression = strings.TrimSpace(expression)
-	if strings.HasPrefix(expression, "(") {
-		_, index := common.FindFirstParenthesesPair(expression)
-		if index == -1 {
-			return "", "", "", fmt.Errorf("invalid condition: can not find corresponding ')': %s", expression)
-		}
-
-		head := strings.TrimSpace(expression[1:index])
-		operatorAndTail := strings.TrimSpace(expression[index+1:])
-		operator, rest, err = splitOperatorAndTail(operatorAndTail)
-		if err != nil {
-			return "", "", "", err
-		}
-		return head, operator, rest, nil
-	} else {
-		firstAndIndex := strings.Index(expression, keywords.OPERATOR_AND)
-		firstOrIndex := strings.Index(expression, keywords.OPERATOR_OR)
-
-		if firstAndIndex < 0 {
-			if firstOrIndex < 0 {
-				return expression, "", "", nil
-			} else if firstOrIndex == 0 {
-				return "", "", "", fmt.Errorf("invalid condition: must not start with '%s': %s", keywords.OPERATOR_OR, expression)
-			} else {
-				head := strings.TrimSpace(expression[:firstOrIndex])
-				operatorAndTail := strings.TrimSpace(expression[firstOrIndex:])
-				operator, rest, err = splitOperatorAndTail(operatorAndTail)
-				if err != nil {
-					return "", "", "", err
-				}
-				return head, operator, rest, nil
-			}
-		} else if firstAndIndex == 0 {
-			return "", "", "", fmt.Errorf("invalid condition: must not start with '%s': %s", keywords.OPERATOR_AND, expression)
-		} else {
-			if firstOrIndex < 0 {
-				head := strings.TrimSpace(expression[:firstAndIndex])
-				operatorAndTail := strings.TrimSpace(expression[firstAndIndex:])
-				operator, rest, err = splitOperatorAndTail(operatorAndTail)
-				if err != nil {
-					return "", "", "", err
-				}
-				return head, operator, rest, nil
-			} else if firstOrIndex == 0 {
-				return "", "", "", fmt.Errorf("invalid condition: must not start with '%s': %s", keywords.OPERATOR_OR, expression)
-			} else {
-				minIndex := Min(firstOrIndex, firstAndIndex)
-				head := strings.TrimSpace(expression[:minIndex])
-				operatorAndTail := strings.TrimSpace(expression[minIndex:])
-				operator, rest, err = splitOperatorAndTail(operatorAndTail)
-				if err != nil {
-					return "", "", "", err
-				}
-				return head, operator, rest, nil
-			}
-		}
+	list, ok := expr.Right.(*ListExpr)
+	if !ok {
+		return nil, errorAt(expr.Right.Position(), "invalid condition: expected list")
 	}
-}
-
-// TODO support quotation
-// "|| expression1 && expression2" => "||", "expression1 && expression2"
-func splitOperatorAndTail(operatorAndTail string) (string, string, error) {
-	if operatorAndTail == "" {
-		return "", "", nil
+	values, err := compileArguments(list.Items)
+	if err != nil {
+		return nil, err
 	}
-
-	if !strings.HasPrefix(operatorAndTail, keywords.OPERATOR_AND) && !strings.HasPrefix(operatorAndTail, keywords.OPERATOR_OR) {
-		return "", "", fmt.Errorf("invalid condition: invalid operator and tail: %s", operatorAndTail)
+	args, err := compileArguments(call.Args)
+	if err != nil {
+		return nil, err
 	}
 
-	operatorLen := 0
-	if strings.HasPrefix(operatorAndTail, keywords.OPERATOR_AND) {
-		operatorLen = len(keywords.OPERATOR_AND)
-	} else if strings.HasPrefix(operatorAndTail, keywords.OPERATOR_OR) {
-		operatorLen = len(keywords.OPERATOR_OR)
+	condition, err := f(expr.Operator, values, args...)
+	if err != nil {
+		return nil, errorAt(call.Pos, "%v", err)
 	}
+	return condition, nil
+}
 
-	operator := operatorAndTail[:operatorLen]
+// compileSingleWordsSimpleCondition like:
+// EXISTS(...)
+// HAS_PREFIX(..., "...")
+func compileSingleWordsSimpleCondition(call *CallExpr) (conditions.Condition, error) {
+	f, ok := keywords.SINGLE_WORDS_SIMPLE_CONDITION_METHODS[call.Method]
+	if !ok {
+		return nil, errorAt(call.Pos, "invalid condition expression: unknown method '%s'", call.Method)
+	}
 
-	tail := strings.TrimSpace(operatorAndTail[operatorLen:])
-	if tail == "" {
-		return "", "", fmt.Errorf("invalid condition: invalid operator and tail: %s", operatorAndTail)
+	args, err := compileArguments(call.Args)
+	if err != nil {
+		return nil, err
 	}
 
-	return operator, tail, nil
+	condition, err := f(args...)
+	if err != nil {
+		return nil, errorAt(call.Pos, "%v", err)
+	}
+	return condition, nil
 }
 
-func Min(a, b int) int {
-	if a < b {
-		return a
+func compileArguments(exprs []Expr) ([]keywords.Argument, error) {
+	var args []keywords.Argument
+	for _, expr := range exprs {
+		arg, err := compileArgument(expr)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
 	}
-	return b
+	return args, nil
 }
@@ -4,482 +4,255 @@ import (
 	"github.com/storm-blue/rubick/pkg/modifier/action"
 	"github.com/storm-blue/rubick/pkg/modifier/conditions"
 	"reflect"
+	"regexp"
 	"testing"
 )
 
-func Test_splitExpression(t *testing.T) {
-	tests := []struct {
-		name               string
-		expression         string
-		wantConditionPart  string
-		wantPureActionPart string
-		wantErr            bool
-	}{
-		{
-			name:               "TEST1",
-			expression:         "IF VALUE_OF(a.b.c)==true THEN DELETE(z.x.f)",
-			wantConditionPart:  "VALUE_OF(a.b.c)==true",
-			wantPureActionPart: "DELETE(z.x.f)",
-			wantErr:            false,
-		},
-		{
-			name:               "TEST2",
-			expression:         "IF VALUE_OF(a.b.c)==true THEN",
-			wantConditionPart:  "",
-			wantPureActionPart: "",
-			wantErr:            true,
-		},
-		{
-			name:               "TEST3",
-			expression:         "IF   VALUE_OF(a.b.c)==true     THEN     DELETE(a.b.c)",
-			wantConditionPart:  "VALUE_OF(a.b.c)==true",
-			wantPureActionPart: "DELETE(a.b.c)",
-			wantErr:            false,
-		},
-		{
-			name:               "TEST4",
-			expression:         "     DELETE(a.b.c)",
-			wantConditionPart:  "",
-			wantPureActionPart: "DELETE(a.b.c)",
-			wantErr:            false,
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			gotConditionPart, gotPureActionPart, err := splitExpression(tt.expression)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("splitExpression() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			if gotConditionPart != tt.wantConditionPart {
-				t.Errorf("splitExpression() gotConditionPart = %v, want %v", gotConditionPart, tt.wantConditionPart)
-			}
-			if gotPureActionPart != tt.wantPureActionPart {
-				t.Errorf("splitExpression() gotPureActionPart = %v, want %v", gotPureActionPart, tt.wantPureActionPart)
-			}
-		})
-	}
-}
-
-func Test_splitCombinationConditionExpression(t *testing.T) {
-	tests := []struct {
-		name             string
-		expression       string
-		wantConditionStr string
-		wantOperator     string
-		wantRest         string
-		wantErr          bool
-	}{
-		{
-			name:             "TEST1",
-			expression:       "(VALUE_OF(a.b.c)==12)||VALUE_OF(z.x)==why",
-			wantConditionStr: "VALUE_OF(a.b.c)==12",
-			wantOperator:     "||",
-			wantRest:         "VALUE_OF(z.x)==why",
-			wantErr:          false,
-		},
-		{
-			name:             "TEST2",
-			expression:       "VALUE_OF(a.b.c)==12||VALUE_OF(z.x)==why",
-			wantConditionStr: "VALUE_OF(a.b.c)==12",
-			wantOperator:     "||",
-			wantRest:         "VALUE_OF(z.x)==why",
-			wantErr:          false,
-		},
-		{
-			name:             "TEST3",
-			expression:       "VALUE_OF(a.b.c)==12||VALUE_OF(z.x)==why&&VALUE_OF(x.c)==shit",
-			wantConditionStr: "VALUE_OF(a.b.c)==12",
-			wantOperator:     "||",
-			wantRest:         "VALUE_OF(z.x)==why&&VALUE_OF(x.c)==shit",
-			wantErr:          false,
-		},
-		{
-			name:             "TEST4",
-			expression:       "VALUE_OF(a.b.c)==12&&VALUE_OF(z.x)==why||VALUE_OF(x.c)==shit",
-			wantConditionStr: "VALUE_OF(a.b.c)==12",
-			wantOperator:     "&&",
-			wantRest:         "VALUE_OF(z.x)==why||VALUE_OF(x.c)==shit",
-			wantErr:          false,
-		},
-		{
-			name:             "TEST5",
-			expression:       "VALUE_OF(a.b.c)==12  &&    VALUE_OF(z.x)==why||VALUE_OF(x.c)==shit    ",
-			wantConditionStr: "VALUE_OF(a.b.c)==12",
-			wantOperator:     "&&",
-			wantRest:         "VALUE_OF(z.x)==why||VALUE_OF(x.c)==shit",
-			wantErr:          false,
-		},
-		{
-			name:             "TEST6",
-			expression:       "(VALUE_OF(a.b.c)==12   ) &&   (    VALUE_OF(z.x)==why||VALUE_OF(x.c)==shit)    ",
-			wantConditionStr: "VALUE_OF(a.b.c)==12",
-			wantOperator:     "&&",
-			wantRest:         "(    VALUE_OF(z.x)==why||VALUE_OF(x.c)==shit)",
-			wantErr:          false,
-		},
-		{
-			name:             "TEST7",
-			expression:       "(VALUE_OF(a.b.c)==12   ) &&   ",
-			wantConditionStr: "",
-			wantOperator:     "",
-			wantRest:         "",
-			wantErr:          true,
-		},
-		{
-			name:             "TEST8",
-			expression:       "(||VALUE_OF(a.b.c)==12   )    ",
-			wantConditionStr: "||VALUE_OF(a.b.c)==12",
-			wantOperator:     "",
-			wantRest:         "",
-			wantErr:          false,
-		},
-		{
-			name:             "TEST9",
-			expression:       "||(||VALUE_OF(a.b.c)==12   )    ",
-			wantConditionStr: "",
-			wantOperator:     "",
-			wantRest:         "",
-			wantErr:          true,
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			gotConditionStr, gotOperator, gotRest, err := splitCombinationConditionExpression(tt.expression)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("splitCombinationConditionExpression() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			if gotConditionStr != tt.wantConditionStr {
-				t.Errorf("splitCombinationConditionExpression() gotConditionStr = %v, want %v", gotConditionStr, tt.wantConditionStr)
-			}
-			if gotOperator != tt.wantOperator {
-				t.Errorf("splitCombinationConditionExpression() gotOperator = %v, want %v", gotOperator, tt.wantOperator)
-			}
-			if gotRest != tt.wantRest {
-				t.Errorf("splitCombinationConditionExpression() gotRest = %v, want %v", gotRest, tt.wantRest)
-			}
-		})
-	}
-}
-
-func Test_indexInQuota(t *testing.T) {
+func TestParseAction(t *testing.T) {
 	tests := []struct {
 		name       string
-		index      int
 		expression string
-		want       bool
+		want       action.Action
+		wantErr    bool
 	}{
 		{
 			name:       "TEST1",
-			index:      3,
-			expression: `my "baby"`,
-			want:       true,
+			expression: "DELETE(a.b.c)",
+			want:       action.NewDeleteAction("a.b.c"),
+			wantErr:    false,
 		},
 		{
 			name:       "TEST2",
-			index:      4,
-			expression: `my "baby"`,
-			want:       true,
+			expression: "DELETE(a.b.c,z.yz)",
+			want:       nil,
+			wantErr:    true,
 		},
 		{
 			name:       "TEST3",
-			index:      5,
-			expression: `my "baby"`,
-			want:       true,
+			expression: "SET(a.b.c,   z.yz)",
+			want:       action.NewSetAction("a.b.c", action.Original("z.yz")),
+			wantErr:    false,
 		},
 		{
 			name:       "TEST4",
-			index:      2,
-			expression: `my "baby"`,
-			want:       false,
+			expression: `SET(a.b.c, VALUE_OF("z.yz"))`,
+			want:       action.NewSetAction("a.b.c", action.ValueOf("z.yz")),
+			wantErr:    false,
 		},
 		{
 			name:       "TEST5",
-			index:      10,
-			expression: `my "baby",i love "you"`,
-			want:       false,
+			expression: `SET(a.b.c, VALUE_OF("  z.yz"))`,
+			want:       nil,
+			wantErr:    true,
 		},
 		{
 			name:       "TEST6",
-			index:      17,
-			expression: `my "baby",i love "you"`,
-			want:       true,
+			expression: `SET(a.b.c, VALUE_OF(  z.yz))`,
+			want:       action.NewSetAction("a.b.c", action.ValueOf("z.yz")),
+			wantErr:    false,
 		},
 		{
 			name:       "TEST7",
-			index:      22,
-			expression: `my "baby",i love "you"`,
-			want:       false,
+			expression: `SET(a.b.c, VALUE_OF(z.yz))`,
+			want:       action.NewSetAction("a.b.c", action.ValueOf("z.yz")),
+			wantErr:    false,
+		},
+		{
+			name:       "TEST8",
+			expression: `SET(metadata.annotations.note, "a, b")`,
+			want:       action.NewSetAction("metadata.annotations.note", action.Original("a, b")),
+			wantErr:    false,
 		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := indexInQuota(tt.index, tt.expression); got != tt.want {
-				t.Errorf("indexInQuota() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
-func Test_splitRelationalSimpleConditionExpression(t *testing.T) {
-	tests := []struct {
-		name         string
-		expression   string
-		wantLeft     string
-		wantRight    string
-		wantOperator string
-		wantErr      bool
-	}{
 		{
-			name:         "TEST1",
-			expression:   `a==b`,
-			wantLeft:     "a",
-			wantRight:    "b",
-			wantOperator: "==",
-			wantErr:      false,
+			name:       "TEST9",
+			expression: `SET(metadata.annotations.note, "say \"hi\"\n")`,
+			want:       action.NewSetAction("metadata.annotations.note", action.Original("say \"hi\"\n")),
+			wantErr:    false,
+		},
+		{
+			name:       "TEST10",
+			expression: `REPLACE_PART(metadata.name, "(a)", ")b,")`,
+			want:       action.NewReplacePartAction("metadata.name", action.Original("(a)"), action.Original(")b,")),
+			wantErr:    false,
 		},
 		{
-			name:         "TEST2",
-			expression:   `a  <==   b d`,
-			wantLeft:     "a  <",
-			wantRight:    "b d",
-			wantOperator: "==",
-			wantErr:      false,
+			name:       "TEST11",
+			expression: `SET(a.b.c, "unterminated)`,
+			want:       nil,
+			wantErr:    true,
 		},
 		{
-			name:         "TEST3",
-			expression:   `a  <="==   b d"`,
-			wantLeft:     "a",
-			wantRight:    `"==   b d"`,
-			wantOperator: "<=",
-			wantErr:      false,
+			name:       "TEST12",
+			expression: `DELETE(a.b.c) DELETE(z.yz)`,
+			want:       nil,
+			wantErr:    true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotLeft, gotRight, gotOperator, err := splitRelationalSimpleConditionExpression(tt.expression)
+			got, err := ParseAction(tt.expression)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("splitRelationalSimpleConditionExpression() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("ParseAction() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if gotLeft != tt.wantLeft {
-				t.Errorf("splitRelationalSimpleConditionExpression() gotLeft = %v, want %v", gotLeft, tt.wantLeft)
-			}
-			if gotRight != tt.wantRight {
-				t.Errorf("splitRelationalSimpleConditionExpression() gotRight = %v, want %v", gotRight, tt.wantRight)
-			}
-			if gotOperator != tt.wantOperator {
-				t.Errorf("splitRelationalSimpleConditionExpression() gotOperator = %v, want %v", gotOperator, tt.wantOperator)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseAction() got = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func Test_splitMethodExpression(t *testing.T) {
+func Test_parseCondition(t *testing.T) {
 	tests := []struct {
 		name       string
 		expression string
-		wantMethod string
-		wantArgs   []string
+		want       conditions.Condition
 		wantErr    bool
 	}{
 		{
 			name:       "TEST1",
-			expression: "ABC(a,b,c)",
-			wantMethod: "ABC",
-			wantArgs:   []string{"a", "b", "c"},
+			expression: "(VALUE_OF(x.y.z)==3 && VALUE_OF(a.b.c)>=18)",
+			want:       conditions.New().ValueOf("x.y.z").EqualTo("3").And(conditions.New().ValueOf("a.b.c").GreaterThanOrEqual("18")),
 			wantErr:    false,
 		},
 		{
 			name:       "TEST2",
-			expression: "ABC(a , b,    c)",
-			wantMethod: "ABC",
-			wantArgs:   []string{"a", "b", "c"},
+			expression: "((((VALUE_OF(x.y.z)==3)) && (VALUE_OF(a.b.c)>=18)))",
+			want:       conditions.New().ValueOf("x.y.z").EqualTo("3").And(conditions.New().ValueOf("a.b.c").GreaterThanOrEqual("18")),
 			wantErr:    false,
 		},
 		{
 			name:       "TEST3",
-			expression: `ABC(a , b,"    c")`,
-			wantMethod: "ABC",
-			wantArgs:   []string{"a", "b", `"    c"`},
+			expression: "((((VALUE_OF(x.y.z)==3)) && (VALUE_OF(a.b.c)>=18 || VALUE_OF(a.b.c[0]) == shit )))",
+			want:       conditions.New().ValueOf("x.y.z").EqualTo("3").And(conditions.New().ValueOf("a.b.c").GreaterThanOrEqual("18").Or(conditions.New().ValueOf("a.b.c[0]").EqualTo("shit"))),
 			wantErr:    false,
 		},
 		{
 			name:       "TEST4",
-			expression: `ABC()`,
-			wantMethod: "ABC",
-			wantArgs:   nil,
-			wantErr:    false,
+			expression: "((((VALUE_OF(x.y.z)==3)) && (VALUE_OF(a.b.c)>=18 || VALUE_OF(a.b.c[0]) == shit ) )))",
+			want:       nil,
+			wantErr:    true,
 		},
 		{
-			name:       "TEST4",
-			expression: `ABC(a,"adb,")`,
-			wantMethod: "ABC",
-			wantArgs:   []string{"a", `"adb`, `"`},
-			wantErr:    false,
+			name:       "TEST5",
+			expression: "((((VALUE_OF(x.y.z)==3)) && (VALUE_OF(a.b.c)>=18 || VALUE_OF(a.b.c[0]) == shit ))) || (VALUE_OF(z)<1.2 && (VALUE_OF(xxx) <= shit))",
+			want: conditions.New().ValueOf("x.y.z").EqualTo("3").And(conditions.New().ValueOf("a.b.c").GreaterThanOrEqual("18").Or(conditions.New().ValueOf("a.b.c[0]").EqualTo("shit"))).
+				Or(conditions.New().ValueOf("z").LesserThan("1.2").And(conditions.New().ValueOf("xxx").LesserThanOrEqual("shit"))),
+			wantErr: false,
 		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			gotMethod, gotArgs, err := splitMethodExpression(tt.expression)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("splitMethodExpression() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			if gotMethod != tt.wantMethod {
-				t.Errorf("splitMethodExpression() gotMethod = %v, want %v", gotMethod, tt.wantMethod)
-			}
-			if !reflect.DeepEqual(gotArgs, tt.wantArgs) {
-				t.Errorf("splitMethodExpression() gotArgs = %v, want %v", gotArgs, tt.wantArgs)
-			}
-		})
-	}
-}
-
-func Test_isSimpleCondition(t *testing.T) {
-	tests := []struct {
-		name       string
-		expression string
-		want       bool
-	}{
 		{
-			name:       "TEST1",
-			expression: "(VALUE_OF(z.x.b)==3)",
-			want:       false,
+			name:       "TEST6",
+			expression: `HAS_PREFIX(x, "a&&b") || HAS_SUFFIX(x, "c||d)")`,
+			want:       conditions.New().HasPrefix("x", "a&&b").Or(conditions.New().HasSuffix("x", "c||d)")),
+			wantErr:    false,
 		},
 		{
-			name:       "TEST2",
-			expression: "VALUE_OF(z.x.b)==3)",
-			want:       true,
+			name:       "TEST7",
+			expression: `VALUE_OF(metadata.labels.(github.io/app)) == "a == b"`,
+			want:       conditions.New().ValueOf("metadata.labels.(github.io/app)").EqualTo("a == b"),
+			wantErr:    false,
 		},
 		{
-			name:       "TEST3",
-			expression: "VALUE_OF(z.x.b)==3)||VALUE_OF(a)>5",
-			want:       false,
+			name:       "TEST8",
+			expression: `EXISTS(a) || EXISTS(b) && EXISTS(c)`,
+			want:       conditions.New().Exists("a").Or(conditions.New().Exists("b").And(conditions.New().Exists("c"))),
+			wantErr:    false,
 		},
 		{
-			name:       "TEST4",
-			expression: "(VALUE_OF(z.x.b)==3)|| VALUE_OF(a)>5",
-			want:       false,
+			name:       "TEST9",
+			expression: `EXISTS(a) && EXISTS(b) || EXISTS(c) && EXISTS(d)`,
+			want: conditions.New().Exists("a").And(conditions.New().Exists("b")).
+				Or(conditions.New().Exists("c").And(conditions.New().Exists("d"))),
+			wantErr: false,
 		},
 		{
-			name:       "TEST5",
-			expression: "(VALUE_OF(z.x.b)==3)VALUE_OF(a)>5",
-			want:       false,
+			name:       "TEST10",
+			expression: `!EXISTS(a) && NOT VALUE_OF(b) == 1`,
+			want:       conditions.New().Not(conditions.New().Exists("a")).And(conditions.New().Not(conditions.New().ValueOf("b").EqualTo("1"))),
+			wantErr:    false,
 		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := isSimpleCondition(tt.expression); got != tt.want {
-				t.Errorf("isSimpleCondition() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
-func Test_parsePureAction(t *testing.T) {
-	tests := []struct {
-		name       string
-		expression string
-		want       action.Action
-		wantErr    bool
-	}{
 		{
-			name:       "TEST1",
-			expression: "DELETE(a.b.c)",
-			want:       action.NewDeleteAction("a.b.c"),
-			wantErr:    false,
+			name:       "TEST11",
+			expression: `!(EXISTS(a) || EXISTS(b)) && !!EXISTS(c)`,
+			want: conditions.New().Not(conditions.New().Exists("a").Or(conditions.New().Exists("b"))).
+				And(conditions.New().Not(conditions.New().Not(conditions.New().Exists("c")))),
+			wantErr: false,
 		},
 		{
-			name:       "TEST2",
-			expression: "DELETE(a.b.c,z.yz)",
+			name:       "TEST12",
+			expression: `EXISTS(a) && !`,
 			want:       nil,
 			wantErr:    true,
 		},
 		{
-			name:       "TEST3",
-			expression: "SET(a.b.c,   z.yz)",
-			want:       action.NewSetAction("a.b.c", action.Original("z.yz")),
+			name:       "TEST13",
+			expression: `MATCHES(metadata.name, "^app-(\\d+)$")`,
+			want:       conditions.New().Matches("metadata.name", regexp.MustCompile(`^app-(\d+)$`)),
 			wantErr:    false,
 		},
 		{
-			name:       "TEST4",
-			expression: `SET(a.b.c, VALUE_OF("z.yz"))`,
-			want:       action.NewSetAction("a.b.c", action.ValueOf("z.yz")),
-			wantErr:    false,
-		},
-		{
-			name:       "TEST5",
-			expression: `SET(a.b.c, VALUE_OF("  z.yz"))`,
+			name:       "TEST14",
+			expression: `MATCHES(metadata.name, "app-(")`,
 			want:       nil,
 			wantErr:    true,
 		},
 		{
-			name:       "TEST6",
-			expression: `SET(a.b.c, VALUE_OF(  z.yz))`,
-			want:       action.NewSetAction("a.b.c", action.ValueOf("z.yz")),
-			wantErr:    false,
+			name:       "TEST15",
+			expression: `MATCHES(metadata.name, $pattern)`,
+			want:       nil,
+			wantErr:    true,
 		},
 		{
-			name:       "TEST7",
-			expression: `SET(a.b.c, VALUE_OF(z.yz))`,
-			want:       action.NewSetAction("a.b.c", action.ValueOf("z.yz")),
-			wantErr:    false,
+			name:       "TEST16",
+			expression: `VALUE_OF(metadata.namespace) IN ("a", b) || VALUE_OF(metadata.namespace) NOT_IN ()`,
+			want: conditions.New().ValueOf("metadata.namespace").In("a", "b").
+				Or(conditions.New().ValueOf("metadata.namespace").NotIn()),
+			wantErr: false,
 		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got, err := parsePureAction(tt.expression)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("parsePureAction() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("parsePureAction() got = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
-func Test_parseCondition(t *testing.T) {
-	tests := []struct {
-		name       string
-		expression string
-		want       conditions.Condition
-		wantErr    bool
-	}{
 		{
-			name:       "TEST1",
-			expression: "(VALUE_OF(x.y.z)==3 && VALUE_OF(a.b.c)>=18)",
-			want:       conditions.New().ValueOf("x.y.z").EqualTo("3").And(conditions.New().ValueOf("a.b.c").GreaterThanOrEqual("18")),
+			name:       "TEST17",
+			expression: `CONTAINS(spec.image, "registry.old.com")`,
+			want:       conditions.New().Contains("spec.image", "registry.old.com"),
 			wantErr:    false,
 		},
 		{
-			name:       "TEST2",
-			expression: "((((VALUE_OF(x.y.z)==3)) && (VALUE_OF(a.b.c)>=18)))",
-			want:       conditions.New().ValueOf("x.y.z").EqualTo("3").And(conditions.New().ValueOf("a.b.c").GreaterThanOrEqual("18")),
-			wantErr:    false,
+			name:       "TEST18",
+			expression: `LENGTH_OF(spec.ports) IN (1, 2)`,
+			want:       nil,
+			wantErr:    true,
 		},
 		{
-			name:       "TEST3",
-			expression: "((((VALUE_OF(x.y.z)==3)) && (VALUE_OF(a.b.c)>=18 || VALUE_OF(a.b.c[0]) == shit )))",
-			want:       conditions.New().ValueOf("x.y.z").EqualTo("3").And(conditions.New().ValueOf("a.b.c").GreaterThanOrEqual("18").Or(conditions.New().ValueOf("a.b.c[0]").EqualTo("shit"))),
-			wantErr:    false,
+			name:       "TEST19",
+			expression: `TYPE_OF(spec.port) != "string" && IS_NULL(spec.host)`,
+			want: conditions.New().TypeOf("spec.port").NotEqual(conditions.TypeString).
+				And(conditions.New().TypeOf("spec.host").EqualTo(conditions.TypeNull)),
+			wantErr: false,
 		},
 		{
-			name:       "TEST4",
-			expression: "((((VALUE_OF(x.y.z)==3)) && (VALUE_OF(a.b.c)>=18 || VALUE_OF(a.b.c[0]) == shit ) )))",
+			name:       "TEST20",
+			expression: `TYPE_OF(spec.port) == "integer"`,
 			want:       nil,
 			wantErr:    true,
 		},
 		{
-			name:       "TEST5",
-			expression: "((((VALUE_OF(x.y.z)==3)) && (VALUE_OF(a.b.c)>=18 || VALUE_OF(a.b.c[0]) == shit ))) || (VALUE_OF(z)<1.2 && (VALUE_OF(xxx) <= shit))",
-			want: conditions.New().ValueOf("x.y.z").EqualTo("3").And(conditions.New().ValueOf("a.b.c").GreaterThanOrEqual("18").Or(conditions.New().ValueOf("a.b.c[0]").EqualTo("shit"))).
-				Or(conditions.New().ValueOf("z").LesserThan("1.2").And(conditions.New().ValueOf("xxx").LesserThanOrEqual("shit"))),
+			name:       "TEST21",
+			expression: `ANY(spec.containers[*].image, HAS_PREFIX(@, "old/"))`,
+			want:       conditions.New().Any("spec.containers[*].image", conditions.New().HasPrefix("@", "old/")),
+			wantErr:    false,
+		},
+		{
+			name:       "TEST22",
+			expression: `ALL(spec.containers, EXISTS(resources) && name != "debug")`,
+			want: conditions.New().All("spec.containers", conditions.New().Exists("resources").
+				And(conditions.New().ValueOf("name").NotEqual("debug"))),
 			wantErr: false,
 		},
+		{
+			name:       "TEST23",
+			expression: `ANY(spec.containers)`,
+			want:       nil,
+			wantErr:    true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -523,7 +296,7 @@ func Test_parseValueOfSimpleCondition(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := parseRelationalSimpleCondition(tt.expression)
+			got, err := parseCondition(tt.expression)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("parseSimpleCondition() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -553,8 +326,8 @@ func Test_parseToNumberIfPossible(t *testing.T) {
 		},
 		{
 			name: "TEST3",
-			arg:  `"1.0"`,
-			want: "1.0",
+			arg:  "a.b",
+			want: "a.b",
 		},
 	}
 	for _, tt := range tests {
@@ -594,7 +367,7 @@ func Test_parseExistsSimpleCondition(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := parseSingleWordsSimpleCondition(tt.expression)
+			got, err := parseCondition(tt.expression)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("parseExistsSimpleCondition() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -634,7 +407,7 @@ func Test_parseNotExistsSimpleCondition(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := parseSingleWordsSimpleCondition(tt.expression)
+			got, err := parseCondition(tt.expression)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("parseNotExistsSimpleCondition() error = %v, wantErr %v", err, tt.wantErr)
 				return
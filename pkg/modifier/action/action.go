@@ -3,6 +3,7 @@ package action
 import (
 	"fmt"
 	"github.com/storm-blue/rubick/pkg/modifier/objects"
+	"strings"
 )
 
 type Action interface {
@@ -10,8 +11,10 @@ type Action interface {
 	String() string
 }
 
+// Valuable is a value calculated from the object when action is done.
 type Valuable interface {
-	getValue(object objects.StructuredObject) (interface{}, error)
+	GetValue(object objects.StructuredObject) (interface{}, error)
+	String() string
 }
 
 func Original(v interface{}) Valuable {
@@ -22,10 +25,17 @@ type originalValue struct {
 	value interface{}
 }
 
-func (o *originalValue) getValue(_ objects.StructuredObject) (interface{}, error) {
+func (o *originalValue) GetValue(_ objects.StructuredObject) (interface{}, error) {
 	return o.value, nil
 }
 
+func (o *originalValue) String() string {
+	if o.value == nil {
+		return "null"
+	}
+	return fmt.Sprintf("%v", o.value)
+}
+
 func ValueOf(key string) Valuable {
 	return &valueOfKeyValue{key: key}
 }
@@ -34,10 +44,41 @@ type valueOfKeyValue struct {
 	key string
 }
 
-func (o *valueOfKeyValue) getValue(object objects.StructuredObject) (interface{}, error) {
+func (o *valueOfKeyValue) GetValue(object objects.StructuredObject) (interface{}, error) {
 	return object.Get(o.key)
 }
 
+func (o *valueOfKeyValue) String() string {
+	return fmt.Sprintf("VALUE_OF(%v)", o.key)
+}
+
+// Concat join the string form of values, nil values are treated as empty string.
+func Concat(values ...Valuable) Valuable {
+	return &concatValue{values: values}
+}
+
+type concatValue struct {
+	values []Valuable
+}
+
+func (c *concatValue) GetValue(object objects.StructuredObject) (interface{}, error) {
+	builder := strings.Builder{}
+	for _, value := range c.values {
+		v, err := value.GetValue(object)
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			builder.WriteString(fmt.Sprintf("%v", v))
+		}
+	}
+	return builder.String(), nil
+}
+
+func (c *concatValue) String() string {
+	return fmt.Sprintf("CONCAT(%v)", joinValuables(c.values))
+}
+
 // -- delete action --
 
 func NewDeleteAction(key string) Action {
@@ -70,7 +111,7 @@ type setAction struct {
 }
 
 func (s *setAction) DoAction(context Context, object objects.StructuredObject) {
-	v, err := s.value.getValue(object)
+	v, err := s.value.GetValue(object)
 	if err != nil {
 		context.Log(object, s, err)
 		return
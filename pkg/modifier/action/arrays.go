@@ -0,0 +1,266 @@
+package action
+
+import (
+	"fmt"
+	"github.com/storm-blue/rubick/pkg/modifier/conditions"
+	"github.com/storm-blue/rubick/pkg/modifier/objects"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// -- append action --
+
+// NewAppendAction append value to the end of array, like: APPEND(spec.template.spec.containers[*].args, "--debug")
+func NewAppendAction(key string, value Valuable) Action {
+	return &appendAction{key: key, value: value}
+}
+
+type appendAction struct {
+	key   string
+	value Valuable
+}
+
+func (a *appendAction) DoAction(context Context, object objects.StructuredObject) {
+	v, err := a.value.GetValue(object)
+	if err != nil {
+		context.Log(object, a, err)
+		return
+	}
+	if err := object.UpdateArrays(a.key, func(array []interface{}) ([]interface{}, error) {
+		return append(array, objects.DeepCopy(v)), nil
+	}); err != nil {
+		context.Log(object, a, err)
+	}
+}
+
+func (a *appendAction) String() string {
+	return fmt.Sprintf("AppendAction: key=%v, value=%v", a.key, a.value)
+}
+
+// -- prepend action --
+
+// NewPrependAction insert value to the beginning of array, like: PREPEND(spec.template.spec.containers[*].args, "--debug")
+func NewPrependAction(key string, value Valuable) Action {
+	return &prependAction{key: key, value: value}
+}
+
+type prependAction struct {
+	key   string
+	value Valuable
+}
+
+func (a *prependAction) DoAction(context Context, object objects.StructuredObject) {
+	v, err := a.value.GetValue(object)
+	if err != nil {
+		context.Log(object, a, err)
+		return
+	}
+	if err := object.UpdateArrays(a.key, func(array []interface{}) ([]interface{}, error) {
+		return append([]interface{}{objects.DeepCopy(v)}, array...), nil
+	}); err != nil {
+		context.Log(object, a, err)
+	}
+}
+
+func (a *prependAction) String() string {
+	return fmt.Sprintf("PrependAction: key=%v, value=%v", a.key, a.value)
+}
+
+// -- insert at action --
+
+// NewInsertAtAction insert value before the element at index, index equals to the length of array means append.
+// like: INSERT_AT(spec.template.spec.containers[*].args, 1, "--debug")
+func NewInsertAtAction(key string, index Valuable, value Valuable) Action {
+	return &insertAtAction{key: key, index: index, value: value}
+}
+
+type insertAtAction struct {
+	key   string
+	index Valuable
+	value Valuable
+}
+
+func (a *insertAtAction) DoAction(context Context, object objects.StructuredObject) {
+	index, err := getIntValue(a.index, object)
+	if err != nil {
+		context.Log(object, a, err)
+		return
+	}
+	v, err := a.value.GetValue(object)
+	if err != nil {
+		context.Log(object, a, err)
+		return
+	}
+	if err := object.UpdateArrays(a.key, func(array []interface{}) ([]interface{}, error) {
+		if index < 0 || index > len(array) {
+			return nil, fmt.Errorf("insert element error: out of range: %v, index: %v", array, index)
+		}
+		return slices.Insert(array, index, objects.DeepCopy(v)), nil
+	}); err != nil {
+		context.Log(object, a, err)
+	}
+}
+
+func (a *insertAtAction) String() string {
+	return fmt.Sprintf("InsertAtAction: key=%v, index=%v, value=%v", a.key, a.index, a.value)
+}
+
+// -- remove where action --
+
+// NewRemoveWhereAction remove all elements of array which match the condition,
+// condition is calculated on each element, like: REMOVE_WHERE(spec.template.spec.containers[*].env, name == "DEBUG")
+func NewRemoveWhereAction(key string, condition conditions.Condition) Action {
+	return &removeWhereAction{key: key, condition: condition}
+}
+
+type removeWhereAction struct {
+	key       string
+	condition conditions.Condition
+}
+
+func (a *removeWhereAction) DoAction(context Context, object objects.StructuredObject) {
+	if err := object.UpdateArrays(a.key, func(array []interface{}) ([]interface{}, error) {
+		var result []interface{}
+		for _, e := range array {
+			r, err := conditions.Calculate(a.condition, objects.ElementObject(object, e), context.ErrorPolicy(), func(err error) {
+				context.Log(object, a, err)
+			})
+			if err != nil {
+				return nil, err
+			}
+			if !r {
+				result = append(result, e)
+			}
+		}
+		return result, nil
+	}); err != nil {
+		context.Log(object, a, err)
+	}
+}
+
+func (a *removeWhereAction) String() string {
+	return fmt.Sprintf("RemoveWhereAction: key=%v, condition=%v", a.key, a.condition)
+}
+
+// -- sort action --
+
+// NewSortAction sort array by the value of field of map elements, or by the elements themselves if field is empty.
+// numbers are compared by value, others are compared as strings, missing values are sorted to the end.
+// like: SORT(spec.ports, port)
+func NewSortAction(key string, field string) Action {
+	return &sortAction{key: key, field: field}
+}
+
+type sortAction struct {
+	key   string
+	field string
+}
+
+func (a *sortAction) DoAction(context Context, object objects.StructuredObject) {
+	if err := object.UpdateArrays(a.key, func(array []interface{}) ([]interface{}, error) {
+		values := make(map[int]interface{}, len(array))
+		indexes := make([]int, len(array))
+		for i, e := range array {
+			v, err := elementValue(object, e, a.field)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = v
+			indexes[i] = i
+		}
+		slices.SortStableFunc(indexes, func(i, j int) int {
+			return compareValues(values[i], values[j])
+		})
+
+		result := make([]interface{}, len(array))
+		for i, index := range indexes {
+			result[i] = array[index]
+		}
+		return result, nil
+	}); err != nil {
+		context.Log(object, a, err)
+	}
+}
+
+func (a *sortAction) String() string {
+	return fmt.Sprintf("SortAction: key=%v, field=%v", a.key, a.field)
+}
+
+// -- unique action --
+
+// NewUniqueAction remove duplicated elements of array and keep the first one, elements are compared by
+// the value of field of map elements, or by the elements themselves if field is empty.
+// like: UNIQUE(spec.template.spec.containers[*].env, name)
+func NewUniqueAction(key string, field string) Action {
+	return &uniqueAction{key: key, field: field}
+}
+
+type uniqueAction struct {
+	key   string
+	field string
+}
+
+func (a *uniqueAction) DoAction(context Context, object objects.StructuredObject) {
+	if err := object.UpdateArrays(a.key, func(array []interface{}) ([]interface{}, error) {
+		var result []interface{}
+		var seen []interface{}
+		for _, e := range array {
+			v, err := elementValue(object, e, a.field)
+			if err != nil {
+				return nil, err
+			}
+			if slices.ContainsFunc(seen, func(s interface{}) bool { return reflect.DeepEqual(s, v) }) {
+				continue
+			}
+			seen = append(seen, v)
+			result = append(result, e)
+		}
+		return result, nil
+	}); err != nil {
+		context.Log(object, a, err)
+	}
+}
+
+func (a *uniqueAction) String() string {
+	return fmt.Sprintf("UniqueAction: key=%v, field=%v", a.key, a.field)
+}
+
+// elementValue get the value of field of element, returns element itself if field is empty.
+func elementValue(object objects.StructuredObject, element interface{}, field string) (interface{}, error) {
+	if field == "" {
+		return element, nil
+	}
+	m, ok := element.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("ojbect is not map: %v", element)
+	}
+	return objects.ElementObject(object, m).Get(field)
+}
+
+// compareValues compare numbers by value and others as strings, nil is greater than any other value.
+func compareValues(a, b interface{}) int {
+	if a == nil || b == nil {
+		switch {
+		case a == b:
+			return 0
+		case a == nil:
+			return 1
+		default:
+			return -1
+		}
+	}
+	af, aIsNumber := toFloat(a)
+	bf, bIsNumber := toFloat(b)
+	if aIsNumber && bIsNumber {
+		switch {
+		case af < bf:
+			return -1
+		case af > bf:
+			return 1
+		default:
+			return 0
+		}
+	}
+	return strings.Compare(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
+}
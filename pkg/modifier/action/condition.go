@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/storm-blue/rubick/pkg/modifier/conditions"
 	"github.com/storm-blue/rubick/pkg/modifier/objects"
+	"strings"
 )
 
 // -- condition action --
@@ -15,13 +16,25 @@ func NewConditionAction(condition conditions.Condition, action Action) Action {
 	}
 }
 
+// NewConditionElseAction do action if condition is true, otherwise do elseAction
+func NewConditionElseAction(condition conditions.Condition, action Action, elseAction Action) Action {
+	return &ConditionAction{
+		condition:  condition,
+		action:     action,
+		elseAction: elseAction,
+	}
+}
+
 type ConditionAction struct {
-	condition conditions.Condition
-	action    Action
+	condition  conditions.Condition
+	action     Action
+	elseAction Action
 }
 
 func (c *ConditionAction) DoAction(context Context, object objects.StructuredObject) {
-	r, err := c.condition.Calculate(object)
+	r, err := conditions.Calculate(c.condition, object, context.ErrorPolicy(), func(err error) {
+		context.Log(object, c, err)
+	})
 	if err != nil {
 		context.Log(object, c, err)
 		return
@@ -29,9 +42,39 @@ func (c *ConditionAction) DoAction(context Context, object objects.StructuredObj
 
 	if r {
 		c.action.DoAction(context, object)
+	} else if c.elseAction != nil {
+		c.elseAction.DoAction(context, object)
 	}
 }
 
 func (c *ConditionAction) String() string {
+	if c.elseAction != nil {
+		return fmt.Sprintf("ConditionAction: condition=%v, action=%v, else=%v", c.condition.String(), c.action.String(), c.elseAction.String())
+	}
 	return fmt.Sprintf("ConditionAction: condition=%v, action=%v", c.condition.String(), c.action.String())
 }
+
+// -- block action --
+
+// NewBlockAction do actions in order
+func NewBlockAction(actions ...Action) Action {
+	return &blockAction{actions: actions}
+}
+
+type blockAction struct {
+	actions []Action
+}
+
+func (b *blockAction) DoAction(context Context, object objects.StructuredObject) {
+	for _, action := range b.actions {
+		action.DoAction(context, object)
+	}
+}
+
+func (b *blockAction) String() string {
+	var actions []string
+	for _, action := range b.actions {
+		actions = append(actions, action.String())
+	}
+	return fmt.Sprintf("BlockAction: actions=[%v]", strings.Join(actions, "; "))
+}
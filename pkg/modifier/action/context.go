@@ -2,23 +2,36 @@ package action
 
 import (
 	"github.com/storm-blue/rubick/pkg/log"
+	"github.com/storm-blue/rubick/pkg/modifier/conditions"
 	"github.com/storm-blue/rubick/pkg/modifier/objects"
 )
 
 func NewContext(logKeys []string) Context {
+	return NewContextWithErrorPolicy(logKeys, conditions.ErrorPolicyAbort)
+}
+
+// NewContextWithErrorPolicy create context, errorPolicy decides how the errors of conditions are handled.
+func NewContextWithErrorPolicy(logKeys []string, errorPolicy conditions.ErrorPolicy) Context {
 	return &actionContext{
-		logKeys: logKeys,
+		logKeys:     logKeys,
+		errorPolicy: errorPolicy,
 	}
 }
 
 type Context interface {
 	Log(object objects.StructuredObject, action Action, err error)
 	Logs() []*Log
+	ErrorPolicy() conditions.ErrorPolicy
 }
 
 type actionContext struct {
-	logKeys []string
-	logs    []*Log
+	logKeys     []string
+	logs        []*Log
+	errorPolicy conditions.ErrorPolicy
+}
+
+func (c *actionContext) ErrorPolicy() conditions.ErrorPolicy {
+	return c.errorPolicy
 }
 
 func (c *actionContext) Logs() []*Log {
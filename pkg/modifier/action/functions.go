@@ -0,0 +1,275 @@
+package action
+
+import (
+	"fmt"
+	"github.com/storm-blue/rubick/pkg/modifier/objects"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// -- string functions --
+
+// Upper convert string value to upper case, like: UPPER(VALUE_OF(metadata.name))
+func Upper(value Valuable) Valuable {
+	return &stringFunction{name: "UPPER", args: []Valuable{value}, f: func(args []string) (interface{}, error) {
+		return strings.ToUpper(args[0]), nil
+	}}
+}
+
+// Lower convert string value to lower case, like: LOWER(VALUE_OF(metadata.name))
+func Lower(value Valuable) Valuable {
+	return &stringFunction{name: "LOWER", args: []Valuable{value}, f: func(args []string) (interface{}, error) {
+		return strings.ToLower(args[0]), nil
+	}}
+}
+
+// Replace replace all old parts of string value to new, like: REPLACE(VALUE_OF(metadata.name), "-", "_")
+func Replace(value, old, new Valuable) Valuable {
+	return &stringFunction{name: "REPLACE", args: []Valuable{value, old, new}, f: func(args []string) (interface{}, error) {
+		return strings.ReplaceAll(args[0], args[1], args[2]), nil
+	}}
+}
+
+// Split split string value to list of strings, like: SPLIT(VALUE_OF(metadata.name), "-")
+func Split(value, separator Valuable) Valuable {
+	return &stringFunction{name: "SPLIT", args: []Valuable{value, separator}, f: func(args []string) (interface{}, error) {
+		var result []interface{}
+		for _, part := range strings.Split(args[0], args[1]) {
+			result = append(result, part)
+		}
+		return result, nil
+	}}
+}
+
+type stringFunction struct {
+	name string
+	args []Valuable
+	f    func(args []string) (interface{}, error)
+}
+
+func (s *stringFunction) GetValue(object objects.StructuredObject) (interface{}, error) {
+	var args []string
+	for _, arg := range s.args {
+		v, err := getStringValue(arg, object)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", s.name, err)
+		}
+		args = append(args, v)
+	}
+	return s.f(args)
+}
+
+func (s *stringFunction) String() string {
+	return fmt.Sprintf("%v(%v)", s.name, joinValuables(s.args))
+}
+
+// Substr get the sub string of value by character index, start is inclusive and end is exclusive,
+// indexes out of range are limited to the string. If end is nil, sub string ends at the end of value.
+// like: SUBSTR(VALUE_OF(metadata.name), 0, 5)
+func Substr(value, start, end Valuable) Valuable {
+	return &substrValue{value: value, start: start, end: end}
+}
+
+type substrValue struct {
+	value Valuable
+	start Valuable
+	end   Valuable
+}
+
+func (s *substrValue) GetValue(object objects.StructuredObject) (interface{}, error) {
+	v, err := getStringValue(s.value, object)
+	if err != nil {
+		return nil, fmt.Errorf("SUBSTR: %v", err)
+	}
+	runes := []rune(v)
+
+	start, err := getIntValue(s.start, object)
+	if err != nil {
+		return nil, fmt.Errorf("SUBSTR: %v", err)
+	}
+	end := len(runes)
+	if s.end != nil {
+		if end, err = getIntValue(s.end, object); err != nil {
+			return nil, fmt.Errorf("SUBSTR: %v", err)
+		}
+	}
+
+	start = min(max(start, 0), len(runes))
+	end = min(max(end, start), len(runes))
+	return string(runes[start:end]), nil
+}
+
+func (s *substrValue) String() string {
+	if s.end == nil {
+		return fmt.Sprintf("SUBSTR(%v)", joinValuables([]Valuable{s.value, s.start}))
+	}
+	return fmt.Sprintf("SUBSTR(%v)", joinValuables([]Valuable{s.value, s.start, s.end}))
+}
+
+// Join join the items of list value with separator, like: JOIN(VALUE_OF(spec.hosts), ",")
+func Join(value, separator Valuable) Valuable {
+	return &joinValue{value: value, separator: separator}
+}
+
+type joinValue struct {
+	value     Valuable
+	separator Valuable
+}
+
+func (j *joinValue) GetValue(object objects.StructuredObject) (interface{}, error) {
+	v, err := j.value.GetValue(object)
+	if err != nil {
+		return nil, err
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("JOIN: expected list, got %v", v)
+	}
+	separator, err := getStringValue(j.separator, object)
+	if err != nil {
+		return nil, fmt.Errorf("JOIN: %v", err)
+	}
+
+	var items []string
+	for _, item := range list {
+		items = append(items, fmt.Sprintf("%v", item))
+	}
+	return strings.Join(items, separator), nil
+}
+
+func (j *joinValue) String() string {
+	return fmt.Sprintf("JOIN(%v)", joinValuables([]Valuable{j.value, j.separator}))
+}
+
+// -- arithmetic --
+
+// Arithmetic calculate numbers with operator "+", "-", "*", "/" or "%",
+// result is integer if both numbers are integers and the result is exact, otherwise result is float.
+// like: VALUE_OF(spec.replicas) * 2
+func Arithmetic(operator string, left, right Valuable) Valuable {
+	return &arithmeticValue{operator: operator, left: left, right: right}
+}
+
+type arithmeticValue struct {
+	operator string
+	left     Valuable
+	right    Valuable
+}
+
+func (a *arithmeticValue) GetValue(object objects.StructuredObject) (interface{}, error) {
+	l, err := a.left.GetValue(object)
+	if err != nil {
+		return nil, err
+	}
+	r, err := a.right.GetValue(object)
+	if err != nil {
+		return nil, err
+	}
+
+	li, lIsInt := toInt(l)
+	ri, rIsInt := toInt(r)
+	if lIsInt && rIsInt {
+		switch a.operator {
+		case "+":
+			return li + ri, nil
+		case "-":
+			return li - ri, nil
+		case "*":
+			return li * ri, nil
+		case "/", "%":
+			if ri == 0 {
+				return nil, fmt.Errorf("calculate error: division by zero: %v", a)
+			}
+			if a.operator == "%" {
+				return li % ri, nil
+			}
+			if li%ri == 0 {
+				return li / ri, nil
+			}
+		}
+	}
+
+	lf, ok := toFloat(l)
+	if !ok {
+		return nil, fmt.Errorf("calculate error: expected number, got %v: %v", l, a)
+	}
+	rf, ok := toFloat(r)
+	if !ok {
+		return nil, fmt.Errorf("calculate error: expected number, got %v: %v", r, a)
+	}
+	switch a.operator {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		if rf == 0 {
+			return nil, fmt.Errorf("calculate error: division by zero: %v", a)
+		}
+		return lf / rf, nil
+	case "%":
+		if rf == 0 {
+			return nil, fmt.Errorf("calculate error: division by zero: %v", a)
+		}
+		return math.Mod(lf, rf), nil
+	default:
+		return nil, fmt.Errorf("calculate error: unsupported operator: %v", a.operator)
+	}
+}
+
+func (a *arithmeticValue) String() string {
+	return fmt.Sprintf("(%v %v %v)", a.left, a.operator, a.right)
+}
+
+func toInt(v interface{}) (int, bool) {
+	switch v_ := v.(type) {
+	case int:
+		return v_, true
+	case int8:
+		return int(v_), true
+	case int32:
+		return int(v_), true
+	case int64:
+		return int(v_), true
+	case uint:
+		return int(v_), true
+	default:
+		return 0, false
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	if i, ok := toInt(v); ok {
+		return float64(i), true
+	}
+	switch v_ := v.(type) {
+	case float32:
+		return float64(v_), true
+	case float64:
+		return v_, true
+	default:
+		return 0, false
+	}
+}
+
+func getIntValue(valuable Valuable, object objects.StructuredObject) (int, error) {
+	v, err := valuable.GetValue(object)
+	if err != nil {
+		return 0, err
+	}
+	if i, ok := toInt(v); ok {
+		return i, nil
+	}
+	return 0, fmt.Errorf("expected integer, got %v", reflect.TypeOf(v))
+}
+
+func joinValuables(values []Valuable) string {
+	var s []string
+	for _, value := range values {
+		s = append(s, value.String())
+	}
+	return strings.Join(s, ", ")
+}
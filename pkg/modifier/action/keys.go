@@ -0,0 +1,103 @@
+package action
+
+import (
+	"fmt"
+	"github.com/storm-blue/rubick/pkg/modifier/objects"
+)
+
+// -- rename action --
+
+// NewRenameAction rename the last segment of key to newName, like: RENAME(metadata.labels.app, "app-name")
+func NewRenameAction(key, newName string) Action {
+	return &renameAction{key: key, newName: newName}
+}
+
+type renameAction struct {
+	key     string
+	newName string
+}
+
+func (a *renameAction) DoAction(context Context, object objects.StructuredObject) {
+	if err := object.Rename(a.key, a.newName); err != nil {
+		context.Log(object, a, err)
+	}
+}
+
+func (a *renameAction) String() string {
+	return fmt.Sprintf("RenameAction: key=%v, newName=%v", a.key, a.newName)
+}
+
+// -- copy action --
+
+// NewCopyAction copy the value of src to dst, maps and arrays are deep copied.
+func NewCopyAction(src, dst string) Action {
+	return &copyAction{src: src, dst: dst}
+}
+
+type copyAction struct {
+	src string
+	dst string
+}
+
+func (a *copyAction) DoAction(context Context, object objects.StructuredObject) {
+	v, err := getSourceValue(object, a.src)
+	if err != nil {
+		context.Log(object, a, err)
+		return
+	}
+
+	if err := object.Set(a.dst, objects.DeepCopy(v)); err != nil {
+		context.Log(object, a, err)
+	}
+}
+
+func (a *copyAction) String() string {
+	return fmt.Sprintf("CopyAction: src=%v, dst=%v", a.src, a.dst)
+}
+
+// -- move action --
+
+// NewMoveAction move the value of src to dst, src is deleted before dst is set, so dst can be under src.
+func NewMoveAction(src, dst string) Action {
+	return &moveAction{src: src, dst: dst}
+}
+
+type moveAction struct {
+	src string
+	dst string
+}
+
+func (a *moveAction) DoAction(context Context, object objects.StructuredObject) {
+	v, err := getSourceValue(object, a.src)
+	if err != nil {
+		context.Log(object, a, err)
+		return
+	}
+
+	if err := object.Delete(a.src); err != nil {
+		context.Log(object, a, err)
+		return
+	}
+	if err := object.Set(a.dst, objects.DeepCopy(v)); err != nil {
+		context.Log(object, a, err)
+		// restore the source value, so that nothing is lost
+		if err := object.Set(a.src, v); err != nil {
+			context.Log(object, a, err)
+		}
+	}
+}
+
+func (a *moveAction) String() string {
+	return fmt.Sprintf("MoveAction: src=%v, dst=%v", a.src, a.dst)
+}
+
+func getSourceValue(object objects.StructuredObject, src string) (interface{}, error) {
+	v, err := object.Get(src)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil && !object.Exist(src) {
+		return nil, fmt.Errorf("can not find key: %v", src)
+	}
+	return v, nil
+}
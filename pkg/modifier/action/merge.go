@@ -0,0 +1,37 @@
+package action
+
+import (
+	"fmt"
+	"github.com/storm-blue/rubick/pkg/modifier/objects"
+)
+
+// -- merge action --
+
+// NewMergeAction deep merge fragment into the value of key, lists are merged by strategy.
+// like: MERGE(metadata.labels, "{app: nginx}")
+func NewMergeAction(key string, fragment *objects.Fragment, strategy objects.ListStrategy) Action {
+	return &mergeAction{key: key, fragment: fragment, strategy: strategy}
+}
+
+type mergeAction struct {
+	key      string
+	fragment *objects.Fragment
+	strategy objects.ListStrategy
+}
+
+func (a *mergeAction) DoAction(context Context, object objects.StructuredObject) {
+	keys, err := object.ExpandKey(a.key)
+	if err != nil {
+		context.Log(object, a, err)
+		return
+	}
+	for _, key := range keys {
+		if err := objects.MergeFragment(object, key, a.fragment, a.strategy); err != nil {
+			context.Log(object, a, err)
+		}
+	}
+}
+
+func (a *mergeAction) String() string {
+	return fmt.Sprintf("MergeAction: key=%v, fragment=%v, strategy=%v", a.key, a.fragment, a.strategy)
+}
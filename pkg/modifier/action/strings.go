@@ -3,39 +3,103 @@ package action
 import (
 	"fmt"
 	"github.com/storm-blue/rubick/pkg/modifier/objects"
+	"regexp"
 	"strings"
 )
 
 // -- replace part action --
 
-func NewReplacePartAction(key, old, new string) Action {
+func NewReplacePartAction(key string, old, new Valuable) Action {
 	return &replacePartAction{key: key, old: old, _new: new}
 }
 
 type replacePartAction struct {
 	key  string
-	old  string
-	_new string
+	old  Valuable
+	_new Valuable
 }
 
 func (a *replacePartAction) DoAction(context Context, object objects.StructuredObject) {
-	v, err := object.GetString(a.key)
+	old, err := getStringValue(a.old, object)
 	if err != nil {
 		context.Log(object, a, err)
 		return
 	}
-
-	v_ := strings.ReplaceAll(v, a.old, a._new)
-
-	if err := object.Set(a.key, v_); err != nil {
+	_new, err := getStringValue(a._new, object)
+	if err != nil {
 		context.Log(object, a, err)
+		return
 	}
+
+	updateStrings(context, object, a, a.key, func(v string) string {
+		return strings.ReplaceAll(v, old, _new)
+	})
 }
 
 func (a *replacePartAction) String() string {
 	return fmt.Sprintf("replacePartAction: key=%v, old=%v, new=%v", a.key, a.old, a._new)
 }
 
+// -- regex replace action --
+
+// NewRegexReplaceAction replace all matches of regex in the value of key, replacement can contain $1 or ${name}
+// like regexp.Regexp.ReplaceAllString, regex is compiled by caller so that it is compiled only once.
+func NewRegexReplaceAction(key string, regex *regexp.Regexp, replacement Valuable) Action {
+	return &regexReplaceAction{key: key, regex: regex, replacement: replacement}
+}
+
+type regexReplaceAction struct {
+	key         string
+	regex       *regexp.Regexp
+	replacement Valuable
+}
+
+func (a *regexReplaceAction) DoAction(context Context, object objects.StructuredObject) {
+	replacement, err := getStringValue(a.replacement, object)
+	if err != nil {
+		context.Log(object, a, err)
+		return
+	}
+
+	updateStrings(context, object, a, a.key, func(v string) string {
+		return a.regex.ReplaceAllString(v, replacement)
+	})
+}
+
+func (a *regexReplaceAction) String() string {
+	return fmt.Sprintf("regexReplaceAction: key=%v, regex=%v, replacement=%v", a.key, a.regex, a.replacement)
+}
+
+// updateStrings update the string value of each key expanded from key, like: spec.containers[*].image
+func updateStrings(context Context, object objects.StructuredObject, action Action, key string, update func(string) string) {
+	keys, err := object.ExpandKey(key)
+	if err != nil {
+		context.Log(object, action, err)
+		return
+	}
+	for _, key := range keys {
+		v, err := object.GetString(key)
+		if err != nil {
+			context.Log(object, action, err)
+			continue
+		}
+		if err := object.Set(key, update(v)); err != nil {
+			context.Log(object, action, err)
+		}
+	}
+}
+
+func getStringValue(valuable Valuable, object objects.StructuredObject) (string, error) {
+	v, err := valuable.GetValue(object)
+	if err != nil {
+		return "", err
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	return "", fmt.Errorf("expected string, got %v", v)
+}
+
 // -- trim prefix action --
 
 func NewTrimPrefixAction(key string, prefix Valuable) Action {
@@ -48,23 +112,15 @@ type trimPrefixAction struct {
 }
 
 func (a *trimPrefixAction) DoAction(context Context, object objects.StructuredObject) {
-	v, err := object.GetString(a.key)
-	if err != nil {
-		context.Log(object, a, err)
-		return
-	}
-
-	argV, err := a.prefix.getValue(object)
+	argV, err := a.prefix.GetValue(object)
 	if err != nil {
 		context.Log(object, a, err)
 		return
 	}
 	if p, ok := argV.(string); ok {
-		v_ := strings.TrimPrefix(v, p)
-
-		if err := object.Set(a.key, v_); err != nil {
-			context.Log(object, a, err)
-		}
+		updateStrings(context, object, a, a.key, func(v string) string {
+			return strings.TrimPrefix(v, p)
+		})
 	} else {
 		context.Log(object, a, fmt.Errorf("expected string, got %v", argV))
 	}
@@ -86,23 +142,15 @@ type trimSuffixAction struct {
 }
 
 func (a *trimSuffixAction) DoAction(context Context, object objects.StructuredObject) {
-	v, err := object.GetString(a.key)
-	if err != nil {
-		context.Log(object, a, err)
-		return
-	}
-
-	argV, err := a.suffix.getValue(object)
+	argV, err := a.suffix.GetValue(object)
 	if err != nil {
 		context.Log(object, a, err)
 		return
 	}
 	if p, ok := argV.(string); ok {
-		v_ := strings.TrimSuffix(v, p)
-
-		if err := object.Set(a.key, v_); err != nil {
-			context.Log(object, a, err)
-		}
+		updateStrings(context, object, a, a.key, func(v string) string {
+			return strings.TrimSuffix(v, p)
+		})
 	} else {
 		context.Log(object, a, fmt.Errorf("expected string, got %v", argV))
 	}
@@ -0,0 +1,52 @@
+package action
+
+import (
+	"fmt"
+	"github.com/storm-blue/rubick/pkg/modifier/objects"
+)
+
+// Variable is the value of scripts variable, which is defined by let action before.
+func Variable(name string) Valuable {
+	return &variableValue{name: name}
+}
+
+type variableValue struct {
+	name string
+}
+
+func (v *variableValue) GetValue(object objects.StructuredObject) (interface{}, error) {
+	value, ok := object.Metadata().GetVariable(v.name)
+	if !ok {
+		return nil, fmt.Errorf("undefined variable: %v", v.name)
+	}
+	return value, nil
+}
+
+func (v *variableValue) String() string {
+	return "$" + v.name
+}
+
+// -- let action --
+
+// NewLetAction define a variable of the object, like: LET app = VALUE_OF(metadata.labels.app)
+func NewLetAction(name string, value Valuable) Action {
+	return &letAction{name: name, value: value}
+}
+
+type letAction struct {
+	name  string
+	value Valuable
+}
+
+func (l *letAction) DoAction(context Context, object objects.StructuredObject) {
+	v, err := l.value.GetValue(object)
+	if err != nil {
+		context.Log(object, l, err)
+		return
+	}
+	object.Metadata().SetVariable(l.name, v)
+}
+
+func (l *letAction) String() string {
+	return fmt.Sprintf("LetAction: name=%v, value=%v", l.name, l.value)
+}
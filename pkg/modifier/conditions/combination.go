@@ -11,23 +11,31 @@ type CombinationCondition struct {
 	operator int
 }
 
+// Calculate with short-circuit, the right condition is not calculated if the left one decides the result.
+// A failed left condition is unknown, the error is returned unless the right condition decides the result,
+// like: unknown && false is false, unknown || true is true.
 func (c *CombinationCondition) Calculate(object objects.StructuredObject) (bool, error) {
-	leftResult, err := c.left.Calculate(object)
-	if err != nil {
-		return false, err
+	if c.operator != And && c.operator != Or {
+		return false, fmt.Errorf("calculate error: unsupported operator: %v", c.operator)
 	}
-	rightResult, err := c.right.Calculate(object)
-	if err != nil {
-		return false, err
+	// false decides the result of &&, and true decides the result of ||
+	decisive := c.operator == Or
+
+	leftResult, leftErr := c.left.Calculate(object)
+	if leftErr == nil && leftResult == decisive {
+		return leftResult, nil
 	}
 
-	switch c.operator {
-	case And:
-		return leftResult && rightResult, nil
-	case Or:
-		return leftResult || rightResult, nil
+	rightResult, err := c.right.Calculate(object)
+	switch {
+	case err == nil && rightResult == decisive:
+		return rightResult, nil
+	case leftErr != nil:
+		return false, leftErr
+	case err != nil:
+		return false, err
 	default:
-		return false, fmt.Errorf("calculate error: unsupported operator: %v", c.operator)
+		return rightResult, nil
 	}
 }
 
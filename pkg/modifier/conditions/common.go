@@ -3,6 +3,7 @@ package conditions
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/storm-blue/rubick/pkg/modifier/objects"
 	"gopkg.in/yaml.v2"
 	"reflect"
 	"strconv"
@@ -21,7 +22,25 @@ const (
 	Not
 )
 
+// resolveValue get the value of Valuable from object, other values are returned as it is.
+func resolveValue(value interface{}, object objects.StructuredObject) (interface{}, error) {
+	if valuable, ok := value.(Valuable); ok {
+		return valuable.GetValue(object)
+	}
+	return value, nil
+}
+
+// equals is same as calculate EqualTo, but values which can not be compared are treated as not equal.
+func equals(objectValue interface{}, conditionValue interface{}) bool {
+	result, err := calculate(EqualTo, objectValue, conditionValue)
+	return err == nil && result
+}
+
 func calculate(operator int, objectValue interface{}, conditionValue interface{}) (bool, error) {
+	if conditionValue == nil && objectValue != nil {
+		return calculateValueAndNil(operator, objectValue)
+	}
+
 	switch v_ := objectValue.(type) {
 	case int8:
 		return calculateNumber(operator, float64(v_), conditionValue)
@@ -37,6 +56,8 @@ func calculate(operator int, objectValue interface{}, conditionValue interface{}
 		return calculateNumber(operator, float64(v_), conditionValue)
 	case float64:
 		return calculateNumber(operator, v_, conditionValue)
+	case bool:
+		return calculateBool(operator, v_, conditionValue)
 	case string:
 		return calculateString(operator, v_, conditionValue)
 	case []interface{}:
@@ -70,6 +91,17 @@ func calculateNilAndValue(operator int, v2 interface{}) (bool, error) {
 	}
 }
 
+func calculateValueAndNil(operator int, v1 interface{}) (bool, error) {
+	switch operator {
+	case EqualTo:
+		return false, nil
+	case NotEqual:
+		return true, nil
+	default:
+		return false, fmt.Errorf("calculateValueAndNil error: can not compare %v and nil", reflect.TypeOf(v1))
+	}
+}
+
 func calculateNumber(operator int, n1 float64, v2 interface{}) (bool, error) {
 	n2, err := tryParseToNumber(v2)
 	if err != nil {
@@ -110,6 +142,21 @@ func calculateString(operator int, s1 string, v2 interface{}) (bool, error) {
 	}
 }
 
+func calculateBool(operator int, b1 bool, v2 interface{}) (bool, error) {
+	b2, err := tryParseToBool(v2)
+	if err != nil {
+		return false, err
+	}
+	switch operator {
+	case EqualTo:
+		return b1 == b2, nil
+	case NotEqual:
+		return b1 != b2, nil
+	default:
+		return false, fmt.Errorf("calculate bool error: unsupported operator: %v", operator)
+	}
+}
+
 func calculateObjectArray(operator int, a1 []interface{}, v2 interface{}) (bool, error) {
 	a2, err := tryParseToArray(v2)
 	if err != nil {
@@ -188,6 +235,11 @@ func tryParseToNumber(v interface{}) (float64, error) {
 		return float64(x), nil
 	case float64:
 		return x, nil
+	case bool:
+		if x {
+			return 1, nil
+		}
+		return 0, nil
 	case string:
 		return strconv.ParseFloat(x, 64)
 	default:
@@ -199,11 +251,26 @@ func tryParseToString(v interface{}) (string, error) {
 	switch x := v.(type) {
 	case string:
 		return x, nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	case int8, int32, int64, int, uint, float32, float64:
+		return fmt.Sprintf("%v", x), nil
 	default:
 		return "", fmt.Errorf("parse to string error: unsupported type: %v", reflect.TypeOf(v))
 	}
 }
 
+func tryParseToBool(v interface{}) (bool, error) {
+	switch x := v.(type) {
+	case bool:
+		return x, nil
+	case string:
+		return strconv.ParseBool(x)
+	default:
+		return false, fmt.Errorf("parse to bool error: unsupported type: %v", reflect.TypeOf(v))
+	}
+}
+
 func tryParseToArray(v interface{}) ([]interface{}, error) {
 	switch x := v.(type) {
 	case string:
@@ -10,3 +10,9 @@ type Condition interface {
 	And(condition Condition) Condition
 	Or(condition Condition) Condition
 }
+
+// Valuable is a value calculated from the object when condition is calculated, like scripts variables.
+type Valuable interface {
+	GetValue(object objects.StructuredObject) (interface{}, error)
+	String() string
+}
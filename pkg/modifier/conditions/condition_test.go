@@ -3,6 +3,7 @@ package conditions
 import (
 	"github.com/storm-blue/rubick/pkg/modifier/objects"
 	"reflect"
+	"regexp"
 	"testing"
 )
 
@@ -37,6 +38,11 @@ func TestNew(t *testing.T) {
 				condition: &valueOfCondition{operator: LesserThan, key: "a", value: 1},
 			},
 		},
+		{
+			name:      "TEST_MATCHES",
+			condition: New().Matches("a", regexp.MustCompile("^app-[0-9]+$")),
+			want:      &matchesCondition{key: "a", regex: regexp.MustCompile("^app-[0-9]+$")},
+		},
 		{
 			name: "TEST_MULTI_1",
 			condition: New().ValueOf("a").LesserThan(1).
@@ -192,6 +198,70 @@ func Test_calculate(t *testing.T) {
 			want:           false,
 			wantErr:        true,
 		},
+		{
+			name:           "TEST_BOOL_EQ_BOOL",
+			operator:       EqualTo,
+			objectValue:    true,
+			conditionValue: true,
+			want:           true,
+			wantErr:        false,
+		},
+		{
+			name:           "TEST_BOOL_NE_STRING",
+			operator:       NotEqual,
+			objectValue:    false,
+			conditionValue: "true",
+			want:           true,
+			wantErr:        false,
+		},
+		{
+			name:           "TEST_BOOL_GT_SHOULD_ERROR",
+			operator:       GreaterThan,
+			objectValue:    true,
+			conditionValue: false,
+			want:           false,
+			wantErr:        true,
+		},
+		{
+			name:           "TEST_NUMBER_EQ_BOOL",
+			operator:       EqualTo,
+			objectValue:    1,
+			conditionValue: true,
+			want:           true,
+			wantErr:        false,
+		},
+		{
+			name:           "TEST_STRING_EQ_NIL",
+			operator:       EqualTo,
+			objectValue:    "null",
+			conditionValue: nil,
+			want:           false,
+			wantErr:        false,
+		},
+		{
+			name:           "TEST_NIL_EQ_NIL",
+			operator:       EqualTo,
+			objectValue:    nil,
+			conditionValue: nil,
+			want:           true,
+			wantErr:        false,
+		},
+		{
+			name:           "TEST_NUMBER_NE_NIL",
+			operator:       NotEqual,
+			objectValue:    0,
+			conditionValue: nil,
+			want:           true,
+			wantErr:        false,
+		},
+		{
+			name:           "TEST_STRING_EQ_NUMBER",
+			operator:       EqualTo,
+			objectValue:    "80",
+			conditionValue: 80,
+			want:           true,
+			wantErr:        false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -380,3 +450,287 @@ func Test_existsCondition_Calculate(t *testing.T) {
 		})
 	}
 }
+
+func TestCalculate(t *testing.T) {
+	object := objects.FromMap(map[interface{}]interface{}{"a": "x", "b": 1, "l": []interface{}{
+		map[interface{}]interface{}{"a": "x"}, map[interface{}]interface{}{"a": map[interface{}]interface{}{"c": "y"}},
+	}})
+	// calculation of "a.c" returns error because "a" is not map
+	failed := New().ValueOf("a.c").EqualTo("y")
+
+	tests := []struct {
+		name      string
+		condition Condition
+		policy    ErrorPolicy
+		want      bool
+		wantErr   bool
+		wantLogs  int
+	}{
+		{
+			name:      "TEST_AND_SHORT_CIRCUIT",
+			condition: New().Exists("c").And(failed),
+			policy:    ErrorPolicyAbort,
+			want:      false,
+		},
+		{
+			name:      "TEST_OR_SHORT_CIRCUIT",
+			condition: New().Exists("a").Or(failed),
+			policy:    ErrorPolicyAbort,
+			want:      true,
+		},
+		{
+			name:      "TEST_ABORT",
+			condition: New().Exists("a").And(failed),
+			policy:    ErrorPolicyAbort,
+			want:      false,
+			wantErr:   true,
+		},
+		{
+			name:      "TEST_FALSE",
+			condition: failed.Or(New().ValueOf("b").EqualTo(1)),
+			policy:    ErrorPolicyFalse,
+			want:      true,
+		},
+		{
+			name:      "TEST_LOG",
+			condition: failed.Or(New().ValueOf("b").EqualTo(2)),
+			policy:    ErrorPolicyLog,
+			want:      false,
+			wantLogs:  1,
+		},
+		{
+			name:      "TEST_FALSE_ANY",
+			condition: New().Any("l", failed),
+			policy:    ErrorPolicyFalse,
+			want:      true,
+		},
+		{
+			name:      "TEST_ABORT_OR_DECIDED",
+			condition: failed.Or(New().ValueOf("b").EqualTo(1)),
+			policy:    ErrorPolicyAbort,
+			want:      true,
+		},
+		{
+			name:      "TEST_ABORT_ANY_DECIDED",
+			condition: New().Any("l", failed),
+			policy:    ErrorPolicyAbort,
+			want:      true,
+		},
+		{
+			name:      "TEST_ABORT_ALL",
+			condition: New().All("l", failed),
+			policy:    ErrorPolicyAbort,
+			want:      false,
+			wantErr:   true,
+		},
+		{
+			name:      "TEST_FALSE_ALL",
+			condition: New().All("l", failed),
+			policy:    ErrorPolicyFalse,
+			want:      false,
+		},
+		{
+			name:      "TEST_FALSE_NOT",
+			condition: New().Not(failed),
+			policy:    ErrorPolicyFalse,
+			want:      false,
+		},
+		{
+			name:      "TEST_FALSE_NOT_LENGTH_OF",
+			condition: New().Not(New().LengthOf("a.c").GreaterThan(0)),
+			policy:    ErrorPolicyFalse,
+			want:      false,
+		},
+		{
+			name:      "TEST_FALSE_NOT_AND",
+			condition: New().Not(failed).And(New().Exists("a")),
+			policy:    ErrorPolicyFalse,
+			want:      false,
+		},
+		{
+			name:      "TEST_LOG_NOT",
+			condition: New().Not(failed),
+			policy:    ErrorPolicyLog,
+			want:      false,
+			wantLogs:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := 0
+			got, err := Calculate(tt.condition, object, tt.policy, func(error) { logs++ })
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Calculate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Calculate() got = %v, want %v", got, tt.want)
+			}
+			if logs != tt.wantLogs {
+				t.Errorf("Calculate() logs = %v, want %v", logs, tt.wantLogs)
+			}
+		})
+	}
+}
+
+func TestMembership(t *testing.T) {
+	object := objects.FromMap(map[interface{}]interface{}{
+		"namespace": "prod",
+		"image":     "registry.old.com/app:1.0",
+		"ports":     []interface{}{80, 8080},
+		"labels":    map[interface{}]interface{}{"app": "a"},
+	})
+
+	tests := []struct {
+		name      string
+		condition Condition
+		want      bool
+		wantErr   bool
+	}{
+		{
+			name:      "TEST_IN",
+			condition: New().ValueOf("namespace").In("test", "prod"),
+			want:      true,
+		},
+		{
+			name:      "TEST_IN_FALSE",
+			condition: New().ValueOf("namespace").In("test", "dev"),
+			want:      false,
+		},
+		{
+			name:      "TEST_NOT_IN",
+			condition: New().ValueOf("namespace").NotIn("test", "dev"),
+			want:      true,
+		},
+		{
+			name:      "TEST_IN_MISSING_KEY",
+			condition: New().ValueOf("missing").In("test"),
+			want:      false,
+		},
+		{
+			name:      "TEST_CONTAINS_STRING",
+			condition: New().Contains("image", "registry.old.com"),
+			want:      true,
+		},
+		{
+			name:      "TEST_CONTAINS_ARRAY",
+			condition: New().Contains("ports", "8080"),
+			want:      true,
+		},
+		{
+			name:      "TEST_CONTAINS_ARRAY_FALSE",
+			condition: New().Contains("ports", 443),
+			want:      false,
+		},
+		{
+			name:      "TEST_CONTAINS_MAP",
+			condition: New().Contains("labels", "app"),
+			want:      true,
+		},
+		{
+			name:      "TEST_CONTAINS_MISSING_KEY",
+			condition: New().Contains("missing", "app"),
+			want:      false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.condition.Calculate(object)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Calculate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Calculate() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTypeOf(t *testing.T) {
+	object := objects.FromMap(map[interface{}]interface{}{
+		"string": "80",
+		"number": 80,
+		"bool":   true,
+		"array":  []interface{}{1},
+		"map":    map[interface{}]interface{}{"a": 1},
+		"null":   nil,
+		"uint64": uint64(18446744073709551615),
+		"ports": []interface{}{
+			map[interface{}]interface{}{"name": "web", "protocol": "TCP", "port": 80},
+			map[interface{}]interface{}{"name": "web", "protocol": "UDP", "port": 81},
+		},
+	})
+
+	tests := []struct {
+		name      string
+		condition Condition
+		want      bool
+	}{
+		{name: "TEST_STRING", condition: New().TypeOf("string").EqualTo(TypeString), want: true},
+		{name: "TEST_NUMBER", condition: New().TypeOf("number").EqualTo(TypeNumber), want: true},
+		{name: "TEST_UINT64", condition: New().TypeOf("uint64").EqualTo(TypeNumber), want: true},
+		{name: "TEST_NUMBER_IS_NOT_STRING", condition: New().TypeOf("number").EqualTo(TypeString), want: false},
+		{name: "TEST_BOOL", condition: New().TypeOf("bool").EqualTo(TypeBool), want: true},
+		{name: "TEST_ARRAY", condition: New().TypeOf("array").EqualTo(TypeArray), want: true},
+		{name: "TEST_MAP", condition: New().TypeOf("map").NotEqual(TypeArray), want: true},
+		{name: "TEST_NULL", condition: New().TypeOf("null").EqualTo(TypeNull), want: true},
+		{name: "TEST_MISSING_IS_NOT_NULL", condition: New().TypeOf("missing").EqualTo(TypeNull), want: false},
+		{name: "TEST_MISSING", condition: New().TypeOf("missing").EqualTo(TypeMissing), want: true},
+		{name: "TEST_SEARCH", condition: New().TypeOf("ports[name=web,protocol=UDP].port").EqualTo(TypeNumber), want: true},
+		{name: "TEST_SEARCH_MAP", condition: New().TypeOf("ports[name=web]").EqualTo(TypeMap), want: true},
+		{name: "TEST_SEARCH_MISSING", condition: New().TypeOf("ports[name=none].port").EqualTo(TypeMissing), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.condition.Calculate(object)
+			if err != nil {
+				t.Errorf("Calculate() error = %v", err)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Calculate() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuantifier(t *testing.T) {
+	object, err := objects.FromYAML(`spec:
+  containers:
+  - image: old/nginx
+    name: a
+  - image: new/busybox
+    name: b
+  empty: []
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		condition Condition
+		want      bool
+	}{
+		{name: "TEST_ANY", condition: New().Any("spec.containers[*].image", New().HasPrefix(objects.SelfKey, "old/")), want: true},
+		{name: "TEST_ANY_FALSE", condition: New().Any("spec.containers[*].image", New().HasPrefix(objects.SelfKey, "none/")), want: false},
+		{name: "TEST_ALL", condition: New().All("spec.containers", New().Exists("image")), want: true},
+		{name: "TEST_ALL_FALSE", condition: New().All("spec.containers", New().ValueOf("name").EqualTo("a")), want: false},
+		{name: "TEST_ANY_EMPTY", condition: New().Any("spec.empty", New().Exists("image")), want: false},
+		{name: "TEST_ALL_EMPTY", condition: New().All("spec.missing[*].image", New().Exists("image")), want: true},
+		{name: "TEST_SINGLE_VALUE", condition: New().Any("spec.containers[0].name", New().ValueOf(objects.SelfKey).EqualTo("a")), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.condition.Calculate(object)
+			if err != nil {
+				t.Errorf("Calculate() error = %v", err)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Calculate() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
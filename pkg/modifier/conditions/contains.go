@@ -0,0 +1,69 @@
+package conditions
+
+import (
+	"fmt"
+	"github.com/storm-blue/rubick/pkg/modifier/objects"
+	"reflect"
+	"strings"
+)
+
+// containsCondition check whether the value of key contains value:
+// sub string for string, item for array and key for map.
+type containsCondition struct {
+	key   string
+	value interface{}
+}
+
+func (c *containsCondition) And(condition Condition) Condition {
+	return &CombinationCondition{
+		left:     c,
+		right:    condition,
+		operator: And,
+	}
+}
+
+func (c *containsCondition) Or(condition Condition) Condition {
+	return &CombinationCondition{
+		left:     c,
+		right:    condition,
+		operator: Or,
+	}
+}
+
+func (c *containsCondition) Calculate(object objects.StructuredObject) (bool, error) {
+	v, err := object.Get(c.key)
+	if err != nil {
+		return false, err
+	}
+	value, err := resolveValue(c.value, object)
+	if err != nil {
+		return false, err
+	}
+
+	switch v_ := v.(type) {
+	case string:
+		return strings.Contains(v_, fmt.Sprintf("%v", value)), nil
+	case []interface{}:
+		for _, item := range v_ {
+			if equals(item, value) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case map[interface{}]interface{}:
+		for k := range v_ {
+			if fmt.Sprintf("%v", k) == fmt.Sprintf("%v", value) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case nil:
+		return false, nil
+	default:
+		return false, fmt.Errorf("calculate contains error: unsupported type: %v", reflect.TypeOf(v))
+	}
+}
+
+func (c *containsCondition) String() string {
+	return fmt.Sprintf("CONTAINS(%v, \"%v\")", c.key, c.value)
+}
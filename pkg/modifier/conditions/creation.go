@@ -1,5 +1,7 @@
 package conditions
 
+import "regexp"
+
 func New() CreateCondition_Start {
 	return CreateCondition_Start{}
 }
@@ -36,20 +38,65 @@ func (c CreateCondition_Start) Exists(key string) Condition {
 	}
 }
 
-func (c CreateCondition_Start) HasPrefix(key string, prefix string) Condition {
+// HasPrefix check whether the string value of key starts with prefix, prefix is a string or Valuable.
+func (c CreateCondition_Start) HasPrefix(key string, prefix interface{}) Condition {
 	return &hasPrefixCondition{
 		key:    key,
 		prefix: prefix,
 	}
 }
 
-func (c CreateCondition_Start) HasSuffix(key string, suffix string) Condition {
+// HasSuffix check whether the string value of key ends with suffix, suffix is a string or Valuable.
+func (c CreateCondition_Start) HasSuffix(key string, suffix interface{}) Condition {
 	return &hasSuffixCondition{
 		key:    key,
 		suffix: suffix,
 	}
 }
 
+// Matches check the string value of key with regex, regex is compiled by caller so that it is compiled only once.
+func (c CreateCondition_Start) Matches(key string, regex *regexp.Regexp) Condition {
+	return &matchesCondition{
+		key:   key,
+		regex: regex,
+	}
+}
+
+// Contains check whether the value of key contains value:
+// sub string for string, item for array and key for map.
+func (c CreateCondition_Start) Contains(key string, value interface{}) Condition {
+	return &containsCondition{
+		key:   key,
+		value: value,
+	}
+}
+
+// Any check whether any item of the value of key matches condition, like: ANY(spec.containers[*].image, HAS_PREFIX(@, "old/"))
+func (c CreateCondition_Start) Any(key string, condition Condition) Condition {
+	return &quantifierCondition{
+		key:       key,
+		condition: condition,
+	}
+}
+
+// All check whether all items of the value of key match condition, like: ALL(spec.containers, EXISTS(resources.limits))
+func (c CreateCondition_Start) All(key string, condition Condition) Condition {
+	return &quantifierCondition{
+		all:       true,
+		key:       key,
+		condition: condition,
+	}
+}
+
+func (c CreateCondition_Start) TypeOf(key string) CreateCondition_TypeOf {
+	return CreateCondition_TypeOf{
+		condition: &typeOfCondition{
+			operator: None,
+			key:      key,
+		},
+	}
+}
+
 type CreateCondition_ValueOf struct {
 	condition *valueOfCondition
 }
@@ -90,42 +137,66 @@ func (c CreateCondition_ValueOf) NotEqual(value interface{}) Condition {
 	return c.condition
 }
 
+func (c CreateCondition_ValueOf) In(values ...interface{}) Condition {
+	return &inCondition{key: c.condition.key, values: values}
+}
+
+func (c CreateCondition_ValueOf) NotIn(values ...interface{}) Condition {
+	return &inCondition{key: c.condition.key, values: values, not: true}
+}
+
 type CreateCondition_LengthOf struct {
 	condition *lengthOfCondition
 }
 
-func (c CreateCondition_LengthOf) GreaterThan(value int) Condition {
+func (c CreateCondition_LengthOf) GreaterThan(value interface{}) Condition {
 	c.condition.operator = GreaterThan
 	c.condition.value = value
 	return c.condition
 }
 
-func (c CreateCondition_LengthOf) GreaterThanOrEqual(value int) Condition {
+func (c CreateCondition_LengthOf) GreaterThanOrEqual(value interface{}) Condition {
 	c.condition.operator = GreaterThanOrEqual
 	c.condition.value = value
 	return c.condition
 }
 
-func (c CreateCondition_LengthOf) LesserThan(value int) Condition {
+func (c CreateCondition_LengthOf) LesserThan(value interface{}) Condition {
 	c.condition.operator = LesserThan
 	c.condition.value = value
 	return c.condition
 }
 
-func (c CreateCondition_LengthOf) LesserThanOrEqual(value int) Condition {
+func (c CreateCondition_LengthOf) LesserThanOrEqual(value interface{}) Condition {
 	c.condition.operator = LesserThanOrEqual
 	c.condition.value = value
 	return c.condition
 }
 
-func (c CreateCondition_LengthOf) EqualTo(value int) Condition {
+func (c CreateCondition_LengthOf) EqualTo(value interface{}) Condition {
 	c.condition.operator = EqualTo
 	c.condition.value = value
 	return c.condition
 }
 
-func (c CreateCondition_LengthOf) NotEqual(value int) Condition {
+func (c CreateCondition_LengthOf) NotEqual(value interface{}) Condition {
 	c.condition.operator = NotEqual
 	c.condition.value = value
 	return c.condition
 }
+
+type CreateCondition_TypeOf struct {
+	condition *typeOfCondition
+}
+
+func (c CreateCondition_TypeOf) EqualTo(valueType string) Condition {
+	c.condition.operator = EqualTo
+	c.condition.valueType = valueType
+	return c.condition
+}
+
+func (c CreateCondition_TypeOf) NotEqual(valueType string) Condition {
+	c.condition.operator = NotEqual
+	c.condition.valueType = valueType
+	return c.condition
+}
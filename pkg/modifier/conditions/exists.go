@@ -30,6 +30,10 @@ func (e *existsCondition) Calculate(object objects.StructuredObject) (bool, erro
 	if err != nil {
 		return false, err
 	}
+	// multi-valued key like "a[*].b" exists if any value is matched
+	if values, ok := result.([]interface{}); ok && objects.IsMultiValuedKey(e.key) {
+		return len(values) > 0, nil
+	}
 	return result != nil, nil
 }
 
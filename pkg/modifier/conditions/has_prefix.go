@@ -8,7 +8,7 @@ import (
 
 type hasPrefixCondition struct {
 	key    string
-	prefix string
+	prefix interface{}
 }
 
 func (c *hasPrefixCondition) And(condition Condition) Condition {
@@ -32,9 +32,20 @@ func (c *hasPrefixCondition) Calculate(object objects.StructuredObject) (bool, e
 	if err != nil {
 		return false, err
 	}
-	return strings.HasPrefix(result, c.prefix), nil
+	value, err := resolveValue(c.prefix, object)
+	if err != nil {
+		return false, err
+	}
+	prefix, err := tryParseToString(value)
+	if err != nil {
+		return false, err
+	}
+	return strings.HasPrefix(result, prefix), nil
 }
 
 func (c *hasPrefixCondition) String() string {
+	if valuable, ok := c.prefix.(Valuable); ok {
+		return fmt.Sprintf("HAS_PREFIX(%v, %v)", c.key, valuable)
+	}
 	return fmt.Sprintf("HAS_PREFIX(%v, \"%v\")", c.key, c.prefix)
 }
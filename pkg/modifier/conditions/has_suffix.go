@@ -8,7 +8,7 @@ import (
 
 type hasSuffixCondition struct {
 	key    string
-	suffix string
+	suffix interface{}
 }
 
 func (c *hasSuffixCondition) And(condition Condition) Condition {
@@ -32,9 +32,20 @@ func (c *hasSuffixCondition) Calculate(object objects.StructuredObject) (bool, e
 	if err != nil {
 		return false, err
 	}
-	return strings.HasSuffix(result, c.suffix), nil
+	value, err := resolveValue(c.suffix, object)
+	if err != nil {
+		return false, err
+	}
+	suffix, err := tryParseToString(value)
+	if err != nil {
+		return false, err
+	}
+	return strings.HasSuffix(result, suffix), nil
 }
 
 func (c *hasSuffixCondition) String() string {
+	if valuable, ok := c.suffix.(Valuable); ok {
+		return fmt.Sprintf("HAS_SUFFIX(%v, %v)", c.key, valuable)
+	}
 	return fmt.Sprintf("HAS_SUFFIX(%v, \"%v\")", c.key, c.suffix)
 }
@@ -0,0 +1,60 @@
+package conditions
+
+import (
+	"fmt"
+	"github.com/storm-blue/rubick/pkg/modifier/objects"
+	"strings"
+)
+
+// inCondition check whether the value of key equals to one of values, like: VALUE_OF(metadata.namespace) IN ("a", "b")
+type inCondition struct {
+	key    string
+	values []interface{}
+	not    bool
+}
+
+func (c *inCondition) And(condition Condition) Condition {
+	return &CombinationCondition{
+		left:     c,
+		right:    condition,
+		operator: And,
+	}
+}
+
+func (c *inCondition) Or(condition Condition) Condition {
+	return &CombinationCondition{
+		left:     c,
+		right:    condition,
+		operator: Or,
+	}
+}
+
+func (c *inCondition) Calculate(object objects.StructuredObject) (bool, error) {
+	v, err := object.Get(c.key)
+	if err != nil {
+		return false, err
+	}
+
+	for _, value := range c.values {
+		value, err := resolveValue(value, object)
+		if err != nil {
+			return false, err
+		}
+		if equals(v, value) {
+			return !c.not, nil
+		}
+	}
+	return c.not, nil
+}
+
+func (c *inCondition) String() string {
+	var values []string
+	for _, value := range c.values {
+		values = append(values, fmt.Sprintf("%v", value))
+	}
+	operator := "IN"
+	if c.not {
+		operator = "NOT_IN"
+	}
+	return fmt.Sprintf("%v %v (%v)", c.key, operator, strings.Join(values, ", "))
+}
@@ -9,7 +9,7 @@ import (
 type lengthOfCondition struct {
 	operator int
 	key      string
-	value    int
+	value    interface{}
 }
 
 func (c *lengthOfCondition) And(condition Condition) Condition {
@@ -48,7 +48,11 @@ func (c *lengthOfCondition) Calculate(object objects.StructuredObject) (bool, er
 		return false, fmt.Errorf("calculate error: unsupported type: %v", reflect.TypeOf(v))
 	}
 
-	return calculateNumber(c.operator, float64(size), c.value)
+	value, err := resolveValue(c.value, object)
+	if err != nil {
+		return false, err
+	}
+	return calculateNumber(c.operator, float64(size), value)
 }
 
 func (c *lengthOfCondition) String() string {
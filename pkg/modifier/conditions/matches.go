@@ -0,0 +1,40 @@
+package conditions
+
+import (
+	"fmt"
+	"github.com/storm-blue/rubick/pkg/modifier/objects"
+	"regexp"
+)
+
+type matchesCondition struct {
+	key   string
+	regex *regexp.Regexp
+}
+
+func (c *matchesCondition) And(condition Condition) Condition {
+	return &CombinationCondition{
+		left:     c,
+		right:    condition,
+		operator: And,
+	}
+}
+
+func (c *matchesCondition) Or(condition Condition) Condition {
+	return &CombinationCondition{
+		left:     c,
+		right:    condition,
+		operator: Or,
+	}
+}
+
+func (c *matchesCondition) Calculate(object objects.StructuredObject) (bool, error) {
+	result, err := object.GetString(c.key)
+	if err != nil {
+		return false, err
+	}
+	return c.regex.MatchString(result), nil
+}
+
+func (c *matchesCondition) String() string {
+	return fmt.Sprintf("MATCHES(%v, \"%v\")", c.key, c.regex)
+}
@@ -25,6 +25,7 @@ func (c *notCondition) Or(condition Condition) Condition {
 	}
 }
 
+// Calculate returns error if the condition returns error, so that the error is not inverted to true.
 func (c *notCondition) Calculate(object objects.StructuredObject) (bool, error) {
 	result, err := c.condition.Calculate(object)
 	if err != nil {
@@ -0,0 +1,58 @@
+package conditions
+
+import (
+	"fmt"
+	"github.com/storm-blue/rubick/pkg/modifier/objects"
+)
+
+// ErrorPolicy decides what happens when a condition returns error in calculation.
+type ErrorPolicy string
+
+const (
+	// ErrorPolicyAbort stops the calculation and returns the error, this is the default policy.
+	ErrorPolicyAbort ErrorPolicy = "abort"
+	// ErrorPolicyFalse treats the failed condition as false silently.
+	ErrorPolicyFalse ErrorPolicy = "false"
+	// ErrorPolicyLog treats the failed condition as false, and reports the error to the error handler.
+	ErrorPolicyLog ErrorPolicy = "log"
+)
+
+// ParseErrorPolicy parse policy like: abort, false, log
+func ParseErrorPolicy(policy string) (ErrorPolicy, error) {
+	switch p := ErrorPolicy(policy); p {
+	case ErrorPolicyAbort, ErrorPolicyFalse, ErrorPolicyLog:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid error policy: %v, expected: %v, %v or %v", policy, ErrorPolicyAbort,
+			ErrorPolicyFalse, ErrorPolicyLog)
+	}
+}
+
+// Calculate the condition with error policy, onError is called with the errors which are skipped by ErrorPolicyLog.
+//
+// The policy is applied to the result of the whole condition: a sub condition which returns error is unknown, and
+// it is still decided by the other sub conditions if possible, like: unknown || true is true, unknown && false is
+// false, but !unknown is unknown. So the policy is not inverted by NOT.
+func Calculate(condition Condition, object objects.StructuredObject, policy ErrorPolicy, onError func(error)) (bool, error) {
+	result, err := condition.Calculate(object)
+	if err != nil {
+		return false, HandleError(err, policy, onError)
+	}
+	return result, nil
+}
+
+// HandleError handle the error of a condition by policy, the condition is treated as false if nil is returned,
+// otherwise the calculation should stop with the returned error.
+func HandleError(err error, policy ErrorPolicy, onError func(error)) error {
+	switch policy {
+	case ErrorPolicyFalse:
+		return nil
+	case ErrorPolicyLog:
+		if onError != nil {
+			onError(err)
+		}
+		return nil
+	default:
+		return err
+	}
+}
@@ -0,0 +1,75 @@
+package conditions
+
+import (
+	"fmt"
+	"github.com/storm-blue/rubick/pkg/modifier/objects"
+)
+
+// quantifierCondition calculate condition on each item of the value of key, the key is usually multi-valued like:
+// spec.containers[*].image. Map items can be accessed by their keys and any item can be accessed by objects.SelfKey.
+type quantifierCondition struct {
+	all       bool
+	key       string
+	condition Condition
+}
+
+func (c *quantifierCondition) And(condition Condition) Condition {
+	return &CombinationCondition{
+		left:     c,
+		right:    condition,
+		operator: And,
+	}
+}
+
+func (c *quantifierCondition) Or(condition Condition) Condition {
+	return &CombinationCondition{
+		left:     c,
+		right:    condition,
+		operator: Or,
+	}
+}
+
+// Calculate returns true if any (or all) of the items match condition,
+// so ANY of empty value is false and ALL of empty value is true.
+// Items are calculated like || for ANY (&& for ALL), so a failed item is unknown, the error is returned unless
+// another item decides the result.
+func (c *quantifierCondition) Calculate(object objects.StructuredObject) (bool, error) {
+	v, err := object.Get(c.key)
+	if err != nil {
+		return false, err
+	}
+
+	var items []interface{}
+	switch v_ := v.(type) {
+	case []interface{}:
+		items = v_
+	case nil:
+	default:
+		items = []interface{}{v_}
+	}
+
+	var unknown error
+	for _, item := range items {
+		r, err := c.condition.Calculate(objects.ElementObject(object, item))
+		if err != nil {
+			if unknown == nil {
+				unknown = err
+			}
+			continue
+		}
+		if r != c.all {
+			return r, nil
+		}
+	}
+	if unknown != nil {
+		return false, unknown
+	}
+	return c.all, nil
+}
+
+func (c *quantifierCondition) String() string {
+	if c.all {
+		return fmt.Sprintf("ALL(%v, %v)", c.key, c.condition)
+	}
+	return fmt.Sprintf("ANY(%v, %v)", c.key, c.condition)
+}
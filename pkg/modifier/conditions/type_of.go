@@ -0,0 +1,90 @@
+package conditions
+
+import (
+	"fmt"
+	"github.com/storm-blue/rubick/pkg/modifier/objects"
+)
+
+// types of values which are returned by TypeOf
+const (
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeBool    = "bool"
+	TypeArray   = "array"
+	TypeMap     = "map"
+	TypeNull    = "null"
+	TypeMissing = "missing"
+)
+
+// Types are all the valid types for TYPE_OF conditions.
+var Types = []string{TypeString, TypeNumber, TypeBool, TypeArray, TypeMap, TypeNull, TypeMissing}
+
+type typeOfCondition struct {
+	operator  int
+	key       string
+	valueType string
+}
+
+func (c *typeOfCondition) And(condition Condition) Condition {
+	return &CombinationCondition{
+		left:     c,
+		right:    condition,
+		operator: And,
+	}
+}
+
+func (c *typeOfCondition) Or(condition Condition) Condition {
+	return &CombinationCondition{
+		left:     c,
+		right:    condition,
+		operator: Or,
+	}
+}
+
+func (c *typeOfCondition) Calculate(object objects.StructuredObject) (bool, error) {
+	valueType := TypeMissing
+	if object.Exist(c.key) {
+		v, err := object.Get(c.key)
+		if err != nil {
+			return false, err
+		}
+		valueType = TypeOf(v)
+	}
+
+	switch c.operator {
+	case EqualTo:
+		return valueType == c.valueType, nil
+	case NotEqual:
+		return valueType != c.valueType, nil
+	default:
+		return false, fmt.Errorf("calculate type error: unsupported operator: %v", c.operator)
+	}
+}
+
+func (c *typeOfCondition) String() string {
+	operatorString := "=="
+	if c.operator == NotEqual {
+		operatorString = "!="
+	}
+	return fmt.Sprintf("TYPE_OF(%v) %v %v", c.key, operatorString, c.valueType)
+}
+
+// TypeOf get the type of value, which is one of TypeString, TypeNumber, TypeBool, TypeArray, TypeMap and TypeNull.
+func TypeOf(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return TypeString
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return TypeNumber
+	case bool:
+		return TypeBool
+	case []interface{}:
+		return TypeArray
+	case map[interface{}]interface{}:
+		return TypeMap
+	case nil:
+		return TypeNull
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
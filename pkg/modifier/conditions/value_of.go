@@ -34,7 +34,12 @@ func (s *valueOfCondition) Calculate(object objects.StructuredObject) (bool, err
 		return false, err
 	}
 
-	return calculate(s.operator, v, s.value)
+	value, err := resolveValue(s.value, object)
+	if err != nil {
+		return false, err
+	}
+
+	return calculate(s.operator, v, value)
 }
 
 func (s *valueOfCondition) String() string {
@@ -71,8 +76,14 @@ func (s *valueOfCondition) String() string {
 		valueString = strconv.FormatFloat(float64(tv), 'g', 20, 64)
 	case float64:
 		valueString = strconv.FormatFloat(tv, 'g', 20, 64)
+	case nil:
+		valueString = "null"
+	case bool:
+		valueString = strconv.FormatBool(tv)
 	case string:
 		valueString = tv
+	case Valuable:
+		valueString = tv.String()
 	case []interface{}:
 		valueString = "<array>"
 	case map[interface{}]interface{}:
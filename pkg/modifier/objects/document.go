@@ -0,0 +1,627 @@
+package objects
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	yamlv3 "go.yaml.in/yaml/v3"
+	"gopkg.in/yaml.v2"
+)
+
+const documentKey = "__metadata.__document"
+
+// document keeps the yaml node which object is decoded from and the value decoded at that time. When object is
+// encoded, only the changed parts of node are updated, so that key order, comments, anchors, quoting styles
+// and block scalars of the unchanged parts are preserved.
+type document struct {
+	node  *yamlv3.Node
+	value map[interface{}]interface{}
+	// orders are the orders of keys added to maps since the node is updated
+	orders keyOrders
+}
+
+// documentOf returns the document of object by its metadata, it is nil if object is not decoded from yaml.
+func documentOf(metadata Metadata) *document {
+	m, _ := metadata.(_metadata)
+	doc, _ := m[documentKey].(*document)
+	return doc
+}
+
+// addKey record that key is added to m, so that new keys are written in the order they are added.
+func (d *document) addKey(m map[interface{}]interface{}, key interface{}) {
+	if d.orders == nil {
+		d.orders = keyOrders{}
+	}
+	d.orders.add(m, key)
+}
+
+// addNodeKeys record the keys of value in the order of node, value is merged from src which is decoded from node.
+func (d *document) addNodeKeys(value, src interface{}, node *yamlv3.Node) {
+	for node.Kind == yamlv3.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node.Kind == yamlv3.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		s, ok := src.(map[interface{}]interface{})
+		if !ok || node.Kind != yamlv3.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, ok := nodeKey(node.Content[i])
+			if !ok {
+				continue
+			}
+			if _, ok := v[key]; ok {
+				d.addKey(v, key)
+				d.addNodeKeys(v[key], s[key], node.Content[i+1])
+			}
+		}
+	case []interface{}:
+		s, ok := src.([]interface{})
+		if !ok || node.Kind != yamlv3.SequenceNode || len(s) != len(node.Content) {
+			return
+		}
+		for i, item := range s {
+			// items are merged by name or copied
+			index := indexByName(v, item)
+			if index < 0 {
+				index = slices.IndexFunc(v, func(e interface{}) bool {
+					return reflect.DeepEqual(e, item)
+				})
+			}
+			if index >= 0 {
+				d.addNodeKeys(v[index], item, node.Content[i])
+			}
+		}
+	}
+}
+
+// keyOrders keeps the orders of keys added to maps, since maps have no order. Maps are identified by their
+// pointers, and they are kept by keyOrder so that the pointers are not reused.
+type keyOrders map[uintptr]*keyOrder
+
+type keyOrder struct {
+	m    map[interface{}]interface{}
+	keys []interface{}
+}
+
+func (o keyOrders) add(m map[interface{}]interface{}, key interface{}) {
+	pointer := reflect.ValueOf(m).Pointer()
+	order, ok := o[pointer]
+	if !ok {
+		order = &keyOrder{m: m}
+		o[pointer] = order
+	}
+	if !slices.Contains(order.keys, key) {
+		order.keys = append(order.keys, key)
+	}
+}
+
+// share let other map share the order of m, like: a copy of m.
+func (o keyOrders) share(m, other map[interface{}]interface{}) {
+	if order, ok := o[reflect.ValueOf(m).Pointer()]; ok {
+		o[reflect.ValueOf(other).Pointer()] = &keyOrder{m: other, keys: order.keys}
+	}
+}
+
+// sort keys of m in the order they are added, the other keys were in m before them (like: m is copied),
+// so they are kept ahead in their order.
+func (o keyOrders) sort(m map[interface{}]interface{}, keys []interface{}) {
+	order, ok := o[reflect.ValueOf(m).Pointer()]
+	if !ok {
+		return
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return slices.Index(order.keys, keys[i]) < slices.Index(order.keys, keys[j])
+	})
+}
+
+// sortNode sort pairs of mapping nodes created for value in the order keys are added.
+func (o keyOrders) sortNode(node *yamlv3.Node, value interface{}) {
+	if len(o) == 0 {
+		return
+	}
+	if object, ok := value.(_object); ok {
+		value = map[interface{}]interface{}(object)
+	}
+
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		if node.Kind != yamlv3.MappingNode {
+			return
+		}
+		var keys []interface{}
+		pairs := map[interface{}][]*yamlv3.Node{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, ok := nodeKey(node.Content[i])
+			if !ok {
+				return
+			}
+			keys = append(keys, key)
+			pairs[key] = node.Content[i : i+2]
+		}
+		o.sort(v, keys)
+
+		content := make([]*yamlv3.Node, 0, len(node.Content))
+		for _, key := range keys {
+			o.sortNode(pairs[key][1], v[key])
+			content = append(content, pairs[key]...)
+		}
+		node.Content = content
+	case []interface{}:
+		if node.Kind != yamlv3.SequenceNode || len(node.Content) != len(v) {
+			return
+		}
+		for i, element := range v {
+			o.sortNode(node.Content[i], element)
+		}
+	}
+}
+
+// fromDocument create object from a document node, values are decoded by yaml.v2 as before, so that
+// the types of values are not changed by the node.
+func fromDocument(node *yamlv3.Node) (StructuredObject, error) {
+	clearMergeTags(node)
+	value := map[interface{}]interface{}{}
+	if len(node.Content) > 0 {
+		yamlBytes, err := encodeNodes(node)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.Unmarshal(yamlBytes, value); err != nil {
+			return nil, err
+		}
+	}
+
+	snapshot := DeepCopy(value).(map[interface{}]interface{})
+	object := FromMap(value)
+	object.Metadata().(_metadata)[documentKey] = &document{node: node, value: snapshot}
+	return object, nil
+}
+
+// toDocument returns the document node of object, the node which object is decoded from is updated by
+// the changes of object, a new node is created if object is not decoded from yaml.
+func toDocument(object StructuredObject) (*yamlv3.Node, error) {
+	value := map[interface{}]interface{}{}
+	for key, v := range object.ToMap() {
+		if key != metadataKey {
+			value[key] = v
+		}
+	}
+
+	metadata, _ := object.Metadata().(_metadata)
+	doc, ok := metadata[documentKey].(*document)
+	if !ok {
+		node, err := newNode(value)
+		if err != nil {
+			return nil, err
+		}
+		return &yamlv3.Node{Kind: yamlv3.DocumentNode, Content: []*yamlv3.Node{node}}, nil
+	}
+
+	// the keys of object are copied to value without metadata
+	doc.orders.share(object.ToMap(), value)
+	r := &reconciler{changedAnchors: map[*yamlv3.Node]bool{}, orders: doc.orders}
+	if len(doc.node.Content) == 0 && len(value) == 0 {
+		// empty or comment only document which is not changed
+		return doc.node, nil
+	} else if len(doc.node.Content) == 0 {
+		// empty or comment only document
+		node, err := r.newNode(value)
+		if err != nil {
+			return nil, err
+		}
+		doc.node.Content = []*yamlv3.Node{node}
+	} else {
+		doc.node.Content[0] = r.reconcile(doc.node.Content[0], doc.value, value)
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	// the node is changed in place, so the value is updated to compare with the next changes,
+	// and the added keys are in the node now
+	doc.value = DeepCopy(value).(map[interface{}]interface{})
+	doc.orders = nil
+	return doc.node, nil
+}
+
+// encodeNodes encode nodes as yaml documents, sequences are not indented like yaml.v2 and kubectl.
+func encodeNodes(nodes ...*yamlv3.Node) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	encoder := yamlv3.NewEncoder(buf)
+	encoder.SetIndent(2)
+	encoder.CompactSeqIndent()
+	for _, node := range nodes {
+		if err := encoder.Encode(node); err != nil {
+			return nil, err
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// newNode encode value to node, strings which are not strings for yaml.v2 (like: "y", "on") are quoted,
+// so that they can be decoded as strings again.
+func newNode(value interface{}) (*yamlv3.Node, error) {
+	node := &yamlv3.Node{}
+	if err := node.Encode(value); err != nil {
+		return nil, err
+	}
+	quoteAmbiguousStrings(node)
+	return node, nil
+}
+
+func quoteAmbiguousStrings(node *yamlv3.Node) {
+	if node.Kind == yamlv3.ScalarNode {
+		if node.Tag == "!!str" && node.Style == 0 && !strings.Contains(node.Value, "\n") {
+			var v interface{}
+			if err := yaml.Unmarshal([]byte(node.Value), &v); err == nil && v != node.Value {
+				node.Style = yamlv3.DoubleQuotedStyle
+			}
+		}
+		return
+	}
+	for _, child := range node.Content {
+		quoteAmbiguousStrings(child)
+	}
+}
+
+// clearMergeTags clear the resolved tags of merge keys ("<<"), otherwise they are encoded as "!!merge <<".
+func clearMergeTags(node *yamlv3.Node) {
+	if node.Kind == yamlv3.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if key := node.Content[i]; key.Tag == "!!merge" && key.Style == 0 {
+				key.Tag = ""
+			}
+		}
+	}
+	for _, child := range node.Content {
+		clearMergeTags(child)
+	}
+}
+
+// reconciler update yaml node by the changes between the old and new value of it.
+type reconciler struct {
+	// changedAnchors are the anchored nodes which are changed, aliases of them are replaced by their own values
+	changedAnchors map[*yamlv3.Node]bool
+	// orders are the orders of keys added to maps
+	orders keyOrders
+	err    error
+}
+
+func (r *reconciler) reconcile(node *yamlv3.Node, oldValue, newValue interface{}) *yamlv3.Node {
+	if r.err != nil || (reflect.DeepEqual(oldValue, newValue) && !r.hasChangedAlias(node)) {
+		return node
+	}
+
+	result := r.update(node, oldValue, newValue)
+	if node.Anchor != "" {
+		r.changedAnchors[node] = true
+	}
+	return result
+}
+
+func (r *reconciler) update(node *yamlv3.Node, oldValue, newValue interface{}) *yamlv3.Node {
+	if object, ok := newValue.(_object); ok {
+		newValue = map[interface{}]interface{}(object)
+	}
+
+	switch node.Kind {
+	case yamlv3.MappingNode:
+		oldMap, ok1 := oldValue.(map[interface{}]interface{})
+		newMap, ok2 := newValue.(map[interface{}]interface{})
+		if ok1 && ok2 && r.updateMapping(node, oldMap, newMap) {
+			return node
+		}
+	case yamlv3.SequenceNode:
+		oldArray, ok1 := oldValue.([]interface{})
+		newArray, ok2 := newValue.([]interface{})
+		if ok1 && ok2 && len(oldArray) == len(node.Content) {
+			r.updateSequence(node, oldArray, newArray)
+			return node
+		}
+	case yamlv3.ScalarNode:
+		return r.replace(node, newValue, true)
+	}
+	// aliases, merged maps and changed types are replaced as a whole
+	return r.replace(node, newValue, false)
+}
+
+// updateMapping update pairs of mapping node in place, the order of existing keys is kept and new keys are
+// appended in the order they are added. It returns false if the keys of node can not be matched with the old value.
+func (r *reconciler) updateMapping(node *yamlv3.Node, oldMap, newMap map[interface{}]interface{}) bool {
+	var keys []interface{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, ok := nodeKey(node.Content[i])
+		if !ok {
+			return false
+		}
+		if _, ok := oldMap[key]; !ok {
+			return false
+		}
+		keys = append(keys, key)
+	}
+	// merge keys ("<<") or duplicated keys
+	if len(keys) != len(oldMap) {
+		return false
+	}
+
+	var newKeys []interface{}
+	for key := range newMap {
+		if _, ok := oldMap[key]; !ok {
+			newKeys = append(newKeys, key)
+		}
+	}
+	sort.Slice(newKeys, func(i, j int) bool {
+		return fmt.Sprint(newKeys[i]) < fmt.Sprint(newKeys[j])
+	})
+	r.orders.sort(newMap, newKeys)
+
+	var content []*yamlv3.Node
+	for i, key := range keys {
+		keyNode, valueNode, oldValue := node.Content[2*i], node.Content[2*i+1], oldMap[key]
+		value, ok := newMap[key]
+		if !ok {
+			// a renamed key takes the place of the old one, it is found by the same value
+			j := slices.IndexFunc(newKeys, func(newKey interface{}) bool {
+				return reflect.DeepEqual(oldValue, newMap[newKey])
+			})
+			if j == -1 {
+				continue
+			}
+			keyNode = r.replace(keyNode, newKeys[j], false)
+			value = newMap[newKeys[j]]
+			newKeys = slices.Delete(newKeys, j, j+1)
+		}
+		content = append(content, keyNode, r.reconcile(valueNode, oldValue, value))
+	}
+
+	for _, key := range newKeys {
+		keyNode, err := newNode(key)
+		if err != nil {
+			r.err = err
+			return true
+		}
+		valueNode, err := r.newNode(newMap[key])
+		if err != nil {
+			r.err = err
+			return true
+		}
+		content = append(content, keyNode, valueNode)
+	}
+
+	if len(oldMap) == 0 && len(content) > 0 {
+		// "{}" becomes a block mapping
+		node.Style &^= yamlv3.FlowStyle
+	}
+	node.Content = content
+	return true
+}
+
+// updateSequence update elements of sequence node in place. Elements are matched by value first, so that
+// comments follow the elements which are moved (like: SORT), the rest elements are matched by position.
+func (r *reconciler) updateSequence(node *yamlv3.Node, oldArray, newArray []interface{}) {
+	matched := make([]int, len(newArray))
+	used := make([]bool, len(oldArray))
+	for i, value := range newArray {
+		matched[i] = -1
+		for j, oldValue := range oldArray {
+			if !used[j] && reflect.DeepEqual(oldValue, value) {
+				matched[i], used[j] = j, true
+				break
+			}
+		}
+	}
+	for i := range newArray {
+		if matched[i] != -1 {
+			continue
+		}
+		for j := range oldArray {
+			if !used[j] {
+				matched[i], used[j] = j, true
+				break
+			}
+		}
+	}
+
+	content := make([]*yamlv3.Node, 0, len(newArray))
+	for i, value := range newArray {
+		if matched[i] == -1 {
+			element, err := r.newNode(value)
+			if err != nil {
+				r.err = err
+				return
+			}
+			content = append(content, element)
+		} else {
+			content = append(content, r.reconcile(node.Content[matched[i]], oldArray[matched[i]], value))
+		}
+	}
+
+	if len(oldArray) == 0 && len(content) > 0 {
+		// "[]" becomes a block sequence
+		node.Style &^= yamlv3.FlowStyle
+	}
+	node.Content = content
+}
+
+// replace create a new node for value with the comments of node, quoting style and block style are kept
+// if keepStyle is true and both of them are strings.
+func (r *reconciler) replace(node *yamlv3.Node, value interface{}, keepStyle bool) *yamlv3.Node {
+	result, err := r.newNode(value)
+	if err != nil {
+		r.err = err
+		return node
+	}
+	if keepStyle && result.Kind == yamlv3.ScalarNode && result.Tag == "!!str" && node.Tag == "!!str" &&
+		node.Style&(yamlv3.DoubleQuotedStyle|yamlv3.SingleQuotedStyle|yamlv3.LiteralStyle|yamlv3.FoldedStyle) != 0 {
+		result.Style = node.Style
+	}
+	result.HeadComment = node.HeadComment
+	result.LineComment = node.LineComment
+	result.FootComment = node.FootComment
+	return result
+}
+
+// newNode create node for value like newNode, keys of maps are in the order they are added.
+func (r *reconciler) newNode(value interface{}) (*yamlv3.Node, error) {
+	node, err := newNode(value)
+	if err != nil {
+		return nil, err
+	}
+	r.orders.sortNode(node, value)
+	return node, nil
+}
+
+// hasChangedAlias returns true if node has aliases of changed anchors.
+func (r *reconciler) hasChangedAlias(node *yamlv3.Node) bool {
+	if len(r.changedAnchors) == 0 {
+		return false
+	}
+	if node.Kind == yamlv3.AliasNode {
+		return r.changedAnchors[node.Alias]
+	}
+	for _, child := range node.Content {
+		if r.hasChangedAlias(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeKey decode key of mapping node like yaml.v2, so that it is the same as the key in value.
+func nodeKey(node *yamlv3.Node) (interface{}, bool) {
+	if node.Kind != yamlv3.ScalarNode {
+		return nil, false
+	}
+	yamlBytes, err := encodeNodes(&yamlv3.Node{Kind: yamlv3.ScalarNode, Style: node.Style, Tag: node.Tag, Value: node.Value})
+	if err != nil {
+		return nil, false
+	}
+	var key interface{}
+	if err := yaml.Unmarshal(yamlBytes, &key); err != nil {
+		return nil, false
+	}
+	return key, true
+}
+
+// DocumentError is the error of a malformed document in multi-document yaml.
+type DocumentError struct {
+	// Index of the document, starts from 0
+	Index int
+	// Line of the error in the whole yaml, starts from 1
+	Line int
+	Err  error
+}
+
+func (e *DocumentError) Error() string {
+	return fmt.Sprintf("document %d (line %d): %v", e.Index, e.Line, e.Err)
+}
+
+func (e *DocumentError) Unwrap() error {
+	return e.Err
+}
+
+// rawDocument is a document split from multi-document yaml, line is the line of its first line in the whole yaml.
+type rawDocument struct {
+	text string
+	line int
+}
+
+var documentStartPattern = regexp.MustCompile(`^---(\s|$)`)
+var decodeErrorPattern = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// splitDocuments split multi-document yaml by "---" lines, so that the documents after a malformed one can
+// still be decoded. Comments before the first "---" belong to the first document, like yaml.
+func splitDocuments(multiYaml string) []rawDocument {
+	var documents []rawDocument
+	current := rawDocument{line: 1}
+	explicit := false
+	lines := strings.SplitAfter(multiYaml, "\n")
+	for i, line := range lines {
+		if documentStartPattern.MatchString(line) && (explicit || hasContent(current.text)) {
+			documents = append(documents, current)
+			current = rawDocument{line: i + 1}
+		}
+		if documentStartPattern.MatchString(line) {
+			explicit = true
+		}
+		current.text += line
+	}
+	if explicit || strings.TrimSpace(current.text) != "" {
+		documents = append(documents, current)
+	}
+	return documents
+}
+
+// comments returns the comment lines of text.
+func comments(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// hasContent returns false if text has only spaces and comments.
+func hasContent(text string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeDocument decode a document split by splitDocuments, errors are returned with the line in the whole yaml.
+func decodeDocument(index int, raw rawDocument) (*yamlv3.Node, error) {
+	node := &yamlv3.Node{}
+	decoder := yamlv3.NewDecoder(strings.NewReader(raw.text))
+	if err := decoder.Decode(node); err != nil && err != io.EOF {
+		documentError := &DocumentError{Index: index, Line: raw.line, Err: err}
+		if match := decodeErrorPattern.FindStringSubmatch(err.Error()); match != nil {
+			line, _ := strconv.Atoi(match[1])
+			documentError.Line = raw.line + line - 1
+			documentError.Err = errors.New(match[2])
+		}
+		return nil, documentError
+	}
+	if isEmptyDocument(node) {
+		// the comments of empty document are kept, so that they can be written back
+		return &yamlv3.Node{Kind: yamlv3.DocumentNode, HeadComment: comments(raw.text)}, nil
+	}
+	if len(node.Content) > 0 {
+		if root := node.Content[0]; root.Kind != yamlv3.MappingNode {
+			return nil, &DocumentError{Index: index, Line: raw.line + root.Line - 1,
+				Err: fmt.Errorf("document is not a map: %s", root.Tag)}
+		}
+	}
+	return node, nil
+}
+
+// isEmptyDocument returns true if document has no content, like: "---" or a document with comments only.
+func isEmptyDocument(node *yamlv3.Node) bool {
+	return len(node.Content) == 0 || isNullNode(node.Content[0])
+}
+
+func isNullNode(node *yamlv3.Node) bool {
+	return node.Kind == yamlv3.ScalarNode && node.Tag == "!!null"
+}
@@ -0,0 +1,130 @@
+package objects
+
+import (
+	"fmt"
+	"strings"
+)
+
+const filterPrefix = "?("
+const filterSuffix = ")"
+
+// ElementFilter reports whether an element of array matches the condition of filter index.
+type ElementFilter func(element StructuredObject) (bool, error)
+
+// FilterCompiler compile the condition of filter index, like: HAS_PREFIX(image, "old/")
+type FilterCompiler func(condition string) (ElementFilter, error)
+
+// FilterErrorHandler handle the error of filter index condition of an element, it returns whether the element
+// matches instead, or returns error to stop accessing the key.
+type FilterErrorHandler func(err error) (bool, error)
+
+// KeyOptions are options of accessing keys of object, see WithOptions.
+type KeyOptions struct {
+	// Filter compile the conditions of filter indexes, like: containers[?(HAS_PREFIX(image, "old/"))],
+	// filter indexes are not supported if it is nil.
+	Filter FilterCompiler
+	// OnFilterError handle the errors of filter conditions, errors are returned if it is nil.
+	OnFilterError FilterErrorHandler
+}
+
+// access is the state of accessing a key of object. Elements matched by filter indexes share the metadata
+// (like scripts variables) and options of the object, and the conditions are compiled once for an access.
+type access struct {
+	metadata Metadata
+	options  KeyOptions
+	filters  map[string]ElementFilter
+}
+
+func newAccess(metadata Metadata, options KeyOptions) *access {
+	return &access{metadata: metadata, options: options, filters: map[string]ElementFilter{}}
+}
+
+// element wrap element of array for the filter conditions.
+func (a *access) element(element interface{}) StructuredObject {
+	return WithOptions(elementObject(a.metadata, element), a.options)
+}
+
+// addKey record that key is added to m, so that new keys are written in the order they are added.
+func (a *access) addKey(m map[interface{}]interface{}, key interface{}) {
+	if doc := documentOf(a.metadata); doc != nil {
+		doc.addKey(m, key)
+	}
+}
+
+func (a *access) filter(index YamlIndex) (ElementFilter, error) {
+	if filter, ok := a.filters[index.value]; ok {
+		return filter, nil
+	}
+	if a.options.Filter == nil {
+		return nil, fmt.Errorf("filter is not supported without filter compiler: %v", index.value)
+	}
+	filter, err := a.options.Filter(index.value)
+	if err != nil {
+		return nil, fmt.Errorf("parse filter index error: %v", err)
+	}
+	a.filters[index.value] = filter
+	return filter, nil
+}
+
+func isFilterIndex(index string) bool {
+	return strings.HasPrefix(index, filterPrefix) && strings.HasSuffix(index, filterSuffix)
+}
+
+// parseFilterIndex parse the condition of filter index, the condition is compiled when the key is accessed.
+func parseFilterIndex(index string) (YamlIndex, error) {
+	condition := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(index, filterPrefix), filterSuffix))
+	if condition == "" {
+		return YamlIndex{}, fmt.Errorf("parse filter index error: empty condition: %v", index)
+	}
+	return YamlIndex{indexType: IndexFilter, value: condition}, nil
+}
+
+// FilterConditions returns the conditions of filter indexes in key, so that they can be validated before the key
+// is accessed. Nothing is returned if key is invalid.
+func FilterConditions(key string) []string {
+	var conditions []string
+	for key != "" {
+		segment, rest, err := ParseNextSegment(key)
+		if err != nil {
+			return nil
+		}
+		if segment.isArray && segment.index.indexType == IndexFilter {
+			conditions = append(conditions, segment.index.value)
+		}
+		key = rest
+	}
+	return conditions
+}
+
+// matchFilter reports whether element matches the filter index, errors of the filter are handled by
+// KeyOptions.OnFilterError if it is set.
+func matchFilter(a *access, element interface{}, index YamlIndex) (bool, error) {
+	filter, err := a.filter(index)
+	if err != nil {
+		return false, err
+	}
+	matched, err := filter(a.element(element))
+	if err != nil {
+		err = fmt.Errorf("filter element error: %v, filter: %v", err, index.value)
+		if a.options.OnFilterError != nil {
+			return a.options.OnFilterError(err)
+		}
+		return false, err
+	}
+	return matched, nil
+}
+
+// filterElements returns all elements of slice which match the filter index.
+func filterElements(a *access, slice []interface{}, index YamlIndex) ([]interface{}, error) {
+	var result []interface{}
+	for _, e := range slice {
+		matched, err := matchFilter(a, e, index)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
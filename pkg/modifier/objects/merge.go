@@ -0,0 +1,152 @@
+package objects
+
+import (
+	"fmt"
+
+	yamlv3 "go.yaml.in/yaml/v3"
+	"gopkg.in/yaml.v2"
+)
+
+// ListStrategy decides how lists are merged by DeepMerge.
+type ListStrategy string
+
+const (
+	// ListReplace replaces the original list with the new list.
+	ListReplace ListStrategy = "replace"
+	// ListAppend appends items of the new list to the original list.
+	ListAppend ListStrategy = "append"
+	// ListMergeByName merges map items which have the same "name" like kubernetes strategic merge,
+	// other items are appended.
+	ListMergeByName ListStrategy = "merge"
+)
+
+// mergeKey is the key of map items to match when lists are merged by ListMergeByName.
+const mergeKey = "name"
+
+// ListStrategies are all the valid list strategies.
+var ListStrategies = []ListStrategy{ListReplace, ListAppend, ListMergeByName}
+
+// DeepMerge merge src into dst and returns the result, maps are merged recursively,
+// lists are merged by strategy, and other values of dst are replaced by src.
+// src is deep copied, so the result does not share anything with src.
+func DeepMerge(dst, src interface{}, strategy ListStrategy) (interface{}, error) {
+	switch s := src.(type) {
+	case map[interface{}]interface{}:
+		d, ok := dst.(map[interface{}]interface{})
+		if !ok {
+			return DeepCopy(s), nil
+		}
+		for key, value := range s {
+			merged, err := DeepMerge(d[key], value, strategy)
+			if err != nil {
+				return nil, err
+			}
+			d[key] = merged
+		}
+		return d, nil
+	case []interface{}:
+		d, ok := dst.([]interface{})
+		if !ok {
+			return DeepCopy(s), nil
+		}
+		merged, err := mergeList(d, s, strategy)
+		if err != nil {
+			return nil, err
+		}
+		return merged, nil
+	default:
+		return DeepCopy(src), nil
+	}
+}
+
+// Fragment is the yaml or json value merged into object, like the value of MERGE, the key order of it is kept
+// when new keys are written.
+type Fragment struct {
+	value interface{}
+	node  *yamlv3.Node
+}
+
+// ParseFragment parse yaml or json as Fragment, the value is decoded by yaml.v2 like objects.
+func ParseFragment(data []byte) (*Fragment, error) {
+	var value interface{}
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	node := &yamlv3.Node{}
+	if err := yamlv3.Unmarshal(data, node); err != nil {
+		return nil, err
+	}
+	return &Fragment{value: value, node: node}, nil
+}
+
+// Value returns the value of fragment, it should not be changed.
+func (f *Fragment) Value() interface{} {
+	return f.value
+}
+
+func (f *Fragment) String() string {
+	return fmt.Sprint(f.value)
+}
+
+// MergeFragment deep merge fragment into the value of key by DeepMerge, new keys are written in the order
+// of fragment.
+func MergeFragment(object StructuredObject, key string, fragment *Fragment, strategy ListStrategy) error {
+	v, err := object.Get(key)
+	if err != nil {
+		return err
+	}
+	merged, err := DeepMerge(v, fragment.value, strategy)
+	if err != nil {
+		return err
+	}
+	if err := object.Set(key, merged); err != nil {
+		return err
+	}
+	if doc := documentOf(object.Metadata()); doc != nil {
+		doc.addNodeKeys(merged, fragment.value, fragment.node)
+	}
+	return nil
+}
+
+func mergeList(dst, src []interface{}, strategy ListStrategy) ([]interface{}, error) {
+	switch strategy {
+	case ListReplace:
+		return DeepCopy(src).([]interface{}), nil
+	case ListAppend:
+		return append(dst, DeepCopy(src).([]interface{})...), nil
+	case ListMergeByName:
+		for _, item := range src {
+			index := indexByName(dst, item)
+			if index < 0 {
+				dst = append(dst, DeepCopy(item))
+				continue
+			}
+			merged, err := DeepMerge(dst[index], item, strategy)
+			if err != nil {
+				return nil, err
+			}
+			dst[index] = merged
+		}
+		return dst, nil
+	default:
+		return nil, fmt.Errorf("merge list error: unknown list strategy: %v", strategy)
+	}
+}
+
+// indexByName find the index of map item in list which has the same name with item, returns -1 if not found.
+func indexByName(list []interface{}, item interface{}) int {
+	m, ok := item.(map[interface{}]interface{})
+	if !ok {
+		return -1
+	}
+	name, ok := m[mergeKey]
+	if !ok {
+		return -1
+	}
+	for i, e := range list {
+		if e_, ok := e.(map[interface{}]interface{}); ok && e_[mergeKey] == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -1,17 +1,26 @@
 package objects
 
 const removedKey = "__metadata.__removed"
+const emptyKey = "__metadata.__empty"
+const variablePrefix = "__metadata.__variable."
 const _true = "true"
 const _false = "false"
 
 type Metadata interface {
 	Removed() bool
 	MarkRemoved(bool)
+	// Empty returns true if object is an empty or comment only document kept by YAMLsOptions.KeepEmpty,
+	// scripts are not executed on it and it is written back as it was.
+	Empty() bool
 	Set(key string, value string)
 	Get(key string) string
+
+	// SetVariable set a scripts variable, variables live as long as the object.
+	SetVariable(name string, value interface{})
+	GetVariable(name string) (interface{}, bool)
 }
 
-type _metadata map[string]string
+type _metadata map[string]interface{}
 
 func (m _metadata) Removed() bool {
 	return m[removedKey] == _true
@@ -25,10 +34,24 @@ func (m _metadata) MarkRemoved(b bool) {
 	}
 }
 
+func (m _metadata) Empty() bool {
+	return m[emptyKey] == _true
+}
+
 func (m _metadata) Set(key string, value string) {
 	m[key] = value
 }
 
 func (m _metadata) Get(key string) string {
-	return m[key]
+	v, _ := m[key].(string)
+	return v
+}
+
+func (m _metadata) SetVariable(name string, value interface{}) {
+	m[variablePrefix+name] = value
+}
+
+func (m _metadata) GetVariable(name string) (interface{}, bool) {
+	v, ok := m[variablePrefix+name]
+	return v, ok
 }
@@ -1,14 +1,17 @@
 package objects
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"github.com/storm-blue/rubick/pkg/common"
 	"gopkg.in/yaml.v2"
 	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+
+	yamlv3 "go.yaml.in/yaml/v3"
 )
 
 const metadataKey = ".__object.__metadata"
@@ -31,29 +34,66 @@ func FromJSON(jsonStr string) (StructuredObject, error) {
 	return FromYAML(string(yamlBytes))
 }
 
+// FromYAML decode the first document of yamlStr, the document node is kept, so that the unchanged parts
+// (key order, comments, anchors and styles) are preserved by ToYAML.
 func FromYAML(yamlStr string) (StructuredObject, error) {
-	result := map[interface{}]interface{}{}
-	if err := yaml.Unmarshal([]byte(yamlStr), result); err != nil {
+	node := &yamlv3.Node{}
+	if err := yamlv3.Unmarshal([]byte(yamlStr), node); err != nil {
 		return nil, err
 	}
-	return FromMap(result), nil
+	if node.Kind == 0 {
+		node.Kind = yamlv3.DocumentNode
+	}
+	return fromDocument(node)
 }
 
+// YAMLsOptions are options of decoding multi-document yaml.
+type YAMLsOptions struct {
+	// SkipInvalid skip malformed documents instead of failing, errors of them are returned
+	SkipInvalid bool
+	// KeepEmpty keep empty and comment only documents as empty objects, so that they are written back by ToYAMLs
+	KeepEmpty bool
+}
+
+// FromYAMLs decode all documents of multiYaml, it fails on the first malformed document with a *DocumentError.
+// Empty and comment only documents are skipped.
 func FromYAMLs(multiYaml string) ([]StructuredObject, error) {
+	result, _, err := FromYAMLsWithOptions(multiYaml, YAMLsOptions{})
+	return result, err
+}
+
+// FromYAMLsWithOptions decode all documents of multiYaml by options, errors of the skipped malformed documents
+// are returned if options.SkipInvalid is true.
+func FromYAMLsWithOptions(multiYaml string, options YAMLsOptions) ([]StructuredObject, []*DocumentError, error) {
 	var result []StructuredObject
-	decoder := yaml.NewDecoder(bytes.NewBuffer([]byte(multiYaml)))
+	var skipped []*DocumentError
 
-	for {
-		o := map[interface{}]interface{}{}
-		if err := decoder.Decode(o); err != nil {
-			if err.Error() == "EOF" {
-				break
+	for i, raw := range splitDocuments(multiYaml) {
+		node, err := decodeDocument(i, raw)
+		if err == nil && isEmptyDocument(node) && !options.KeepEmpty {
+			continue
+		}
+
+		var o StructuredObject
+		if err == nil {
+			if o, err = fromDocument(node); err != nil {
+				err = &DocumentError{Index: i, Line: raw.line, Err: err}
+			} else if isEmptyDocument(node) {
+				o.Metadata().(_metadata)[emptyKey] = _true
 			}
 		}
-		result = append(result, FromMap(o))
+		if err != nil {
+			documentError := err.(*DocumentError)
+			if !options.SkipInvalid {
+				return nil, nil, documentError
+			}
+			skipped = append(skipped, documentError)
+			continue
+		}
+		result = append(result, o)
 	}
 
-	return result, nil
+	return result, skipped, nil
 }
 
 func FromMap(m map[interface{}]interface{}) StructuredObject {
@@ -69,26 +109,33 @@ func ToYAML(object StructuredObject) (string, error) {
 	return object.ToYAML()
 }
 
+// ToYAMLs encode objects as multi-document yaml, empty documents kept by YAMLsOptions.KeepEmpty are written
+// as bare "---" separators with their comments.
 func ToYAMLs(_objects []StructuredObject) (string, error) {
-	buf := &bytes.Buffer{}
-	encoder := yaml.NewEncoder(buf)
-	for _, object := range _objects {
-		o := object.(_object)
-		metadata := object.Metadata()
-
-		// remove metadata before marshal
-		delete(o, metadataKey)
-
-		err := encoder.Encode(o)
-
-		// restore metadata after marshal
-		o[metadataKey] = metadata
+	result := strings.Builder{}
+	for i, object := range _objects {
+		node, err := toDocument(object)
+		if err != nil {
+			return "", err
+		}
+		if len(node.Content) == 0 {
+			result.WriteString("---\n")
+			if node.HeadComment != "" {
+				result.WriteString(node.HeadComment + "\n")
+			}
+			continue
+		}
 
+		yamlBytes, err := encodeNodes(node)
 		if err != nil {
 			return "", err
 		}
+		if i > 0 {
+			result.WriteString("---\n")
+		}
+		result.Write(yamlBytes)
 	}
-	return buf.String(), nil
+	return result.String(), nil
 }
 
 type StructuredObject interface {
@@ -106,6 +153,14 @@ type StructuredObject interface {
 
 	Delete(string) error
 	Set(key string, value interface{}) error
+	// Rename the last segment of key to newName, the value is kept.
+	Rename(key string, newName string) error
+	// UpdateArrays replace every array matched by key with the result of update,
+	// a missing array is updated as an empty one and only set if the result is not empty.
+	UpdateArrays(key string, update func([]interface{}) ([]interface{}, error)) error
+	// ExpandKey expand multi-valued key like "a[*].b" to the keys of all matched values like "a[0].b", "a[1].b",
+	// other keys are returned as they are.
+	ExpandKey(key string) ([]string, error)
 
 	Exist(string) bool
 	Len() int
@@ -127,6 +182,12 @@ func (o _object) Metadata() Metadata {
 	return metadata.(Metadata)
 }
 
+// metadata returns the metadata of o without creating it, it is nil if o has no metadata, like objects of sub maps.
+func (o _object) metadata() Metadata {
+	metadata, _ := o[metadataKey].(Metadata)
+	return metadata
+}
+
 func (o _object) Len() int {
 	return len(o)
 }
@@ -144,6 +205,124 @@ func (o _object) ToMap() map[interface{}]interface{} {
 }
 
 func (o _object) GetObject(s string) (StructuredObject, error) {
+	return optionsObject{_object: o}.GetObject(s)
+}
+
+func (o _object) GetObjects(s string) ([]StructuredObject, error) {
+	return optionsObject{_object: o}.GetObjects(s)
+}
+
+func (o _object) GetArray(s string) ([]interface{}, error) {
+	return optionsObject{_object: o}.GetArray(s)
+}
+
+func (o _object) GetInt(s string) (int, error) {
+	return optionsObject{_object: o}.GetInt(s)
+}
+
+func (o _object) GetInt32(s string) (int32, error) {
+	return optionsObject{_object: o}.GetInt32(s)
+}
+
+func (o _object) GetInt64(s string) (int64, error) {
+	return optionsObject{_object: o}.GetInt64(s)
+}
+
+func (o _object) GetFloat32(s string) (float32, error) {
+	return optionsObject{_object: o}.GetFloat32(s)
+}
+
+func (o _object) GetFloat64(s string) (float64, error) {
+	return optionsObject{_object: o}.GetFloat64(s)
+}
+
+func (o _object) GetString(s string) (string, error) {
+	return optionsObject{_object: o}.GetString(s)
+}
+
+func (o _object) Delete(key string) error {
+	return optionsObject{_object: o}.Delete(key)
+}
+
+func (o _object) Exist(key string) bool {
+	return optionsObject{_object: o}.Exist(key)
+}
+
+func (o _object) Get(key string) (interface{}, error) {
+	return optionsObject{_object: o}.Get(key)
+}
+
+func (o _object) Set(key string, value interface{}) error {
+	return optionsObject{_object: o}.Set(key, value)
+}
+
+func (o _object) Rename(key string, newName string) error {
+	return optionsObject{_object: o}.Rename(key, newName)
+}
+
+func (o _object) ExpandKey(key string) ([]string, error) {
+	return optionsObject{_object: o}.ExpandKey(key)
+}
+
+func (o _object) UpdateArrays(key string, update func([]interface{}) ([]interface{}, error)) error {
+	return optionsObject{_object: o}.UpdateArrays(key, update)
+}
+
+func (o _object) ToYAML() (string, error) {
+	node, err := toDocument(o)
+	if err != nil {
+		return "", err
+	}
+	yamlBytes, err := encodeNodes(node)
+	if err != nil {
+		return "", err
+	}
+	return string(yamlBytes), nil
+}
+
+func (o _object) ToJSON() (string, error) {
+	metadata := o.Metadata()
+
+	// remove metadata before marshal
+	delete(o, metadataKey)
+	bs, err := json.Marshal(o)
+
+	// restore metadata after marshal
+	o[metadataKey] = metadata
+
+	if err != nil {
+		return "", err
+	}
+	return string(bs), nil
+}
+
+// WithOptions returns object whose keys are accessed with options, like: filter indexes are supported by
+// options.Filter. Values and metadata are shared with object.
+func WithOptions(object StructuredObject, options KeyOptions) StructuredObject {
+	o, ok := object.(_object)
+	if view, isView := object.(optionsObject); isView {
+		o, ok = view._object, true
+	}
+	if !ok {
+		return object
+	}
+	if options.Filter == nil && options.OnFilterError == nil {
+		return o
+	}
+	return optionsObject{_object: o, options: options}
+}
+
+// optionsObject is the object whose keys are accessed with options, see WithOptions.
+type optionsObject struct {
+	_object
+	options KeyOptions
+}
+
+func (o optionsObject) keyAccess() *access {
+	return newAccess(o.metadata(), o.options)
+}
+
+func (o optionsObject) GetObject(s string) (StructuredObject, error) {
 	object_, err := o.Get(s)
 	if err != nil {
 		return nil, err
@@ -155,15 +334,15 @@ func (o _object) GetObject(s string) (StructuredObject, error) {
 
 	switch object := object_.(type) {
 	case _object:
-		return object, nil
+		return WithOptions(object, o.options), nil
 	case map[interface{}]interface{}:
-		return _object(object), nil
+		return WithOptions(_object(object), o.options), nil
 	default:
 		return nil, fmt.Errorf("GetObject error: value is not object, key: %v", s)
 	}
 }
 
-func (o _object) GetObjects(s string) ([]StructuredObject, error) {
+func (o optionsObject) GetObjects(s string) ([]StructuredObject, error) {
 	array, err := o.GetArray(s)
 	if err != nil {
 		return nil, err
@@ -172,7 +351,7 @@ func (o _object) GetObjects(s string) ([]StructuredObject, error) {
 	var result []StructuredObject
 	for _, object_ := range array {
 		if object, ok := object_.(map[interface{}]interface{}); ok {
-			result = append(result, _object(object))
+			result = append(result, WithOptions(_object(object), o.options))
 		} else {
 			return nil, fmt.Errorf("GetObjects error: value is not objects, key: %v", s)
 		}
@@ -180,7 +359,7 @@ func (o _object) GetObjects(s string) ([]StructuredObject, error) {
 	return result, nil
 }
 
-func (o _object) GetArray(s string) ([]interface{}, error) {
+func (o optionsObject) GetArray(s string) ([]interface{}, error) {
 	object_, err := o.Get(s)
 	if err != nil {
 		return nil, err
@@ -198,7 +377,7 @@ func (o _object) GetArray(s string) ([]interface{}, error) {
 	}
 }
 
-func (o _object) GetInt(s string) (int, error) {
+func (o optionsObject) GetInt(s string) (int, error) {
 	object_, err := o.Get(s)
 	if err != nil {
 		return 0, err
@@ -224,7 +403,7 @@ func (o _object) GetInt(s string) (int, error) {
 	}
 }
 
-func (o _object) GetInt32(s string) (int32, error) {
+func (o optionsObject) GetInt32(s string) (int32, error) {
 	object_, err := o.Get(s)
 	if err != nil {
 		return 0, err
@@ -250,7 +429,7 @@ func (o _object) GetInt32(s string) (int32, error) {
 	}
 }
 
-func (o _object) GetInt64(s string) (int64, error) {
+func (o optionsObject) GetInt64(s string) (int64, error) {
 	object_, err := o.Get(s)
 	if err != nil {
 		return 0, err
@@ -276,7 +455,7 @@ func (o _object) GetInt64(s string) (int64, error) {
 	}
 }
 
-func (o _object) GetFloat32(s string) (float32, error) {
+func (o optionsObject) GetFloat32(s string) (float32, error) {
 	object_, err := o.Get(s)
 	if err != nil {
 		return 0, err
@@ -302,7 +481,7 @@ func (o _object) GetFloat32(s string) (float32, error) {
 	}
 }
 
-func (o _object) GetFloat64(s string) (float64, error) {
+func (o optionsObject) GetFloat64(s string) (float64, error) {
 	object_, err := o.Get(s)
 	if err != nil {
 		return 0, err
@@ -328,7 +507,7 @@ func (o _object) GetFloat64(s string) (float64, error) {
 	}
 }
 
-func (o _object) GetString(s string) (string, error) {
+func (o optionsObject) GetString(s string) (string, error) {
 	object_, err := o.Get(s)
 	if err != nil {
 		return "", err
@@ -346,61 +525,65 @@ func (o _object) GetString(s string) (string, error) {
 	}
 }
 
-func (o _object) ToYAML() (string, error) {
-	metadata := o.Metadata()
-
-	// remove metadata before marshal
-	delete(o, metadataKey)
-
-	yamlBytes, err := yaml.Marshal(o)
-
-	// restore metadata after marshal
-	o[metadataKey] = metadata
-
-	if err != nil {
-		return "", err
-	}
-	return string(yamlBytes), nil
+func (o optionsObject) Delete(key string) error {
+	return deleteObject(o.keyAccess(), o._object, key)
 }
 
-func (o _object) ToJSON() (string, error) {
-	metadata := o.Metadata()
-
-	// remove metadata before marshal
-	delete(o, metadataKey)
-	bs, err := json.Marshal(o)
-
-	// restore metadata after marshal
-	o[metadataKey] = metadata
+func (o optionsObject) Exist(key string) bool {
+	return existObject(o.keyAccess(), o._object, key)
+}
 
-	if err != nil {
-		return "", err
-	}
-	return string(bs), nil
+func (o optionsObject) Get(key string) (interface{}, error) {
+	return getObject(o.keyAccess(), o._object, key)
 }
 
-func (o _object) Delete(key string) error {
-	return deleteObject(o, key)
+func (o optionsObject) Set(key string, value interface{}) error {
+	return setObject(o.keyAccess(), o._object, key, value)
 }
 
-func (o _object) Exist(key string) bool {
-	return existObject(o, key)
+func (o optionsObject) Rename(key string, newName string) error {
+	renamed, err := renameObject(o.keyAccess(), o._object, key, newName)
+	if err != nil {
+		return err
+	}
+	if renamed == 0 {
+		return fmt.Errorf("can not find key: %v", key)
+	}
+	return nil
 }
 
-func (o _object) Get(key string) (interface{}, error) {
-	return getObject(o, key)
+func (o optionsObject) ExpandKey(key string) ([]string, error) {
+	return expandKey(o.keyAccess(), o._object, key)
 }
 
-func (o _object) Set(key string, value interface{}) error {
-	return setObject(o, key, value)
+func (o optionsObject) UpdateArrays(key string, update func([]interface{}) ([]interface{}, error)) error {
+	return updateArrays(o.keyAccess(), o._object, key, update)
 }
 
-func getObject(objects map[interface{}]interface{}, fullKey string) (interface{}, error) {
+func getObject(a *access, objects map[interface{}]interface{}, fullKey string) (interface{}, error) {
 	key, restKey, err := ParseNextSegment(fullKey)
 	if err != nil {
 		return nil, err
 	}
 
+	if key.wildcard || key.recursive {
+		keys, err := expandKey(a, objects, fullKey)
+		if err != nil {
+			return nil, err
+		}
+		result := []interface{}{}
+		for _, key := range keys {
+			v, err := getObject(a, objects, key)
+			if err != nil {
+				return nil, err
+			}
+			if v != nil {
+				result = append(result, v)
+			}
+		}
+		return result, nil
+	}
+
 	if restKey == "" {
 		if key.isArray {
 			arrayObject, ok := objects[key.key]
@@ -412,7 +595,7 @@ func getObject(objects map[interface{}]interface{}, fullKey string) (interface{}
 				return nil, fmt.Errorf("ojbect is not array: %v", arrayObject)
 			}
 
-			return getElement(array, key.index)
+			return getElement(a, array, key.index)
 		} else {
 			return objects[key.key], nil
 		}
@@ -427,24 +610,28 @@ func getObject(objects map[interface{}]interface{}, fullKey string) (interface{}
 				return nil, fmt.Errorf("ojbect is not array: %v", arrayObject)
 			}
 
-			_subObject, err := getElement(array, key.index)
+			_subObject, err := getElement(a, array, key.index)
 			if err != nil {
 				return nil, err
 			}
 
+			if isMultiValuedIndex(key.index) {
+				return getMultiValues(a, _subObject.([]interface{}), restKey)
+			}
+
 			subObject, ok := _subObject.(map[interface{}]interface{})
 			if !ok {
 				return nil, fmt.Errorf("ojbect is not map: %v", _subObject)
 			}
 
-			return getObject(subObject, restKey)
+			return getObject(a, subObject, restKey)
 		} else {
 			if subObject, ok := objects[key.key]; ok {
 				subObjects, ok := subObject.(map[interface{}]interface{})
 				if !ok {
 					return nil, fmt.Errorf("ojbect is not map: %v", subObject)
 				}
-				return getObject(subObjects, restKey)
+				return getObject(a, subObjects, restKey)
 			}
 		}
 	}
@@ -452,12 +639,253 @@ func getObject(objects map[interface{}]interface{}, fullKey string) (interface{}
 	return nil, nil
 }
 
-func existObject(objects map[interface{}]interface{}, fullKey string) bool {
+// getMultiValues get the value of restKey from each element selected by "[*]" or filter index,
+// missing values are skipped, and values are flattened if restKey is multi-valued too.
+func getMultiValues(a *access, elements []interface{}, restKey string) ([]interface{}, error) {
+	multiValued := IsMultiValuedKey(restKey)
+	result := []interface{}{}
+	for _, element := range elements {
+		subObject, ok := element.(map[interface{}]interface{})
+		if !ok {
+			return nil, fmt.Errorf("ojbect is not map: %v", element)
+		}
+		v, err := getObject(a, subObject, restKey)
+		if err != nil {
+			return nil, err
+		}
+		if v == nil {
+			continue
+		}
+		if values, ok := v.([]interface{}); ok && multiValued {
+			result = append(result, values...)
+		} else {
+			result = append(result, v)
+		}
+	}
+	return result, nil
+}
+
+// IsMultiValuedKey returns true if key selects multiple values by "[*]", filter index, "*" or recursive descent,
+// the value of multi-valued key is a list of all matched values.
+func IsMultiValuedKey(key string) bool {
+	for key != "" {
+		segment, rest, err := ParseNextSegment(key)
+		if err != nil {
+			return false
+		}
+		if segment.wildcard || segment.recursive || (segment.isArray && isMultiValuedIndex(segment.index)) {
+			return true
+		}
+		key = rest
+	}
+	return false
+}
+
+func isMultiValuedIndex(index YamlIndex) bool {
+	return index.indexType == IndexLoop || index.indexType == IndexFilter || index.indexType == IndexRange
+}
+
+// expandKey expand multi-valued key to the keys of all matched values, like: a[*].b => a[0].b, a[1].b
+// other keys are returned as they are.
+func expandKey(a *access, objects map[interface{}]interface{}, fullKey string) ([]string, error) {
+	return expandKeyWithOption(a, objects, fullKey, false)
+}
+
+// expandKeyWithOption expand key like expandKey, if existingOnly is true, only keys of existing values are returned,
+// it is used by recursive descent which can not create values at any depth.
+func expandKeyWithOption(a *access, objects map[interface{}]interface{}, fullKey string, existingOnly bool) ([]string, error) {
+	if !IsMultiValuedKey(fullKey) {
+		if !IsValidKey(fullKey) {
+			return nil, fmt.Errorf("invalid key: %v", fullKey)
+		}
+		if existingOnly && !existObject(a, objects, fullKey) {
+			return nil, nil
+		}
+		return []string{fullKey}, nil
+	}
+
+	key, restKey, err := ParseNextSegment(fullKey)
+	if err != nil {
+		return nil, err
+	}
+	if key.recursive {
+		return expandRecursiveKey(a, objects, restKey)
+	}
+
+	head := fullKey
+	if restKey != "" {
+		// rest key may follow without '.', like: a["b"]
+		head = strings.TrimSuffix(fullKey[:len(fullKey)-len(restKey)], ".")
+	}
+
+	var keys []string
+	for _, name := range segmentNames(objects, key) {
+		// replace '*' with the name of map key, the index part is kept
+		nameHead := head
+		if key.wildcard {
+			nameHead = wrapKeySegment(name) + strings.TrimPrefix(head, wildcardKey)
+		}
+		subKeys, err := expandNamedSegment(a, objects, key, name, nameHead, restKey, existingOnly)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, subKeys...)
+	}
+	return keys, nil
+}
+
+// segmentNames returns the names of map keys matched by segment, which are all keys of objects for wildcard,
+// internal keys are skipped.
+func segmentNames(objects map[interface{}]interface{}, segment KeySegment) []string {
+	if !segment.wildcard {
+		return []string{segment.key}
+	}
+	var names []string
+	for key := range objects {
+		name, ok := key.(string)
+		if !ok || name == metadataKey || name == SelfKey {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func expandNamedSegment(a *access, objects map[interface{}]interface{}, key KeySegment, name string, head string, restKey string,
+	existingOnly bool) ([]string, error) {
+	var elements []interface{}
+	var heads []string
+	if key.isArray {
+		array, ok := objects[name].([]interface{})
+		if !ok {
+			return nil, nil
+		}
+		if isMultiValuedIndex(key.index) {
+			for i, element := range array {
+				if key.index.indexType == IndexFilter {
+					matched, err := matchFilter(a, element, key.index)
+					if err != nil {
+						return nil, err
+					}
+					if !matched {
+						continue
+					}
+				}
+				if key.index.indexType == IndexRange && !inRange(array, key.index, i) {
+					continue
+				}
+				elements = append(elements, element)
+				heads = append(heads, fmt.Sprintf("%v[%d]", wrapKeySegment(name), i))
+			}
+		} else {
+			element, err := getElement(a, array, key.index)
+			if err != nil || element == nil {
+				if existingOnly {
+					return nil, nil
+				}
+				return nil, err
+			}
+			elements, heads = []interface{}{element}, []string{head}
+		}
+	} else if element, ok := objects[name]; ok || (restKey == "" && !existingOnly) {
+		// the last segment is kept even if it is missing, like keys which are not multi-valued
+		elements, heads = []interface{}{element}, []string{head}
+	}
+
+	var keys []string
+	for i, element := range elements {
+		if restKey == "" {
+			keys = append(keys, heads[i])
+			continue
+		}
+		subObject, ok := element.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		subKeys, err := expandKeyWithOption(a, subObject, restKey, existingOnly)
+		if err != nil {
+			return nil, err
+		}
+		for _, subKey := range subKeys {
+			keys = append(keys, heads[i]+"."+subKey)
+		}
+	}
+	return keys, nil
+}
+
+// expandRecursiveKey expand restKey at any depth of objects, including objects itself and maps in arrays.
+func expandRecursiveKey(a *access, objects map[interface{}]interface{}, restKey string) ([]string, error) {
+	keys, err := expandKeyWithOption(a, objects, restKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range segmentNames(objects, KeySegment{wildcard: true}) {
+		var subObjects []map[interface{}]interface{}
+		var heads []string
+		switch child := objects[name].(type) {
+		case map[interface{}]interface{}:
+			subObjects, heads = append(subObjects, child), append(heads, wrapKeySegment(name))
+		case []interface{}:
+			for i, element := range child {
+				if subObject, ok := element.(map[interface{}]interface{}); ok {
+					subObjects, heads = append(subObjects, subObject), append(heads, fmt.Sprintf("%v[%d]", wrapKeySegment(name), i))
+				}
+			}
+		}
+
+		for i, subObject := range subObjects {
+			subKeys, err := expandRecursiveKey(a, subObject, restKey)
+			if err != nil {
+				return nil, err
+			}
+			for _, subKey := range subKeys {
+				keys = append(keys, heads[i]+"."+subKey)
+			}
+		}
+	}
+	return uniqueKeys(keys), nil
+}
+
+// uniqueKeys remove duplicated keys and keep the order, keys are duplicated by recursive descent like: **.**.a
+func uniqueKeys(keys []string) []string {
+	var result []string
+	for _, key := range keys {
+		if !slices.Contains(result, key) {
+			result = append(result, key)
+		}
+	}
+	return result
+}
+
+// wrapKeySegment wrap key segment with '()' if it contains '.', or quote it if it has other special characters.
+func wrapKeySegment(key string) string {
+	if key == SelfKey || !keySegmentRegex.MatchString(key) {
+		return "[" + strconv.Quote(key) + "]"
+	}
+	if strings.Contains(key, ".") {
+		return "(" + key + ")"
+	}
+	return key
+}
+
+func existObject(a *access, objects map[interface{}]interface{}, fullKey string) bool {
 	key, restKey, err := ParseNextSegment(fullKey)
 	if err != nil {
 		return false
 	}
 
+	if key.wildcard || key.recursive {
+		keys, err := expandKey(a, objects, fullKey)
+		if err != nil {
+			return false
+		}
+		return slices.ContainsFunc(keys, func(key string) bool {
+			return existObject(a, objects, key)
+		})
+	}
+
 	if restKey == "" {
 		if key.isArray {
 			arrayObject, ok := objects[key.key]
@@ -468,7 +896,7 @@ func existObject(objects map[interface{}]interface{}, fullKey string) bool {
 			if !ok {
 				return false
 			}
-			return existElement(array, key.index)
+			return existElement(a, array, key.index)
 		} else {
 			_, ok := objects[key.key]
 			return ok
@@ -484,6 +912,21 @@ func existObject(objects map[interface{}]interface{}, fullKey string) bool {
 				return false
 			}
 
+			// exists if any of the matched elements has the rest key
+			if key.index.indexType == IndexFilter || key.index.indexType == IndexRange {
+				v, err := getElement(a, array, key.index)
+				if err != nil {
+					return false
+				}
+				elements := v.([]interface{})
+				for _, element := range elements {
+					if subObject, ok := element.(map[interface{}]interface{}); ok && existObject(a, subObject, restKey) {
+						return true
+					}
+				}
+				return false
+			}
+
 			_subObject, err := getElementForExist(array, key.index)
 			if err != nil {
 				return false
@@ -494,14 +937,14 @@ func existObject(objects map[interface{}]interface{}, fullKey string) bool {
 				return false
 			}
 
-			return existObject(subObject, restKey)
+			return existObject(a, subObject, restKey)
 		} else {
 			if subObject, ok := objects[key.key]; ok {
 				subObjects, ok := subObject.(map[interface{}]interface{})
 				if !ok {
 					return false
 				}
-				return existObject(subObjects, restKey)
+				return existObject(a, subObjects, restKey)
 			} else {
 				return false
 			}
@@ -512,10 +955,11 @@ func existObject(objects map[interface{}]interface{}, fullKey string) bool {
 func getElementForExist(slice []interface{}, index YamlIndex) (interface{}, error) {
 	switch index.indexType {
 	case IndexNormal:
-		if index.index < 0 || index.index > len(slice)-1 {
+		i := resolveIndex(index.index, len(slice))
+		if i < 0 || i > len(slice)-1 {
 			return nil, fmt.Errorf("get elements for exsit by index error: out of range: %v", index.index)
 		}
-		return slice[index.index], nil
+		return slice[i], nil
 	case IndexMax:
 		if len(slice) == 0 {
 			return nil, nil
@@ -525,17 +969,38 @@ func getElementForExist(slice []interface{}, index YamlIndex) (interface{}, erro
 		fallthrough
 	case IndexLoop:
 		return nil, fmt.Errorf("get elements for exsit by index error: unsupported index type: %v", index.indexType)
+	case IndexSearch:
+		for _, e := range slice {
+			if matched, err := matchSearch(e, index); err == nil && matched {
+				return e, nil
+			}
+		}
+		return nil, fmt.Errorf("get elements for exsit by index error: not found: %v", index.fields)
 	default:
 		return nil, fmt.Errorf("get elements by index error: unkown index type: %v", index.indexType)
 	}
 }
 
-func deleteObject(objects _object, fullKey string) error {
+func deleteObject(a *access, objects _object, fullKey string) error {
 	key, restKey, err := ParseNextSegment(fullKey)
 	if err != nil {
 		return err
 	}
 
+	if key.wildcard || key.recursive {
+		keys, err := expandKey(a, objects, fullKey)
+		if err != nil {
+			return err
+		}
+		// delete in reverse order, so that indexes of arrays and nested keys are still valid
+		for i := len(keys) - 1; i >= 0; i-- {
+			if err := deleteObject(a, objects, keys[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
 	if restKey == "" {
 		if key.isArray {
 			arrayObject, ok := objects[key.key]
@@ -547,7 +1012,7 @@ func deleteObject(objects _object, fullKey string) error {
 				return fmt.Errorf("ojbect is not array: %v", arrayObject)
 			}
 
-			objects[key.key], err = deleteElement(array, key.index)
+			objects[key.key], err = deleteElement(a, array, key.index)
 			if err != nil {
 				return err
 			}
@@ -565,7 +1030,7 @@ func deleteObject(objects _object, fullKey string) error {
 				return fmt.Errorf("ojbect is not array: %v", arrayObject)
 			}
 
-			_subObjects, err := getElementForDelete(array, key.index)
+			_subObjects, err := getElementForDelete(a, array, key.index)
 			if err != nil {
 				return err
 			}
@@ -577,7 +1042,7 @@ func deleteObject(objects _object, fullKey string) error {
 						return fmt.Errorf("ojbect is not map: %v", _subObjects)
 					}
 
-					if err := deleteObject(subObject, restKey); err != nil {
+					if err := deleteObject(a, subObject, restKey); err != nil {
 						return err
 					}
 				}
@@ -588,7 +1053,7 @@ func deleteObject(objects _object, fullKey string) error {
 				if !ok {
 					return fmt.Errorf("ojbect is not map: %v", subObject)
 				}
-				if err := deleteObject(subObjects, restKey); err != nil {
+				if err := deleteObject(a, subObjects, restKey); err != nil {
 					return err
 				}
 			}
@@ -597,9 +1062,218 @@ func deleteObject(objects _object, fullKey string) error {
 	return nil
 }
 
-func setObject(objects map[interface{}]interface{}, fullKey string, value interface{}) error {
-	if _, ok := value.(_object); ok {
-		value = value.(_object).ToMap()
+// renameObject rename the last segment of fullKey to newName, returns the count of renamed keys.
+func renameObject(a *access, objects map[interface{}]interface{}, fullKey string, newName string) (int, error) {
+	key, restKey, err := ParseNextSegment(fullKey)
+	if err != nil {
+		return 0, err
+	}
+
+	if key.wildcard || key.recursive {
+		keys, err := expandKey(a, objects, fullKey)
+		if err != nil {
+			return 0, err
+		}
+		renamed := 0
+		for i := len(keys) - 1; i >= 0; i-- {
+			n, err := renameObject(a, objects, keys[i], newName)
+			if err != nil {
+				return renamed, err
+			}
+			renamed += n
+		}
+		return renamed, nil
+	}
+
+	if restKey == "" {
+		if key.isArray {
+			return 0, fmt.Errorf("can not rename array element: %v", fullKey)
+		}
+		value, ok := objects[key.key]
+		if !ok {
+			return 0, nil
+		}
+		if key.key == newName {
+			return 1, nil
+		}
+		if _, ok := objects[newName]; ok {
+			return 0, fmt.Errorf("can not rename %v: key already exists: %v", fullKey, newName)
+		}
+		objects[newName] = value
+		delete(objects, key.key)
+		return 1, nil
+	}
+
+	// has rest key
+	var subObjects []interface{}
+	if key.isArray {
+		arrayObject, ok := objects[key.key]
+		if !ok {
+			return 0, nil
+		}
+		array, ok := arrayObject.([]interface{})
+		if !ok {
+			return 0, fmt.Errorf("ojbect is not array: %v", arrayObject)
+		}
+		if subObjects, err = getElementForDelete(a, array, key.index); err != nil {
+			return 0, err
+		}
+	} else if subObject, ok := objects[key.key]; ok {
+		subObjects = []interface{}{subObject}
+	}
+
+	renamed := 0
+	for _, _subObject := range subObjects {
+		if _subObject == nil {
+			continue
+		}
+		subObject, ok := _subObject.(map[interface{}]interface{})
+		if !ok {
+			return renamed, fmt.Errorf("ojbect is not map: %v", _subObject)
+		}
+		n, err := renameObject(a, subObject, restKey, newName)
+		if err != nil {
+			return renamed, err
+		}
+		renamed += n
+	}
+	return renamed, nil
+}
+
+func updateArrays(a *access, objects map[interface{}]interface{}, fullKey string, update func([]interface{}) ([]interface{}, error)) error {
+	key, restKey, err := ParseNextSegment(fullKey)
+	if err != nil {
+		return err
+	}
+
+	if key.wildcard || key.recursive {
+		keys, err := expandKey(a, objects, fullKey)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err := updateArrays(a, objects, key, update); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if restKey == "" {
+		if key.isArray {
+			return fmt.Errorf("can not update array element as array: %v", fullKey)
+		}
+		var array []interface{}
+		if arrayObject, ok := objects[key.key]; ok {
+			if array, ok = arrayObject.([]interface{}); !ok {
+				return fmt.Errorf("ojbect is not array: %v", arrayObject)
+			}
+		}
+		result, err := update(array)
+		if err != nil {
+			return err
+		}
+		_, ok := objects[key.key]
+		if !ok && len(result) > 0 {
+			a.addKey(objects, key.key)
+		}
+		if ok || len(result) > 0 {
+			objects[key.key] = result
+		}
+		return nil
+	}
+
+	// has rest key
+	var subObjects []interface{}
+	if key.isArray {
+		arrayObject, ok := objects[key.key]
+		if !ok {
+			return nil
+		}
+		array, ok := arrayObject.([]interface{})
+		if !ok {
+			return fmt.Errorf("ojbect is not array: %v", arrayObject)
+		}
+		if subObjects, err = getElementForDelete(a, array, key.index); err != nil {
+			return err
+		}
+	} else if subObject, ok := objects[key.key]; ok {
+		subObjects = []interface{}{subObject}
+	}
+
+	for _, _subObject := range subObjects {
+		if _subObject == nil {
+			continue
+		}
+		subObject, ok := _subObject.(map[interface{}]interface{})
+		if !ok {
+			return fmt.Errorf("ojbect is not map: %v", _subObject)
+		}
+		if err := updateArrays(a, subObject, restKey, update); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SelfKey refers to the element itself in the conditions of elements, like: REMOVE_WHERE(args, @ == "--debug")
+const SelfKey = "@"
+
+// ElementObject wrap an element of array as StructuredObject, keys of map element can be accessed directly,
+// and the element itself can be accessed by SelfKey. The element shares metadata (like scripts variables)
+// with parent if parent is not nil, and the element itself is not changed.
+func ElementObject(parent StructuredObject, element interface{}) StructuredObject {
+	if view, ok := parent.(optionsObject); ok {
+		return WithOptions(elementObject(parent.Metadata(), element), view.options)
+	}
+	if parent != nil {
+		return elementObject(parent.Metadata(), element)
+	}
+	return elementObject(nil, element)
+}
+
+// elementObject wrap element like ElementObject, the element has its own metadata if metadata is nil.
+func elementObject(metadata Metadata, element interface{}) StructuredObject {
+	o := _object{}
+	if m, ok := element.(map[interface{}]interface{}); ok {
+		for key, value := range m {
+			o[key] = value
+		}
+	}
+	o[SelfKey] = element
+	if metadata != nil {
+		o[metadataKey] = metadata
+	} else {
+		o[metadataKey] = _metadata{}
+	}
+	return o
+}
+
+// DeepCopy copy maps and arrays recursively, so that the copied value does not share anything with the original one.
+func DeepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case _object:
+		return DeepCopy(map[interface{}]interface{}(v))
+	case map[interface{}]interface{}:
+		m := make(map[interface{}]interface{}, len(v))
+		for key, value := range v {
+			m[key] = DeepCopy(value)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for i, value := range v {
+			a[i] = DeepCopy(value)
+		}
+		return a
+	default:
+		return value
+	}
+}
+
+func setObject(a *access, objects map[interface{}]interface{}, fullKey string, value interface{}) error {
+	if object, ok := value.(StructuredObject); ok {
+		value = object.ToMap()
 	}
 
 	key, restKey, err := ParseNextSegment(fullKey)
@@ -607,6 +1281,23 @@ func setObject(objects map[interface{}]interface{}, fullKey string, value interf
 		return err
 	}
 
+	if key.wildcard || key.recursive {
+		keys, err := expandKey(a, objects, fullKey)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err := setObject(a, objects, key, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if _, ok := objects[key.key]; !ok {
+		a.addKey(objects, key.key)
+	}
+
 	if restKey == "" {
 		if key.isArray {
 			var array []interface{}
@@ -621,7 +1312,7 @@ func setObject(objects map[interface{}]interface{}, fullKey string, value interf
 				objects[key.key] = array
 			}
 
-			array, err := setElements(array, key.index, value)
+			array, err := setElements(a, array, key.index, value)
 			if err != nil {
 				return err
 			}
@@ -643,7 +1334,7 @@ func setObject(objects map[interface{}]interface{}, fullKey string, value interf
 				objects[key.key] = array
 			}
 
-			subObjects_, array, err := getElementForSet(array, key.index)
+			subObjects_, array, err := getElementForSet(a, array, key.index)
 			if err != nil {
 				return err
 			}
@@ -657,7 +1348,7 @@ func setObject(objects map[interface{}]interface{}, fullKey string, value interf
 					if !ok {
 						return fmt.Errorf("ojbect is not map: %v", subObject)
 					}
-					if err := setObject(subObject, restKey, value); err != nil {
+					if err := setObject(a, subObject, restKey, value); err != nil {
 						return err
 					}
 				}
@@ -676,7 +1367,7 @@ func setObject(objects map[interface{}]interface{}, fullKey string, value interf
 				objects[key.key] = subObjects
 			}
 
-			if err := setObject(subObjects, restKey, value); err != nil {
+			if err := setObject(a, subObjects, restKey, value); err != nil {
 				return err
 			}
 		}
@@ -684,13 +1375,14 @@ func setObject(objects map[interface{}]interface{}, fullKey string, value interf
 	return nil
 }
 
-func getElement(slice []interface{}, index YamlIndex) (interface{}, error) {
+func getElement(a *access, slice []interface{}, index YamlIndex) (interface{}, error) {
 	switch index.indexType {
 	case IndexNormal:
-		if index.index < 0 || index.index > len(slice)-1 {
+		i := resolveIndex(index.index, len(slice))
+		if i < 0 || i > len(slice)-1 {
 			return nil, fmt.Errorf("get elements by index error: out of range: %v", index.index)
 		}
-		return slice[index.index], nil
+		return slice[i], nil
 	case IndexMax:
 		if len(slice) == 0 {
 			return nil, nil
@@ -702,24 +1394,37 @@ func getElement(slice []interface{}, index YamlIndex) (interface{}, error) {
 		return slice, nil
 	case IndexSearch:
 		for _, e := range slice {
-			e_, ok := e.(map[interface{}]interface{})
-			if !ok {
+			matched, err := matchSearch(e, index)
+			if err != nil {
 				return nil, fmt.Errorf("get element error: %v, index: %v", slice, index)
 			}
-			if lenientEqual(e_[index.key], index.value) {
+			if matched {
 				return e, nil
 			}
 		}
 		return nil, nil
+	case IndexFilter:
+		elements, err := filterElements(a, slice, index)
+		if err != nil {
+			return nil, err
+		}
+		if elements == nil {
+			elements = []interface{}{}
+		}
+		return elements, nil
+	case IndexRange:
+		start, end := rangeBounds(slice, index)
+		return append([]interface{}{}, slice[start:end]...), nil
 	default:
 		return nil, fmt.Errorf("get elements by index error: unkown index type: %v", index.indexType)
 	}
 }
 
-func existElement(slice []interface{}, index YamlIndex) bool {
+func existElement(a *access, slice []interface{}, index YamlIndex) bool {
 	switch index.indexType {
 	case IndexNormal:
-		if index.index < 0 || index.index > len(slice)-1 {
+		i := resolveIndex(index.index, len(slice))
+		if i < 0 || i > len(slice)-1 {
 			return false
 		}
 		return true
@@ -732,18 +1437,30 @@ func existElement(slice []interface{}, index YamlIndex) bool {
 		return false
 	case IndexLoop:
 		return true
+	case IndexSearch:
+		return slices.ContainsFunc(slice, func(e interface{}) bool {
+			matched, err := matchSearch(e, index)
+			return err == nil && matched
+		})
+	case IndexFilter:
+		elements, err := filterElements(a, slice, index)
+		return err == nil && len(elements) > 0
+	case IndexRange:
+		start, end := rangeBounds(slice, index)
+		return start < end
 	default:
 		return false
 	}
 }
 
-func getElementForDelete(slice []interface{}, index YamlIndex) ([]interface{}, error) {
+func getElementForDelete(a *access, slice []interface{}, index YamlIndex) ([]interface{}, error) {
 	switch index.indexType {
 	case IndexNormal:
-		if index.index < 0 || index.index > len(slice)-1 {
+		i := resolveIndex(index.index, len(slice))
+		if i < 0 || i > len(slice)-1 {
 			return nil, fmt.Errorf("get elements by index for delete error: out of range: %v", index.index)
 		}
-		return []interface{}{slice[index.index]}, nil
+		return []interface{}{slice[i]}, nil
 	case IndexMax:
 		if len(slice) == 0 {
 			return nil, nil
@@ -754,23 +1471,28 @@ func getElementForDelete(slice []interface{}, index YamlIndex) ([]interface{}, e
 	case IndexSearch:
 		var result []interface{}
 		for _, e := range slice {
-			e_, ok := e.(map[interface{}]interface{})
-			if !ok {
+			matched, err := matchSearch(e, index)
+			if err != nil {
 				return nil, fmt.Errorf("delete elements by index error: %v, index: %v", slice, index)
 			}
-			if lenientEqual(e_[index.key], index.value) {
+			if matched {
 				result = append(result, e)
 			}
 		}
 		return result, nil
 	case IndexLoop:
 		return slice, nil
+	case IndexFilter:
+		return filterElements(a, slice, index)
+	case IndexRange:
+		start, end := rangeBounds(slice, index)
+		return slice[start:end], nil
 	default:
 		return nil, fmt.Errorf("get elements by index for delete error: unkown index type: %v", index.indexType)
 	}
 }
 
-func deleteElement(slice []interface{}, index YamlIndex) ([]interface{}, error) {
+func deleteElement(a *access, slice []interface{}, index YamlIndex) ([]interface{}, error) {
 	var result []interface{}
 
 	// delete all elements
@@ -781,7 +1503,7 @@ func deleteElement(slice []interface{}, index YamlIndex) ([]interface{}, error)
 	for i, e := range slice {
 		switch index.indexType {
 		case IndexNormal:
-			if i != index.index {
+			if i != resolveIndex(index.index, len(slice)) {
 				result = append(result, e)
 			}
 		case IndexMax: // delete last elements
@@ -789,11 +1511,23 @@ func deleteElement(slice []interface{}, index YamlIndex) ([]interface{}, error)
 				result = append(result, e)
 			}
 		case IndexSearch:
-			e_, ok := e.(map[interface{}]interface{})
-			if !ok {
+			matched, err := matchSearch(e, index)
+			if err != nil {
 				return nil, fmt.Errorf("delete elements by index error: %v, index: %v", slice, index)
 			}
-			if !lenientEqual(e_[index.key], index.value) {
+			if !matched {
+				result = append(result, e)
+			}
+		case IndexRange:
+			if !inRange(slice, index, i) {
+				result = append(result, e)
+			}
+		case IndexFilter:
+			matched, err := matchFilter(a, e, index)
+			if err != nil {
+				return nil, err
+			}
+			if !matched {
 				result = append(result, e)
 			}
 		case IndexAppend: // append(++) operation is not allowed in deletion
@@ -805,16 +1539,17 @@ func deleteElement(slice []interface{}, index YamlIndex) ([]interface{}, error)
 	return result, nil
 }
 
-func getElementForSet(slice []interface{}, index YamlIndex) ([]interface{}, []interface{}, error) {
+func getElementForSet(a *access, slice []interface{}, index YamlIndex) ([]interface{}, []interface{}, error) {
 	switch index.indexType {
 	case IndexNormal:
-		if index.index < 0 || index.index > len(slice) {
+		i := resolveIndex(index.index, len(slice))
+		if i < 0 || i > len(slice) {
 			return nil, slice, fmt.Errorf("get elements by index for set error: out of range: %v, index: %v", slice, index.index)
-		} else if index.index == len(slice) {
+		} else if i == len(slice) {
 			e := map[interface{}]interface{}{}
 			return []interface{}{e}, append(slice, e), nil
 		} else {
-			return []interface{}{slice[index.index]}, slice, nil
+			return []interface{}{slice[i]}, slice, nil
 		}
 	case IndexMax:
 		if len(slice) == 0 {
@@ -828,29 +1563,36 @@ func getElementForSet(slice []interface{}, index YamlIndex) ([]interface{}, []in
 		return slice, slice, nil
 	case IndexSearch:
 		for _, e := range slice {
-			e_, ok := e.(map[interface{}]interface{})
-			if !ok {
+			matched, err := matchSearch(e, index)
+			if err != nil {
 				return nil, slice, fmt.Errorf("get elements by index for set error: %v, index: %v", slice, index)
 			}
-			if lenientEqual(e_[index.key], index.value) {
+			if matched {
 				return []interface{}{e}, slice, nil
 			}
 		}
 		return nil, slice, nil
+	case IndexFilter:
+		elements, err := filterElements(a, slice, index)
+		return elements, slice, err
+	case IndexRange:
+		start, end := rangeBounds(slice, index)
+		return slice[start:end], slice, nil
 	default:
 		return nil, slice, fmt.Errorf("get elements by index for set error: unkown index type: %v", index.indexType)
 	}
 }
 
-func setElements(slice []interface{}, index YamlIndex, value interface{}) ([]interface{}, error) {
+func setElements(a *access, slice []interface{}, index YamlIndex, value interface{}) ([]interface{}, error) {
 	switch index.indexType {
 	case IndexNormal:
-		if index.index > len(slice) || index.index < 0 {
+		i := resolveIndex(index.index, len(slice))
+		if i > len(slice) || i < 0 {
 			return slice, fmt.Errorf("set elements by index error: out of range: %v, index: %v", slice, index.index)
-		} else if index.index == len(slice) {
+		} else if i == len(slice) {
 			return append(slice, value), nil
 		} else {
-			slice[index.index] = value
+			slice[i] = value
 			return slice, nil
 		}
 	case IndexMax:
@@ -864,17 +1606,37 @@ func setElements(slice []interface{}, index YamlIndex, value interface{}) ([]int
 	case IndexSearch:
 		var results []interface{}
 		for _, e := range slice {
-			e_, ok := e.(map[interface{}]interface{})
-			if !ok {
-				return slice, fmt.Errorf("set elements by index error: element is not map: %v", e)
+			matched, err := matchSearch(e, index)
+			if err != nil {
+				return slice, fmt.Errorf("set elements by index error: %v", err)
+			}
+			if matched {
+				results = append(results, value)
+			} else {
+				results = append(results, e)
+			}
+		}
+		return results, nil
+	case IndexFilter:
+		var results []interface{}
+		for _, e := range slice {
+			matched, err := matchFilter(a, e, index)
+			if err != nil {
+				return slice, err
 			}
-			if lenientEqual(e_[index.key], index.value) {
+			if matched {
 				results = append(results, value)
 			} else {
 				results = append(results, e)
 			}
 		}
 		return results, nil
+	case IndexRange:
+		start, end := rangeBounds(slice, index)
+		for i := start; i < end; i++ {
+			slice[i] = value
+		}
+		return slice, nil
 	default:
 		return slice, fmt.Errorf("set elements by index error: unkown index type: %v", index)
 	}
@@ -884,6 +1646,37 @@ func setElements(slice []interface{}, index YamlIndex, value interface{}) ([]int
 // Not support array like: a[1][2]
 func ParseNextSegment(key string) (KeySegment, string, error) {
 
+	// recursive descent: "a..b" is the same as "a.**.b", the previous segment leaves ".b" here
+	if strings.HasPrefix(key, ".") {
+		restKey := strings.TrimPrefix(strings.TrimPrefix(key, "."), ".")
+		if restKey == "" {
+			return KeySegment{}, "", fmt.Errorf("parse next key error: %v", key)
+		}
+		return KeySegment{key: recursiveKey, recursive: true}, restKey, nil
+	}
+
+	// need parse quotes, like: "a.b", 'a.b' or ["a.b"]
+	if isQuotedSegment(key) {
+		bracketed := strings.HasPrefix(key, "[")
+		quoted := key
+		if bracketed {
+			quoted = key[1:]
+		}
+		head, length, err := unquoteKeySegment(quoted)
+		if err != nil {
+			return KeySegment{}, "", fmt.Errorf("parse next key error: %v, %v", key, err)
+		}
+
+		tail := quoted[length:]
+		if bracketed {
+			if !strings.HasPrefix(tail, "]") {
+				return KeySegment{}, "", fmt.Errorf("parse next key error: %v", key)
+			}
+			tail = tail[1:]
+		}
+		return parseSegmentTail(KeySegment{key: head}, tail, key)
+	}
+
 	// need parse '()'
 	if strings.HasPrefix(key, "(") {
 		_, rightIndex := common.FindFirstParenthesesPair(key)
@@ -896,130 +1689,149 @@ func ParseNextSegment(key string) (KeySegment, string, error) {
 		if !isValidateKeySegment(head) {
 			return KeySegment{}, "", fmt.Errorf("parse next key error: %v", key)
 		}
+		return parseSegmentTail(KeySegment{key: head}, key[rightIndex+1:], key)
+	}
 
-		tail := key[rightIndex+1:]
-		if tail == "" {
-			return KeySegment{key: head}, "", nil
-		}
-
-		if strings.HasPrefix(tail, ".") {
-			restKey := strings.TrimPrefix(tail, ".")
-			if restKey == "" {
-				return KeySegment{}, "", fmt.Errorf("parse next key error: %v", key)
-			}
-			return KeySegment{key: head}, restKey, nil
-		} else if strings.HasPrefix(tail, "[") {
-			_, rightIndexOfTail := common.FindFirstBracketsPair(tail)
-			if rightIndexOfTail == -1 {
-				return KeySegment{}, "", fmt.Errorf("parse next key error: %v", key)
-			}
-			headOfTail := tail[:rightIndexOfTail+1]
-			headOfTail = common.UnwrapBracketsIfNeeded(headOfTail)
-			tailOfTail := tail[rightIndexOfTail+1:]
-
-			if headOfTail == "" {
-				return KeySegment{}, "", fmt.Errorf("parse next key error: %v", key)
-			}
-			index, err := parseIndex(headOfTail)
-			if err != nil {
-				return KeySegment{}, "", fmt.Errorf("parse next key error: %v", key)
-			}
+	// parse by separators: '.' and '[', '[]' has higher priority than '.'
+	head, tail := key, ""
+	if i := strings.IndexAny(key, ".["); i != -1 {
+		head, tail = key[:i], key[i:]
+	}
 
-			if tailOfTail == "" {
-				return KeySegment{key: head, isArray: true, index: index}, "", nil
-			}
+	switch {
+	case head == wildcardKey:
+		return parseSegmentTail(KeySegment{key: head, wildcard: true}, tail, key)
+	case head == recursiveKey:
+		return parseSegmentTail(KeySegment{key: head, recursive: true}, tail, key)
+	case isValidateKeySegment(head):
+		return parseSegmentTail(KeySegment{key: head}, tail, key)
+	default:
+		return KeySegment{}, "", fmt.Errorf("parse next key error: %v", key)
+	}
+}
 
-			if !strings.HasPrefix(tailOfTail, ".") || tailOfTail == "." {
-				return KeySegment{}, "", fmt.Errorf("parse next key error: %v", key)
-			}
-			return KeySegment{key: head, isArray: true, index: index}, strings.TrimPrefix(tailOfTail, "."), nil
-		} else {
+// parseSegmentTail parse the optional index and the separator after the key part of segment, the tail may be:
+// empty, ".rest", "[index]", "[index].rest", "[\"quoted\"]...", "[index][\"quoted\"]..."
+func parseSegmentTail(segment KeySegment, tail string, key string) (KeySegment, string, error) {
+	if strings.HasPrefix(tail, "[") && !isQuotedSegment(tail) {
+		rightIndex := findBracketsEnd(tail)
+		if rightIndex == -1 {
 			return KeySegment{}, "", fmt.Errorf("parse next key error: %v", key)
 		}
-	} else
-	// do not need to parse '()'
-	{
-		firstLeftBracketIndex := strings.Index(key, "[")
-		firstDotIndex := strings.Index(key, ".")
-		// '[]' has higher priority than '.' , parse '[]'
-		if firstLeftBracketIndex != -1 && firstLeftBracketIndex < firstDotIndex {
-			leftBracketsIndex, rightBracketsIndex := common.FindFirstBracketsPair(key)
-			if leftBracketsIndex == -1 || rightBracketsIndex == -1 {
-				return KeySegment{}, "", fmt.Errorf("parse next key error: %v", key)
-			}
 
-			head := key[:rightBracketsIndex+1]
-			tail := key[rightBracketsIndex+1:]
+		indexPart := strings.TrimSpace(tail[1:rightIndex])
+		if indexPart == "" || segment.recursive {
+			return KeySegment{}, "", fmt.Errorf("parse next key error: %v", key)
+		}
+		index, err := parseIndex(indexPart)
+		if err != nil {
+			return KeySegment{}, "", fmt.Errorf("parse next key error: %v", key)
+		}
+		segment.isArray, segment.index = true, index
+		tail = tail[rightIndex+1:]
+	}
 
-			keyPartOfHead := head[:leftBracketsIndex]
-			if !isValidateKeySegment(keyPartOfHead) {
-				return KeySegment{}, "", fmt.Errorf("parse next key error: %v", key)
-			}
+	switch {
+	case tail == "":
+		if segment.recursive {
+			return KeySegment{}, "", fmt.Errorf("parse next key error: recursive descent must be followed by key: %v", key)
+		}
+		return segment, "", nil
+	case strings.HasPrefix(tail, "["):
+		// quoted segment follows without '.', like: annotations["prometheus.io/scrape"]
+		return segment, tail, nil
+	case strings.HasPrefix(tail, ".") && tail != ".":
+		return segment, tail[1:], nil
+	default:
+		return KeySegment{}, "", fmt.Errorf("parse next key error: %v", key)
+	}
+}
 
-			indexPartOfHead := head[leftBracketsIndex:]
-			indexPartOfHead = common.UnwrapBracketsIfNeeded(indexPartOfHead)
-			index, err := parseIndex(indexPartOfHead)
-			if err != nil {
-				return KeySegment{}, "", fmt.Errorf("parse next key error: %v", key)
-			}
+// isQuotedSegment returns true if key starts with a quoted segment, like: "a.b", 'a.b', ["a.b"] or ['a.b']
+func isQuotedSegment(key string) bool {
+	key = strings.TrimPrefix(key, "[")
+	return strings.HasPrefix(key, "\"") || strings.HasPrefix(key, "'")
+}
 
-			if tail == "" {
-				return KeySegment{isArray: true, key: keyPartOfHead, index: index}, "", nil
-			}
+// unquoteKeySegment unquote the quoted segment at the start of key, escapes like '\"', '\\' and '\n' are supported,
+// returns the unquoted segment and the length of the quoted part.
+func unquoteKeySegment(key string) (string, int, error) {
+	quote := key[0]
+	result := strings.Builder{}
+	rest := key[1:]
+	for {
+		if rest == "" {
+			return "", 0, fmt.Errorf("unterminated quoted segment")
+		}
+		if rest[0] == quote {
+			return result.String(), len(key) - len(rest) + 1, nil
+		}
+		r, multibyte, tail, err := strconv.UnquoteChar(rest, quote)
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid escape in quoted segment")
+		}
+		if multibyte {
+			result.WriteRune(r)
+		} else {
+			result.WriteByte(byte(r))
+		}
+		rest = tail
+	}
+}
 
-			if !strings.HasPrefix(tail, ".") || tail == "." {
-				return KeySegment{}, "", fmt.Errorf("parse next key error: %v", key)
+// splitOutsideQuotes split s by sep, separators in quoted content are ignored.
+func splitOutsideQuotes(s string, sep byte) []string {
+	var result []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case sep:
+			result = append(result, s[start:i])
+			start = i + 1
+		case '"', '\'':
+			quote := s[i]
+			for i++; i < len(s) && s[i] != quote; i++ {
+				if s[i] == '\\' {
+					i++
+				}
 			}
+		}
+	}
+	return append(result, s[start:])
+}
 
-			tail = strings.TrimPrefix(tail, ".")
-			return KeySegment{isArray: true, key: keyPartOfHead, index: index}, tail, nil
-		} else
-		// parse by separator: '.'
-		{
-			headAndTailParts := strings.SplitN(key, ".", 2)
-			if len(headAndTailParts) == 2 && headAndTailParts[1] == "" {
-				return KeySegment{}, "", fmt.Errorf("parse next key error: %v", key)
+// findBracketsEnd returns the index of ']' which closes the '[' at the start of key, quoted content is skipped,
+// returns -1 if it is not found.
+func findBracketsEnd(key string) int {
+	depth := 0
+	for i := 0; i < len(key); i++ {
+		switch key[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return i
 			}
-
-			head := headAndTailParts[0]
-			leftBracketsIndex, rightBracketsIndex := common.FindFirstBracketsPair(head)
-			if leftBracketsIndex != -1 && rightBracketsIndex != -1 {
-				if rightBracketsIndex != len(head)-1 {
-					return KeySegment{}, "", fmt.Errorf("parse next key error: %v", key)
-				}
-				keyPartOfHead := head[:leftBracketsIndex]
-				if !isValidateKeySegment(keyPartOfHead) {
-					return KeySegment{}, "", fmt.Errorf("parse next key error: %v", key)
-				}
-
-				indexPartOfHead := head[leftBracketsIndex:]
-				indexPartOfHead = common.UnwrapBracketsIfNeeded(indexPartOfHead)
-
-				index, err := parseIndex(indexPartOfHead)
-				if err != nil {
-					return KeySegment{}, "", fmt.Errorf("parse next key error: %v", key)
-				}
-				if len(headAndTailParts) == 1 {
-					return KeySegment{key: keyPartOfHead, isArray: true, index: index}, "", nil
-				}
-				return KeySegment{key: keyPartOfHead, isArray: true, index: index}, headAndTailParts[1], nil
-			} else {
-				if !isValidateKeySegment(head) {
-					return KeySegment{}, "", fmt.Errorf("parse next key error: %v", key)
-				}
-				if len(headAndTailParts) == 1 {
-					return KeySegment{key: head}, "", nil
+		case '"', '\'':
+			quote := key[i]
+			for i++; i < len(key) && key[i] != quote; i++ {
+				if key[i] == '\\' {
+					i++
 				}
-				return KeySegment{key: head}, headAndTailParts[1], nil
+			}
+			if i >= len(key) {
+				return -1
 			}
 		}
 	}
+	return -1
 }
 
 var keySegmentRegex = regexp.MustCompile("^[a-zA-Z0-9/_.-]+$")
 
 func isValidateKeySegment(key string) bool {
-	return keySegmentRegex.MatchString(key)
+	return key == SelfKey || keySegmentRegex.MatchString(key)
 }
 
 func IsValidKey(key string) bool {
@@ -1056,13 +1868,16 @@ func parseIndex(index string) (YamlIndex, error) {
 		}, nil
 	}
 
+	if isFilterIndex(index) {
+		return parseFilterIndex(index)
+	}
+
 	if strings.Contains(index, "=") {
-		strs := strings.SplitN(index, "=", 2)
-		return YamlIndex{
-			indexType: IndexSearch,
-			key:       strs[0],
-			value:     strs[1],
-		}, nil
+		return parseSearchIndex(index)
+	}
+
+	if strings.Contains(index, ":") {
+		return parseRangeIndex(index)
 	}
 
 	numberIndex, err := strconv.Atoi(index)
@@ -1076,12 +1891,102 @@ func parseIndex(index string) (YamlIndex, error) {
 	}, nil
 }
 
+// parseSearchIndex parse index like: name=web,protocol=TCP or name="a, b"
+func parseSearchIndex(index string) (YamlIndex, error) {
+	var fields []searchField
+	for _, field := range splitOutsideQuotes(index, ',') {
+		strs := strings.SplitN(field, "=", 2)
+		if len(strs) != 2 || strs[0] == "" {
+			return YamlIndex{}, fmt.Errorf("invalid search index: %v", index)
+		}
+		value := strs[1]
+		if isQuotedSegment(value) && !strings.HasPrefix(value, "[") {
+			unquoted, length, err := unquoteKeySegment(value)
+			if err != nil || length != len(value) {
+				return YamlIndex{}, fmt.Errorf("invalid search index: %v", index)
+			}
+			value = unquoted
+		}
+		fields = append(fields, searchField{key: strs[0], value: value})
+	}
+	return YamlIndex{
+		indexType: IndexSearch,
+		fields:    fields,
+	}, nil
+}
+
+// parseRangeIndex parse index like: 1:3, :2, -2:
+func parseRangeIndex(index string) (YamlIndex, error) {
+	strs := strings.SplitN(index, ":", 2)
+	result := YamlIndex{indexType: IndexRange}
+	if strs[0] != "" {
+		start, err := strconv.Atoi(strs[0])
+		if err != nil {
+			return YamlIndex{}, fmt.Errorf("invalid range index: %v", index)
+		}
+		result.index = start
+	}
+	if strs[1] != "" {
+		end, err := strconv.Atoi(strs[1])
+		if err != nil {
+			return YamlIndex{}, fmt.Errorf("invalid range index: %v", index)
+		}
+		result.end, result.hasEnd = end, true
+	}
+	return result, nil
+}
+
+// resolveIndex convert negative index to the index from the start of array, the result may still be out of range.
+func resolveIndex(index int, length int) int {
+	if index < 0 {
+		return index + length
+	}
+	return index
+}
+
+// rangeBounds returns the bounds of IndexRange in slice, out of range bounds are clamped like python slices.
+func rangeBounds(slice []interface{}, index YamlIndex) (int, int) {
+	clamp := func(i int) int {
+		return max(0, min(resolveIndex(i, len(slice)), len(slice)))
+	}
+	start, end := clamp(index.index), len(slice)
+	if index.hasEnd {
+		end = clamp(index.end)
+	}
+	return start, max(start, end)
+}
+
+func inRange(slice []interface{}, index YamlIndex, i int) bool {
+	start, end := rangeBounds(slice, index)
+	return i >= start && i < end
+}
+
+func matchSearch(e interface{}, index YamlIndex) (bool, error) {
+	e_, ok := e.(map[interface{}]interface{})
+	if !ok {
+		return false, fmt.Errorf("element is not map: %v", e)
+	}
+	for _, field := range index.fields {
+		if !lenientEqual(e_[field.key], field.value) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
 type KeySegment struct {
 	isArray bool
 	key     string
 	index   YamlIndex
+	// wildcard matches all keys of map, like: metadata.labels.*
+	wildcard bool
+	// recursive matches the rest key at any depth, like: **.namespace or spec..image
+	recursive bool
 }
 
+const wildcardKey = "*"
+const recursiveKey = "**"
+
 type IndexType string
 
 const (
@@ -1090,13 +1995,27 @@ const (
 	IndexAppend = "index-append"
 	IndexSearch = "index-search"
 	IndexLoop   = "index-loop"
+	// IndexFilter selects the elements which match a condition, like: containers[?(HAS_PREFIX(image, "old/"))]
+	IndexFilter = "index-filter"
+	// IndexRange selects the elements in a python style slice, like: containers[1:3], containers[:-1]
+	IndexRange = "index-range"
 )
 
 type YamlIndex struct {
 	indexType IndexType
-	index     int
-	key       string
-	value     string
+	// index is the start of IndexRange, negative index counts from the end of array
+	index  int
+	end    int
+	hasEnd bool
+	fields []searchField
+	value  string
+	filter ElementFilter
+}
+
+// searchField is a key=value pair of IndexSearch, an element is matched only if all fields are matched.
+type searchField struct {
+	key   string
+	value string
 }
 
 func lenientEqual(v interface{}, s string) bool {
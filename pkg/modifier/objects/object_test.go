@@ -1,7 +1,9 @@
 package objects
 
 import (
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -33,6 +35,114 @@ func TestParseNextSegment(t *testing.T) {
 			want1:   "b.c",
 			wantErr: false,
 		},
+		{
+			name:  "TEST_PARSE_WILDCARD",
+			key:   "*.b",
+			want:  KeySegment{key: "*", wildcard: true},
+			want1: "b",
+		},
+		{
+			name:  "TEST_PARSE_WILDCARD_ARRAY",
+			key:   "*[0]",
+			want:  KeySegment{key: "*", wildcard: true, isArray: true, index: YamlIndex{indexType: IndexNormal, index: 0}},
+			want1: "",
+		},
+		{
+			name:  "TEST_PARSE_RECURSIVE",
+			key:   "**.a.b",
+			want:  KeySegment{key: "**", recursive: true},
+			want1: "a.b",
+		},
+		{
+			name:  "TEST_PARSE_RECURSIVE_DOTS",
+			key:   "..a.b",
+			want:  KeySegment{key: "**", recursive: true},
+			want1: "a.b",
+		},
+		{
+			name:    "TEST_PARSE_RECURSIVE_ERROR",
+			key:     "**",
+			wantErr: true,
+		},
+		{
+			name:  "TEST_PARSE_NEGATIVE_INDEX",
+			key:   "a[-2].b",
+			want:  KeySegment{key: "a", isArray: true, index: YamlIndex{indexType: IndexNormal, index: -2}},
+			want1: "b",
+		},
+		{
+			name:  "TEST_PARSE_RANGE_INDEX",
+			key:   "a[1:3]",
+			want:  KeySegment{key: "a", isArray: true, index: YamlIndex{indexType: IndexRange, index: 1, end: 3, hasEnd: true}},
+			want1: "",
+		},
+		{
+			name:  "TEST_PARSE_RANGE_INDEX_WITHOUT_END",
+			key:   "a[-2:]",
+			want:  KeySegment{key: "a", isArray: true, index: YamlIndex{indexType: IndexRange, index: -2}},
+			want1: "",
+		},
+		{
+			name: "TEST_PARSE_MULTI_FIELDS_SEARCH",
+			key:  "a[name=web,protocol=TCP]",
+			want: KeySegment{key: "a", isArray: true, index: YamlIndex{indexType: IndexSearch, fields: []searchField{
+				{key: "name", value: "web"}, {key: "protocol", value: "TCP"},
+			}}},
+			want1: "",
+		},
+		{
+			name:  "TEST_PARSE_QUOTED",
+			key:   `"a.b[0]"[1].c`,
+			want:  KeySegment{key: "a.b[0]", isArray: true, index: YamlIndex{indexType: IndexNormal, index: 1}},
+			want1: "c",
+		},
+		{
+			name:  "TEST_PARSE_SINGLE_QUOTED",
+			key:   `'it\'s "ok"'`,
+			want:  KeySegment{key: `it's "ok"`},
+			want1: "",
+		},
+		{
+			name:  "TEST_PARSE_BRACKETS_QUOTED",
+			key:   `["prometheus.io/scrape"].b`,
+			want:  KeySegment{key: "prometheus.io/scrape"},
+			want1: "b",
+		},
+		{
+			name:  "TEST_PARSE_BRACKETS_QUOTED_FOLLOWED",
+			key:   `annotations["prometheus.io/scrape"]`,
+			want:  KeySegment{key: "annotations"},
+			want1: `["prometheus.io/scrape"]`,
+		},
+		{
+			name:  "TEST_PARSE_BRACKETS_QUOTED_AFTER_INDEX",
+			key:   `a[0]['b\\c']`,
+			want:  KeySegment{key: "a", isArray: true, index: YamlIndex{indexType: IndexNormal, index: 0}},
+			want1: `['b\\c']`,
+		},
+		{
+			name: "TEST_PARSE_QUOTED_SEARCH_VALUE",
+			key:  `a[name="x, y]",protocol=TCP]`,
+			want: KeySegment{key: "a", isArray: true, index: YamlIndex{indexType: IndexSearch, fields: []searchField{
+				{key: "name", value: "x, y]"}, {key: "protocol", value: "TCP"},
+			}}},
+			want1: "",
+		},
+		{
+			name:    "TEST_PARSE_UNTERMINATED_QUOTED",
+			key:     `["a.b]`,
+			wantErr: true,
+		},
+		{
+			name:    "TEST_PARSE_QUOTED_ERROR",
+			key:     `"a"b`,
+			wantErr: true,
+		},
+		{
+			name:    "TEST_PARSE_RANGE_INDEX_ERROR",
+			key:     "a[1:b]",
+			wantErr: true,
+		},
 		{
 			name:    "TEST_PARSE_ARRAY_ERROR",
 			key:     "a[1].",
@@ -180,6 +290,7 @@ a: 9223372036854775807
 				t.Errorf("FromYAML() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			withoutDocument(got)
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("FromYAML() got = %v, want %v", got, tt.want)
 			}
@@ -251,12 +362,30 @@ a: b
 c: d
 `,
 			want: []StructuredObject{
-				_object{metadataKey: _metadata{}},
 				_object{"a": "b", metadataKey: _metadata{}},
 				_object{"c": "d", metadataKey: _metadata{}},
 			},
 			wantErr: false,
 		},
+		{
+			name:      "TEST_COMMENTS_ONLY",
+			multiYaml: "# head\n---\na: b\n---\n# comment only\n---\nc: d\n",
+			want: []StructuredObject{
+				_object{"a": "b", metadataKey: _metadata{}},
+				_object{"c": "d", metadataKey: _metadata{}},
+			},
+			wantErr: false,
+		},
+		{
+			name:      "TEST_MALFORMED",
+			multiYaml: "a: b\n---\nc: [d\n---\ne: f\n",
+			wantErr:   true,
+		},
+		{
+			name:      "TEST_NOT_MAP",
+			multiYaml: "a: b\n---\n- c\n",
+			wantErr:   true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -265,6 +394,7 @@ c: d
 				t.Errorf("FromYAMLs() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			withoutDocument(got...)
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("FromYAMLs() got = %v, want %v", got, tt.want)
 			}
@@ -272,6 +402,270 @@ c: d
 	}
 }
 
+func TestFromYAMLsWithOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		multiYaml string
+		options   YAMLsOptions
+		want      []StructuredObject
+		wantErrs  []string
+		wantErr   string
+	}{
+		{
+			name:      "TEST_ERROR",
+			multiYaml: "a: b\n---\nc: d\n  e: f\n---\ng: h\n",
+			wantErr:   "document 1 (line 4): mapping values are not allowed in this context",
+		},
+		{
+			name:      "TEST_SKIP_INVALID",
+			multiYaml: "# head\na: b\n---\nc: d\n  e: f\n---\n\n- g\n---\ni: j\n",
+			options:   YAMLsOptions{SkipInvalid: true},
+			want: []StructuredObject{
+				_object{"a": "b", metadataKey: _metadata{}},
+				_object{"i": "j", metadataKey: _metadata{}},
+			},
+			wantErrs: []string{
+				"document 1 (line 5): mapping values are not allowed in this context",
+				"document 2 (line 8): document is not a map: !!seq",
+			},
+		},
+		{
+			name:      "TEST_KEEP_EMPTY",
+			multiYaml: "\n---\n---\na: b\n---\n# comment only\n",
+			options:   YAMLsOptions{KeepEmpty: true},
+			want: []StructuredObject{
+				_object{metadataKey: _metadata{emptyKey: _true}},
+				_object{"a": "b", metadataKey: _metadata{}},
+				_object{metadataKey: _metadata{emptyKey: _true}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errs, err := FromYAMLsWithOptions(tt.multiYaml, tt.options)
+			if err != nil || tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("FromYAMLsWithOptions() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			var gotErrs []string
+			for _, e := range errs {
+				gotErrs = append(gotErrs, e.Error())
+			}
+			if !reflect.DeepEqual(gotErrs, tt.wantErrs) {
+				t.Errorf("FromYAMLsWithOptions() errs = %v, want %v", gotErrs, tt.wantErrs)
+			}
+			withoutDocument(got...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FromYAMLsWithOptions() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToYAML_Preserved(t *testing.T) {
+	tests := []struct {
+		name    string
+		yamlStr string
+		do      func(object StructuredObject) error
+		want    string
+	}{
+		{
+			name:    "TEST_UNCHANGED",
+			yamlStr: "# head\nkind: Service # kind\nmetadata:\n  name: 'app'\n  labels: {a: \"1\"}\nspec:\n  ports:\n  - port: 80\n",
+			do: func(object StructuredObject) error {
+				return nil
+			},
+			want: "# head\nkind: Service # kind\nmetadata:\n  name: 'app'\n  labels: {a: \"1\"}\nspec:\n  ports:\n  - port: 80\n",
+		},
+		{
+			name: "TEST_COMMENTS_AND_STYLES",
+			yamlStr: `# head comment
+kind: Deployment # kind
+metadata:
+  name: app
+  # labels
+  labels:
+    z: "1"
+    a: '2'
+spec:
+  args:
+  - |
+    multi
+    line
+  - --port=80 # port
+  - --debug
+`,
+			do: func(object StructuredObject) error {
+				if err := object.Set("metadata.labels.a", "3"); err != nil {
+					return err
+				}
+				if err := object.Set("metadata.labels.m", "y"); err != nil {
+					return err
+				}
+				if err := object.Set("spec.args[0]", "multi\nlines\n"); err != nil {
+					return err
+				}
+				return object.Delete("spec.args[2]")
+			},
+			want: `# head comment
+kind: Deployment # kind
+metadata:
+  name: app
+  # labels
+  labels:
+    z: "1"
+    a: '3'
+    m: "y"
+spec:
+  args:
+  - |
+    multi
+    lines
+  - --port=80 # port
+`,
+		},
+		{
+			name:    "TEST_RENAME_IN_PLACE",
+			yamlStr: "a:\n  b: 1 # b\n  c: 2\n",
+			do: func(object StructuredObject) error {
+				return object.Rename("a.b", "d")
+			},
+			want: "a:\n  d: 1 # b\n  c: 2\n",
+		},
+		{
+			name:    "TEST_SORTED_ELEMENTS",
+			yamlStr: "args:\n- c # third\n- a # first\n- b # second\n",
+			do: func(object StructuredObject) error {
+				return object.UpdateArrays("args", func(array []interface{}) ([]interface{}, error) {
+					return []interface{}{array[1], array[2], array[0]}, nil
+				})
+			},
+			want: "args:\n- a # first\n- b # second\n- c # third\n",
+		},
+		{
+			name:    "TEST_ALIASES",
+			yamlStr: "base: &base\n  image: nginx\na: *base\nb: *base\nc:\n  <<: *base\n  name: c\n",
+			do: func(object StructuredObject) error {
+				return object.Set("b.image", "x")
+			},
+			want: "base: &base\n  image: nginx\na: *base\nb:\n  image: x\nc:\n  <<: *base\n  name: c\n",
+		},
+		{
+			name:    "TEST_CHANGED_ANCHOR",
+			yamlStr: "base: &base\n  image: nginx\na: *base\nc:\n  <<: *base\n  name: c\n",
+			do: func(object StructuredObject) error {
+				return object.Set("base.image", "envoy")
+			},
+			want: "base: &base\n  image: envoy\na:\n  image: nginx\nc:\n  image: nginx\n  name: c\n",
+		},
+		{
+			name:    "TEST_INSERTION_ORDER",
+			yamlStr: "metadata:\n  name: app\n",
+			do: func(object StructuredObject) error {
+				for _, key := range []string{"metadata.labels.z", "metadata.labels.a", "metadata.b", "spec.y", "spec.x"} {
+					if err := object.Set(key, "1"); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			want: "metadata:\n  name: app\n  labels:\n    z: \"1\"\n    a: \"1\"\n  b: \"1\"\nspec:\n  \"y\": \"1\"\n  x: \"1\"\n",
+		},
+		{
+			name:    "TEST_MERGE_ORDER",
+			yamlStr: "spec:\n  containers:\n  - name: a\n    image: nginx\n",
+			do: func(object StructuredObject) error {
+				fragment, err := ParseFragment([]byte("containers:\n- name: sidecar\n  image: envoy\n  env: {z: 1, a: 2}\nvolumes: []\ndns: {policy: None}\n"))
+				if err != nil {
+					return err
+				}
+				return MergeFragment(object, "spec", fragment, ListMergeByName)
+			},
+			want: "spec:\n  containers:\n  - name: a\n    image: nginx\n  - name: sidecar\n    image: envoy\n    env:\n      z: 1\n      a: 2\n  volumes: []\n  dns:\n    policy: None\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			object, err := FromYAML(tt.yamlStr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.do(object); err != nil {
+				t.Fatal(err)
+			}
+			got, err := object.ToYAML()
+			if err != nil {
+				t.Errorf("ToYAML() error = %v", err)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ToYAML() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToYAMLs_Preserved(t *testing.T) {
+	objects, err := FromYAMLs("# first\na: 1\n---\n# second\nb: [1, 2]\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := objects[1].Set("b[++]", 3); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ToYAMLs(objects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# first\na: 1\n---\n# second\nb: [1, 2, 3]\n"; got != want {
+		t.Errorf("ToYAMLs() got = %v, want %v", got, want)
+	}
+}
+
+func TestToYAMLs_KeepEmpty(t *testing.T) {
+	tests := []struct {
+		name      string
+		multiYaml string
+	}{
+		{
+			name:      "TEST_HEAD_COMMENT",
+			multiYaml: "# head\na: b\n---\nc: d\n",
+		},
+		{
+			name:      "TEST_EMPTY",
+			multiYaml: "---\n---\na: b\n---\n---\n# comment only\n---\nc: d\n---\n",
+		},
+		{
+			name:      "TEST_EMPTY_HEAD_COMMENT",
+			multiYaml: "---\n# head\n---\na: b\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_objects, _, err := FromYAMLsWithOptions(tt.multiYaml, YAMLsOptions{KeepEmpty: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ToYAMLs(_objects)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.multiYaml {
+				t.Errorf("ToYAMLs() got = %q, want %q", got, tt.multiYaml)
+			}
+		})
+	}
+}
+
+// withoutDocument remove the document node from metadata, which is compared by the yaml tests
+func withoutDocument(objects ...StructuredObject) {
+	for _, object := range objects {
+		delete(object.Metadata().(_metadata), documentKey)
+	}
+}
+
 func TestToYAMLs(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -370,6 +764,86 @@ func TestIsValidKey(t *testing.T) {
 			key:  "(a).b.[80].c",
 			want: false,
 		},
+		{
+			name: "TEST",
+			key:  "metadata.labels.*",
+			want: true,
+		},
+		{
+			name: "TEST",
+			key:  "spec..image",
+			want: true,
+		},
+		{
+			name: "TEST",
+			key:  "**.namespace",
+			want: true,
+		},
+		{
+			name: "TEST",
+			key:  "a.*[0].b",
+			want: true,
+		},
+		{
+			name: "TEST",
+			key:  "a.**",
+			want: false,
+		},
+		{
+			name: "TEST",
+			key:  "a..",
+			want: false,
+		},
+		{
+			name: "TEST",
+			key:  "a.*b",
+			want: false,
+		},
+		{
+			name: "TEST",
+			key:  "a[-1].b[1:3].c[:2]",
+			want: true,
+		},
+		{
+			name: "TEST",
+			key:  "ports[name=web,protocol=TCP].port",
+			want: true,
+		},
+		{
+			name: "TEST",
+			key:  "a[1:2:3]",
+			want: false,
+		},
+		{
+			name: "TEST",
+			key:  "a[name=web,TCP]",
+			want: false,
+		},
+		{
+			name: "TEST",
+			key:  `metadata.annotations["prometheus.io/scrape"]`,
+			want: true,
+		},
+		{
+			name: "TEST",
+			key:  `data.'a b:c~@'.'中文'`,
+			want: true,
+		},
+		{
+			name: "TEST",
+			key:  `a["b"]["c"][0].d`,
+			want: true,
+		},
+		{
+			name: "TEST",
+			key:  `a."b`,
+			want: false,
+		},
+		{
+			name: "TEST",
+			key:  `a["b"`,
+			want: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -379,3 +853,763 @@ func TestIsValidKey(t *testing.T) {
 		})
 	}
 }
+
+func Test_object_Rename(t *testing.T) {
+	tests := []struct {
+		name    string
+		yamlStr string
+		key     string
+		newName string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "TEST1",
+			yamlStr: "a:\n  b: 1\n  c: 2\n",
+			key:     "a.b",
+			newName: "d",
+			want:    "a:\n  d: 1\n  c: 2\n",
+		},
+		{
+			name:    "TEST2",
+			yamlStr: "a:\n- b: 1\n- c: 2\n- b: 3\n",
+			key:     "a[*].b",
+			newName: "e.f",
+			want:    "a:\n- e.f: 1\n- c: 2\n- e.f: 3\n",
+		},
+		{
+			name:    "TEST3",
+			yamlStr: "a:\n- name: x\n  b: 1\n- name: z\n  b: 2\n",
+			key:     "a[name=z].b",
+			newName: "c",
+			want:    "a:\n- name: x\n  b: 1\n- name: z\n  c: 2\n",
+		},
+		{
+			name:    "TEST_NOT_FOUND",
+			yamlStr: "a:\n  b: 1\n",
+			key:     "a.c",
+			newName: "d",
+			wantErr: true,
+		},
+		{
+			name:    "TEST_ALREADY_EXISTS",
+			yamlStr: "a:\n  b: 1\n  c: 2\n",
+			key:     "a.b",
+			newName: "c",
+			wantErr: true,
+		},
+		{
+			name:    "TEST_ARRAY_ELEMENT",
+			yamlStr: "a:\n- 1\n",
+			key:     "a[0]",
+			newName: "c",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			object, err := FromYAML(tt.yamlStr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = object.Rename(tt.key, tt.newName)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Rename() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := object.ToYAML()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Rename() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_object_UpdateArrays(t *testing.T) {
+	appendOne := func(array []interface{}) ([]interface{}, error) {
+		return append(array, 1), nil
+	}
+	tests := []struct {
+		name    string
+		yamlStr string
+		key     string
+		update  func([]interface{}) ([]interface{}, error)
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "TEST1",
+			yamlStr: "a:\n  b:\n  - 0\n",
+			key:     "a.b",
+			update:  appendOne,
+			want:    "a:\n  b:\n  - 0\n  - 1\n",
+		},
+		{
+			name:    "TEST2",
+			yamlStr: "a:\n- b: [0]\n- c: 2\n",
+			key:     "a[*].b",
+			update:  appendOne,
+			want:    "a:\n- b: [0, 1]\n- c: 2\n  b:\n  - 1\n",
+		},
+		{
+			name:    "TEST_MISSING_PARENT",
+			yamlStr: "a: {}\n",
+			key:     "b.c",
+			update:  appendOne,
+			want:    "a: {}\n",
+		},
+		{
+			name:    "TEST_EMPTY_RESULT",
+			yamlStr: "a: {}\n",
+			key:     "a.b",
+			update: func(array []interface{}) ([]interface{}, error) {
+				return array, nil
+			},
+			want: "a: {}\n",
+		},
+		{
+			name:    "TEST_NOT_ARRAY",
+			yamlStr: "a:\n  b: 1\n",
+			key:     "a.b",
+			update:  appendOne,
+			wantErr: true,
+		},
+		{
+			name:    "TEST_ARRAY_ELEMENT",
+			yamlStr: "a:\n- [1]\n",
+			key:     "a[0]",
+			update:  appendOne,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			object, err := FromYAML(tt.yamlStr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = object.UpdateArrays(tt.key, tt.update)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("UpdateArrays() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := object.ToYAML()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("UpdateArrays() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_object_FilterIndex(t *testing.T) {
+	// a simple compiler only for test, condition like: name=a
+	options := KeyOptions{Filter: func(condition string) (ElementFilter, error) {
+		kv := strings.SplitN(condition, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid condition: %v", condition)
+		}
+		return func(element StructuredObject) (bool, error) {
+			v, err := element.Get(kv[0])
+			return v == kv[1], err
+		}, nil
+	}}
+
+	yamlStr := "a:\n- name: x\n  b: 1\n- name: z\n  b: 2\n- name: x\n  b: 3\n- 4\n"
+	tests := []struct {
+		name    string
+		do      func(object StructuredObject) (interface{}, error)
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name: "TEST_GET",
+			do: func(object StructuredObject) (interface{}, error) {
+				return object.Get("a[?(name=z)].b")
+			},
+			want: []interface{}{2},
+		},
+		{
+			name: "TEST_EXIST",
+			do: func(object StructuredObject) (interface{}, error) {
+				return []bool{object.Exist("a[?(name=z)]"), object.Exist("a[?(name=w)]"), object.Exist("a[?(name=x)].b"), object.Exist("a[?(name=x)].c")}, nil
+			},
+			want: []bool{true, false, true, false},
+		},
+		{
+			name: "TEST_SET",
+			do: func(object StructuredObject) (interface{}, error) {
+				if err := object.Set("a[?(name=x)].b", 0); err != nil {
+					return nil, err
+				}
+				return object.ToYAML()
+			},
+			want: "a:\n- name: x\n  b: 0\n- name: z\n  b: 2\n- name: x\n  b: 0\n- 4\n",
+		},
+		{
+			name: "TEST_SET_ELEMENTS",
+			do: func(object StructuredObject) (interface{}, error) {
+				if err := object.Set("a[?(name=x)]", 0); err != nil {
+					return nil, err
+				}
+				return object.ToYAML()
+			},
+			want: "a:\n- 0\n- name: z\n  b: 2\n- 0\n- 4\n",
+		},
+		{
+			name: "TEST_DELETE",
+			do: func(object StructuredObject) (interface{}, error) {
+				if err := object.Delete("a[?(name=x)]"); err != nil {
+					return nil, err
+				}
+				return object.ToYAML()
+			},
+			want: "a:\n- name: z\n  b: 2\n- 4\n",
+		},
+		{
+			name: "TEST_GET_MULTIPLE",
+			do: func(object StructuredObject) (interface{}, error) {
+				return object.Get("a[?(name=x)].b")
+			},
+			want: []interface{}{1, 3},
+		},
+		{
+			name: "TEST_INVALID_CONDITION",
+			do: func(object StructuredObject) (interface{}, error) {
+				return object.Get("a[?(name)].b")
+			},
+			wantErr: true,
+		},
+		{
+			name: "TEST_GET_OBJECT",
+			do: func(object StructuredObject) (interface{}, error) {
+				if err := object.Set("c.a", []interface{}{map[interface{}]interface{}{"name": "y", "b": 5}}); err != nil {
+					return nil, err
+				}
+				c, err := object.GetObject("c")
+				if err != nil {
+					return nil, err
+				}
+				return c.Get("a[?(name=y)].b")
+			},
+			want: []interface{}{5},
+		},
+		{
+			name: "TEST_NOT_SUPPORTED",
+			do: func(object StructuredObject) (interface{}, error) {
+				return FromMap(object.ToMap()).Get("a[?(name=z)].b")
+			},
+			wantErr: true,
+		},
+		{
+			name: "TEST_ON_FILTER_ERROR",
+			do: func(object StructuredObject) (interface{}, error) {
+				var errs []string
+				object = WithOptions(object, KeyOptions{
+					Filter: func(condition string) (ElementFilter, error) {
+						return func(element StructuredObject) (bool, error) {
+							return false, fmt.Errorf("failed")
+						}, nil
+					},
+					OnFilterError: func(err error) (bool, error) {
+						errs = append(errs, err.Error())
+						return false, nil
+					},
+				})
+				if _, err := object.Get("a[?(name=z)].b"); err != nil {
+					return nil, err
+				}
+				return len(errs), nil
+			},
+			want: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			object, err := FromYAML(yamlStr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := tt.do(WithOptions(object, options))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("FilterIndex error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FilterIndex got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterConditions(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		want []string
+	}{
+		{name: "TEST_NONE", key: "a[0].b", want: nil},
+		{name: "TEST_NESTED", key: `a[?(b == "x")].c[?(EXISTS(d[?(e > 1)]))]`, want: []string{`b == "x"`, `EXISTS(d[?(e > 1)])`}},
+		{name: "TEST_INVALID_KEY", key: "a[?(b)].", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FilterConditions(tt.key); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FilterConditions() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_object_RangeIndex(t *testing.T) {
+	yamlStr := "a:\n- name: web\n  protocol: TCP\n  b: 1\n- name: web\n  protocol: UDP\n  b: 2\n- name: metrics\n  protocol: TCP\n  b: 3\n"
+	tests := []struct {
+		name    string
+		do      func(object StructuredObject) (interface{}, error)
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name: "TEST_GET_NEGATIVE",
+			do: func(object StructuredObject) (interface{}, error) {
+				return object.Get("a[-1].name")
+			},
+			want: "metrics",
+		},
+		{
+			name: "TEST_GET_NEGATIVE_OUT_OF_RANGE",
+			do: func(object StructuredObject) (interface{}, error) {
+				return object.Get("a[-4].name")
+			},
+			wantErr: true,
+		},
+		{
+			name: "TEST_GET_RANGE",
+			do: func(object StructuredObject) (interface{}, error) {
+				return object.Get("a[1:].b")
+			},
+			want: []interface{}{2, 3},
+		},
+		{
+			name: "TEST_GET_RANGE_CLAMPED",
+			do: func(object StructuredObject) (interface{}, error) {
+				return object.Get("a[:-1].b")
+			},
+			want: []interface{}{1, 2},
+		},
+		{
+			name: "TEST_GET_MULTI_FIELDS_SEARCH",
+			do: func(object StructuredObject) (interface{}, error) {
+				return object.Get("a[name=web,protocol=UDP].b")
+			},
+			want: 2,
+		},
+		{
+			name: "TEST_EXIST",
+			do: func(object StructuredObject) (interface{}, error) {
+				return []bool{object.Exist("a[-3]"), object.Exist("a[-4]"), object.Exist("a[1:2].b"), object.Exist("a[3:]"),
+					object.Exist("a[name=metrics,protocol=UDP]")}, nil
+			},
+			want: []bool{true, false, true, false, false},
+		},
+		{
+			name: "TEST_EXIST_SEARCH",
+			do: func(object StructuredObject) (interface{}, error) {
+				return []bool{object.Exist("a[name=web,protocol=UDP]"), object.Exist("a[name=web].b"),
+					object.Exist("a[name=metrics].c"), object.Exist("a[name=none].b")}, nil
+			},
+			want: []bool{true, true, false, false},
+		},
+		{
+			name: "TEST_QUOTED_SEARCH_VALUE",
+			do: func(object StructuredObject) (interface{}, error) {
+				if err := object.Set("a[name=metrics].name", "a,b"); err != nil {
+					return nil, err
+				}
+				if !object.Exist(`a[name="a,b"].b`) {
+					return nil, fmt.Errorf("a[name=\"a,b\"].b not exists")
+				}
+				return object.Get(`a[name="a,b",protocol=TCP].b`)
+			},
+			want: 3,
+		},
+		{
+			name: "TEST_SET",
+			do: func(object StructuredObject) (interface{}, error) {
+				if err := object.Set("a[-1].b", 0); err != nil {
+					return nil, err
+				}
+				if err := object.Set("a[:2].protocol", "SCTP"); err != nil {
+					return nil, err
+				}
+				if err := object.Set("a[name=web,protocol=SCTP].name", "http"); err != nil {
+					return nil, err
+				}
+				return object.Get("a")
+			},
+			want: []interface{}{
+				map[interface{}]interface{}{"name": "http", "protocol": "SCTP", "b": 1},
+				map[interface{}]interface{}{"name": "web", "protocol": "SCTP", "b": 2},
+				map[interface{}]interface{}{"name": "metrics", "protocol": "TCP", "b": 0},
+			},
+		},
+		{
+			name: "TEST_DELETE_NEGATIVE",
+			do: func(object StructuredObject) (interface{}, error) {
+				if err := object.Delete("a[-2]"); err != nil {
+					return nil, err
+				}
+				return object.Get("a[*].b")
+			},
+			want: []interface{}{1, 3},
+		},
+		{
+			name: "TEST_DELETE_RANGE",
+			do: func(object StructuredObject) (interface{}, error) {
+				if err := object.Delete("a[1:3]"); err != nil {
+					return nil, err
+				}
+				return object.Get("a[*].b")
+			},
+			want: []interface{}{1},
+		},
+		{
+			name: "TEST_DELETE_MULTI_FIELDS_SEARCH",
+			do: func(object StructuredObject) (interface{}, error) {
+				if err := object.Delete("a[protocol=TCP,name=web]"); err != nil {
+					return nil, err
+				}
+				return object.Get("a[*].b")
+			},
+			want: []interface{}{2, 3},
+		},
+		{
+			name: "TEST_EXPAND_RANGE",
+			do: func(object StructuredObject) (interface{}, error) {
+				return object.ExpandKey("a[-2:].b")
+			},
+			want: []string{"a[1].b", "a[2].b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			object, err := FromYAML(yamlStr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := tt.do(object)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RangeIndex error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RangeIndex got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_object_QuotedKey(t *testing.T) {
+	yamlStr := "metadata:\n  annotations:\n    prometheus.io/scrape: \"true\"\n    a b: \"1\"\n    '*': \"2\"\n"
+	tests := []struct {
+		name string
+		do   func(object StructuredObject) (interface{}, error)
+		want interface{}
+	}{
+		{
+			name: "TEST_GET",
+			do: func(object StructuredObject) (interface{}, error) {
+				return object.Get(`metadata.annotations["prometheus.io/scrape"]`)
+			},
+			want: "true",
+		},
+		{
+			name: "TEST_GET_LITERAL_WILDCARD",
+			do: func(object StructuredObject) (interface{}, error) {
+				return object.Get(`metadata.annotations.'*'`)
+			},
+			want: "2",
+		},
+		{
+			name: "TEST_SET_AND_DELETE",
+			do: func(object StructuredObject) (interface{}, error) {
+				if err := object.Set(`metadata.annotations."a b"`, "3"); err != nil {
+					return nil, err
+				}
+				if err := object.Set(`metadata["labels"]["app/[v1]"]`, "x"); err != nil {
+					return nil, err
+				}
+				if err := object.Delete(`metadata.annotations['*']`); err != nil {
+					return nil, err
+				}
+				return object.ToYAML()
+			},
+			want: "metadata:\n  annotations:\n    prometheus.io/scrape: \"true\"\n    a b: \"3\"\n  labels:\n    app/[v1]: x\n",
+		},
+		{
+			name: "TEST_EXPAND_SPECIAL_NAMES",
+			do: func(object StructuredObject) (interface{}, error) {
+				return object.ExpandKey("metadata.annotations.*")
+			},
+			want: []string{`metadata.annotations.["*"]`, `metadata.annotations.["a b"]`, "metadata.annotations.(prometheus.io/scrape)"},
+		},
+		{
+			name: "TEST_GET_WILDCARD_SPECIAL_NAMES",
+			do: func(object StructuredObject) (interface{}, error) {
+				return object.Get("metadata.annotations.*")
+			},
+			want: []interface{}{"2", "1", "true"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			object, err := FromYAML(yamlStr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := tt.do(object)
+			if err != nil {
+				t.Errorf("QuotedKey error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("QuotedKey got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_object_ExpandKey(t *testing.T) {
+	yamlStr := `a:
+  b:
+  - c: 1
+  - d: 2
+  e.f:
+    c: 3
+  g:
+    h:
+      c: 4
+`
+	tests := []struct {
+		name    string
+		key     string
+		want    []string
+		wantErr bool
+	}{
+		{name: "TEST_SINGLE", key: "a.x", want: []string{"a.x"}},
+		{name: "TEST_LOOP", key: "a.b[*].c", want: []string{"a.b[0].c", "a.b[1].c"}},
+		{name: "TEST_WILDCARD", key: "a.*", want: []string{"a.b", "a.(e.f)", "a.g"}},
+		{name: "TEST_WILDCARD_WITH_REST", key: "a.*.c", want: []string{"a.(e.f).c", "a.g.c"}},
+		{name: "TEST_RECURSIVE", key: "**.c", want: []string{"a.b[0].c", "a.(e.f).c", "a.g.h.c"}},
+		{name: "TEST_RECURSIVE_DOTS", key: "a.g..c", want: []string{"a.g.h.c"}},
+		{name: "TEST_RECURSIVE_DUPLICATED", key: "**.**.h", want: []string{"a.g.h"}},
+		{name: "TEST_INVALID", key: "a.**", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			object, err := FromYAML(yamlStr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := object.ExpandKey(tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ExpandKey() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExpandKey() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_object_Wildcards(t *testing.T) {
+	yamlStr := "metadata:\n  labels:\n    a: \"1\"\n    b: \"2\"\n  resourceVersion: \"3\"\nspec:\n  template:\n    metadata:\n      resourceVersion: \"4\"\n"
+	tests := []struct {
+		name string
+		do   func(object StructuredObject) (interface{}, error)
+		want interface{}
+	}{
+		{
+			name: "TEST_GET_WILDCARD",
+			do: func(object StructuredObject) (interface{}, error) {
+				return object.Get("metadata.labels.*")
+			},
+			want: []interface{}{"1", "2"},
+		},
+		{
+			name: "TEST_GET_RECURSIVE",
+			do: func(object StructuredObject) (interface{}, error) {
+				return object.Get("**.resourceVersion")
+			},
+			want: []interface{}{"3", "4"},
+		},
+		{
+			name: "TEST_EXIST",
+			do: func(object StructuredObject) (interface{}, error) {
+				return []bool{object.Exist("spec..resourceVersion"), object.Exist("**.uid"), object.Exist("metadata.labels.*")}, nil
+			},
+			want: []bool{true, false, true},
+		},
+		{
+			name: "TEST_SET",
+			do: func(object StructuredObject) (interface{}, error) {
+				if err := object.Set("metadata.labels.*", "0"); err != nil {
+					return nil, err
+				}
+				if err := object.Set("**.resourceVersion", "5"); err != nil {
+					return nil, err
+				}
+				return object.ToYAML()
+			},
+			want: "metadata:\n  labels:\n    a: \"0\"\n    b: \"0\"\n  resourceVersion: \"5\"\nspec:\n  template:\n    metadata:\n      resourceVersion: \"5\"\n",
+		},
+		{
+			name: "TEST_DELETE",
+			do: func(object StructuredObject) (interface{}, error) {
+				if err := object.Delete("**.resourceVersion"); err != nil {
+					return nil, err
+				}
+				if err := object.Delete("metadata.labels.*"); err != nil {
+					return nil, err
+				}
+				return object.ToYAML()
+			},
+			want: "metadata:\n  labels: {}\nspec:\n  template:\n    metadata: {}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			object, err := FromYAML(yamlStr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := tt.do(object)
+			if err != nil {
+				t.Errorf("Wildcards error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Wildcards got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeepCopy(t *testing.T) {
+	original := map[interface{}]interface{}{"a": []interface{}{map[interface{}]interface{}{"b": 1}}}
+	copied := DeepCopy(original)
+	if !reflect.DeepEqual(copied, original) {
+		t.Errorf("DeepCopy() got = %v, want %v", copied, original)
+	}
+
+	copied.(map[interface{}]interface{})["a"].([]interface{})[0].(map[interface{}]interface{})["b"] = 2
+	if original["a"].([]interface{})[0].(map[interface{}]interface{})["b"] != 1 {
+		t.Errorf("DeepCopy() shares values with original: %v", original)
+	}
+}
+
+func TestDeepMerge(t *testing.T) {
+	type args struct {
+		dst      interface{}
+		src      interface{}
+		strategy ListStrategy
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name: "TEST_MAP",
+			args: args{
+				dst:      map[interface{}]interface{}{"a": 1, "b": map[interface{}]interface{}{"c": 2, "d": 3}},
+				src:      map[interface{}]interface{}{"b": map[interface{}]interface{}{"c": 4}, "e": 5},
+				strategy: ListReplace,
+			},
+			want: map[interface{}]interface{}{"a": 1, "b": map[interface{}]interface{}{"c": 4, "d": 3}, "e": 5},
+		},
+		{
+			name: "TEST_NIL_DST",
+			args: args{
+				dst:      nil,
+				src:      map[interface{}]interface{}{"a": 1},
+				strategy: ListReplace,
+			},
+			want: map[interface{}]interface{}{"a": 1},
+		},
+		{
+			name: "TEST_LIST_REPLACE",
+			args: args{
+				dst:      []interface{}{1, 2},
+				src:      []interface{}{3},
+				strategy: ListReplace,
+			},
+			want: []interface{}{3},
+		},
+		{
+			name: "TEST_LIST_APPEND",
+			args: args{
+				dst:      []interface{}{1, 2},
+				src:      []interface{}{3},
+				strategy: ListAppend,
+			},
+			want: []interface{}{1, 2, 3},
+		},
+		{
+			name: "TEST_LIST_MERGE_BY_NAME",
+			args: args{
+				dst: []interface{}{
+					map[interface{}]interface{}{"name": "a", "image": "nginx", "ports": []interface{}{map[interface{}]interface{}{"name": "http", "port": 80}}},
+					map[interface{}]interface{}{"name": "b", "image": "busybox"},
+				},
+				src: []interface{}{
+					map[interface{}]interface{}{"name": "a", "image": "nginx:1.25", "ports": []interface{}{map[interface{}]interface{}{"name": "https", "port": 443}}},
+					map[interface{}]interface{}{"image": "envoy"},
+				},
+				strategy: ListMergeByName,
+			},
+			want: []interface{}{
+				map[interface{}]interface{}{"name": "a", "image": "nginx:1.25", "ports": []interface{}{
+					map[interface{}]interface{}{"name": "http", "port": 80},
+					map[interface{}]interface{}{"name": "https", "port": 443},
+				}},
+				map[interface{}]interface{}{"name": "b", "image": "busybox"},
+				map[interface{}]interface{}{"image": "envoy"},
+			},
+		},
+		{
+			name: "TEST_UNKNOWN_STRATEGY",
+			args: args{
+				dst:      []interface{}{1},
+				src:      []interface{}{2},
+				strategy: "unknown",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DeepMerge(tt.args.dst, tt.args.src, tt.args.strategy)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DeepMerge() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DeepMerge() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/storm-blue/rubick/pkg/common"
+	"github.com/storm-blue/rubick/pkg/modifier/objects"
+	"github.com/storm-blue/rubick/pkg/utils"
+)
+
+// ClusterKey is the key of the cluster name which resources are got from, scripts can branch on it like:
+// IF VALUE_OF(__cluster) == "prod" THEN ...
+const ClusterKey = "__cluster"
+
+// clusterSource get resources from a live k8s cluster.
+type clusterSource struct {
+	source utils.ResourceSource
+	name   string
+	// workers is the number of goroutines getting resource types by GetAllResources, the concurrent requests are
+	// limited by the Requests of options
+	workers int
+}
+
+// NewClusterSource create a source of cluster, resources are tagged with name by ClusterKey if name is not blank.
+func NewClusterSource(name string, options utils.ClusterOptions) (Source, error) {
+	source, err := utils.NewResourceSource(options)
+	if err != nil {
+		return nil, wrapClusterError(name, err)
+	}
+	return &clusterSource{source: source, name: name, workers: options.Workers}, nil
+}
+
+func (s *clusterSource) GetResources(ctx context.Context, resourceType string) ([]objects.StructuredObject, error) {
+	if resourceType == "" {
+		return nil, fmt.Errorf("resource type is required to get resources from cluster")
+	}
+	resources, err := s.source.GetResources(ctx, nil, resourceType)
+	if err != nil {
+		return nil, wrapClusterError(s.name, err)
+	}
+	if err := TagCluster(resources, s.name); err != nil {
+		return nil, err
+	}
+	return resources, nil
+}
+
+// GetAllResources get resources of the discovered resource types concurrently, they are returned in the
+// order of resource types.
+func (s *clusterSource) GetAllResources(ctx context.Context, options DiscoveryOptions) ([]objects.StructuredObject, error) {
+	resourceTypes, err := DiscoverResourceTypes(ctx, s.source, options)
+	if err != nil {
+		return nil, wrapClusterError(s.name, err)
+	}
+
+	results, err := common.Parallel(ctx, s.workers, len(resourceTypes), func(ctx context.Context, i int) ([]objects.StructuredObject, error) {
+		return s.GetResources(ctx, resourceTypes[i])
+	})
+	if err != nil {
+		return nil, err
+	}
+	return slices.Concat(results...), nil
+}
+
+// TagCluster set the cluster name of resources by ClusterKey, nothing is changed if name is blank.
+func TagCluster(resources []objects.StructuredObject, name string) error {
+	if name == "" {
+		return nil
+	}
+	for _, resource := range resources {
+		if err := resource.Set(ClusterKey, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func wrapClusterError(name string, err error) error {
+	if name == "" {
+		return err
+	}
+	return fmt.Errorf("cluster %v: %w", name, err)
+}
@@ -0,0 +1,162 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/storm-blue/rubick/pkg/modifier/objects"
+)
+
+// fakeResourceSource returns resources decoded from yaml, or err if it is not nil.
+// A resource with kind of the resource type is returned if yaml is blank, after the delay of the resource type.
+type fakeResourceSource struct {
+	yaml   string
+	err    error
+	delays map[string]time.Duration
+}
+
+func (s *fakeResourceSource) GetResources(ctx context.Context, namespaces []string, resourceType string) ([]objects.StructuredObject, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if s.yaml != "" {
+		return objects.FromYAMLs(s.yaml)
+	}
+	select {
+	case <-time.After(s.delays[resourceType]):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return []objects.StructuredObject{objects.FromMap(map[interface{}]interface{}{"kind": resourceType})}, nil
+}
+
+func (s *fakeResourceSource) GetAllNamespaces(ctx context.Context) ([]string, error) {
+	return nil, s.err
+}
+
+func (s *fakeResourceSource) GetResourceTypes(ctx context.Context, clusterScoped bool) ([]string, error) {
+	types := []string{"deployments.apps", "endpoints", "events", "events.events.k8s.io", "pods.metrics.k8s.io", "services"}
+	if clusterScoped {
+		types = append(types, "namespaces")
+	}
+	return types, s.err
+}
+
+func (s *fakeResourceSource) GetYAML(ctx context.Context, namespace, resourceType, resourceName string) (string, error) {
+	return s.yaml, s.err
+}
+
+func Test_clusterSource_GetResources(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  *clusterSource
+		want    []interface{}
+		wantErr string
+	}{
+		{
+			name:   "TEST_NAMED",
+			source: &clusterSource{source: &fakeResourceSource{yaml: "kind: Service\n---\nkind: Service\n"}, name: "prod"},
+			want:   []interface{}{"prod", "prod"},
+		},
+		{
+			name:   "TEST_UNNAMED",
+			source: &clusterSource{source: &fakeResourceSource{yaml: "kind: Service\n"}},
+			want:   []interface{}{nil},
+		},
+		{
+			name:    "TEST_ERROR",
+			source:  &clusterSource{source: &fakeResourceSource{err: errors.New("forbidden")}, name: "prod"},
+			wantErr: "cluster prod: forbidden",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resources, err := tt.source.GetResources(context.Background(), "service")
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("GetResources() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetResources() error = %v", err)
+			}
+			var got []interface{}
+			for _, resource := range resources {
+				cluster, _ := resource.Get(ClusterKey)
+				got = append(got, cluster)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetResources() got clusters = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_clusterSource_GetAllResources(t *testing.T) {
+	source := &clusterSource{
+		source: &fakeResourceSource{delays: map[string]time.Duration{
+			"deployments.apps": 100 * time.Millisecond,
+			"services":         10 * time.Millisecond,
+		}},
+		name:    "prod",
+		workers: 4,
+	}
+	options := DiscoveryOptions{ClusterScoped: true}
+
+	resources, err := source.GetAllResources(context.Background(), options)
+	if err != nil {
+		t.Fatalf("GetAllResources() error = %v", err)
+	}
+	var got []string
+	for _, resource := range resources {
+		kind, _ := resource.GetString("kind")
+		got = append(got, kind)
+	}
+	if want := []string{"deployments.apps", "services", "namespaces"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetAllResources() got = %v, want %v", got, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := source.GetAllResources(ctx, options); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetAllResources() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestDiscoverResourceTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		options DiscoveryOptions
+		want    []string
+	}{
+		{
+			name: "TEST_DEFAULT",
+			want: []string{"deployments.apps", "services"},
+		},
+		{
+			name:    "TEST_CLUSTER_SCOPED",
+			options: DiscoveryOptions{ClusterScoped: true, Exclude: []string{"apps"}},
+			want:    []string{"services", "namespaces"},
+		},
+		{
+			name:    "TEST_INCLUDE",
+			options: DiscoveryOptions{Include: []string{"events", "metrics.k8s.io", "services"}, Exclude: []string{"events.k8s.io"}},
+			want:    []string{"events", "pods.metrics.k8s.io", "services"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DiscoverResourceTypes(context.Background(), &fakeResourceSource{}, tt.options)
+			if err != nil {
+				t.Fatalf("DiscoverResourceTypes() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiscoverResourceTypes() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/storm-blue/rubick/pkg/modifier/objects"
+	"github.com/storm-blue/rubick/pkg/utils"
+)
+
+// DefaultExcludes are the resource types and groups which are not got by GetAllResources unless they are
+// included explicitly, they are generated by cluster and useless for migrations.
+var DefaultExcludes = []string{"events", "endpoints", "endpointslices.discovery.k8s.io", "metrics.k8s.io"}
+
+// DiscoveryOptions are options of getting all resources.
+//
+// Include and Exclude are resource types like: services, deployments.apps, or groups like: metrics.k8s.io.
+// Resource types of all groups are matched if the group is omitted, like: events matches events and
+// events.events.k8s.io.
+type DiscoveryOptions struct {
+	// Include are the resource types or groups to get, blank means all except DefaultExcludes
+	Include []string
+	// Exclude are the resource types or groups not to get
+	Exclude []string
+	// ClusterScoped get cluster scoped resources too (like: namespaces, clusterroles)
+	ClusterScoped bool
+}
+
+// DiscoverResourceTypes discover resource types of source which are selected by options.
+func DiscoverResourceTypes(ctx context.Context, source utils.ResourceSource, options DiscoveryOptions) ([]string, error) {
+	resourceTypes, err := source.GetResourceTypes(ctx, options.ClusterScoped)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for _, resourceType := range resourceTypes {
+		if options.selected(func(pattern string) bool { return matchResourceTypePattern(resourceType, pattern) }) {
+			result = append(result, resourceType)
+		}
+	}
+	return result, nil
+}
+
+// selected check Include and Exclude of options by match, which returns true if a pattern matches.
+func (options DiscoveryOptions) selected(match func(pattern string) bool) bool {
+	matchAny := func(patterns []string) bool {
+		for _, pattern := range patterns {
+			if match(strings.ToLower(strings.TrimSpace(pattern))) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if matchAny(options.Exclude) {
+		return false
+	}
+	if len(options.Include) > 0 {
+		return matchAny(options.Include)
+	}
+	return !matchAny(DefaultExcludes)
+}
+
+// selectedResource check Include and Exclude of options for resources decoded from files.
+func (options DiscoveryOptions) selectedResource(resource objects.StructuredObject) bool {
+	if namespace, _ := resource.GetString("metadata.namespace"); namespace == "" && !options.ClusterScoped {
+		return false
+	}
+	return options.selected(func(pattern string) bool {
+		if MatchResourceType(resource, pattern) {
+			return true
+		}
+		apiVersion, _ := resource.GetString("apiVersion")
+		group, _, found := strings.Cut(apiVersion, "/")
+		return found && strings.ToLower(group) == pattern
+	})
+}
+
+// matchResourceTypePattern check resource type like: deployments.apps by pattern like: deployments.apps,
+// deployments, apps
+func matchResourceTypePattern(resourceType, pattern string) bool {
+	name, group, _ := strings.Cut(resourceType, ".")
+	return pattern == resourceType || pattern == name || (group != "" && pattern == group)
+}
+
+// ResourceKey returns the identity of resource, which is used to remove duplicated resources got by
+// different resource types.
+func ResourceKey(resource objects.StructuredObject) string {
+	var values []string
+	for _, key := range []string{ClusterKey, "apiVersion", "kind", "metadata.namespace", "metadata.name"} {
+		value, _ := resource.Get(key)
+		values = append(values, fmt.Sprint(value))
+	}
+	if apiVersion := values[1]; strings.Contains(apiVersion, "/") {
+		// the same resource may be got by different versions
+		values[1], _, _ = strings.Cut(apiVersion, "/")
+	} else {
+		values[1] = ""
+	}
+	return strings.Join(values, "/")
+}
@@ -0,0 +1,248 @@
+package source
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/storm-blue/rubick/pkg/common"
+	"github.com/storm-blue/rubick/pkg/modifier/objects"
+)
+
+// Stdin is the path of stdin for Open
+const Stdin = "-"
+
+// FileSource provides resources decoded from files, like the dumps exported by rubick or kubectl.
+// Lists (like: kind: List) are expanded to their items.
+type FileSource struct {
+	resources []objects.StructuredObject
+	// Skipped are errors of the skipped malformed documents, when options.SkipInvalid is true
+	Skipped []error
+	options objects.YAMLsOptions
+}
+
+// Open open path as a source, path is "-" for stdin, a directory, an archive (.zip, .tar, .tar.gz, .tgz)
+// or a yaml/json file.
+func Open(path string, options objects.YAMLsOptions) (*FileSource, error) {
+	if path == Stdin {
+		return NewStdinSource(os.Stdin, options)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case info.IsDir():
+		return NewDirSource(path, options)
+	case isArchive(path):
+		return NewArchiveSource(path, options)
+	default:
+		return NewFileSource(path, options)
+	}
+}
+
+// NewFileSource create a source of a yaml or json file.
+func NewFileSource(path string, options objects.YAMLsOptions) (*FileSource, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &FileSource{options: options}
+	if err := s.add(path, string(content)); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewDirSource create a source of all yaml and json files in dir recursively, files are read in lexical order.
+func NewDirSource(dir string, options objects.YAMLsOptions) (*FileSource, error) {
+	s := &FileSource{options: options}
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !isResourceFile(path) {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return s.add(path, string(content))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewStdinSource create a source of yaml read from reader.
+func NewStdinSource(reader io.Reader, options objects.YAMLsOptions) (*FileSource, error) {
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	s := &FileSource{options: options}
+	if err := s.add("stdin", string(content)); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewArchiveSource create a source of all yaml and json files in a zip or tar archive, files are read in
+// lexical order.
+func NewArchiveSource(path string, options objects.YAMLsOptions) (*FileSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var files map[string]string
+	if strings.HasSuffix(strings.ToLower(path), ".zip") {
+		files, err = common.Unzip(data)
+	} else {
+		files, err = untar(path, data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+
+	var names []string
+	for name := range files {
+		if isResourceFile(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	s := &FileSource{options: options}
+	for _, name := range names {
+		if err := s.add(path+"/"+name, files[name]); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *FileSource) GetResources(ctx context.Context, resourceType string) ([]objects.StructuredObject, error) {
+	var result []objects.StructuredObject
+	for _, resource := range s.resources {
+		if MatchResourceType(resource, resourceType) {
+			result = append(result, resource)
+		}
+	}
+	return result, nil
+}
+
+// GetAllResources get resources selected by options, resources without namespace are cluster scoped.
+func (s *FileSource) GetAllResources(ctx context.Context, options DiscoveryOptions) ([]objects.StructuredObject, error) {
+	var result []objects.StructuredObject
+	for _, resource := range s.resources {
+		if options.selectedResource(resource) {
+			result = append(result, resource)
+		}
+	}
+	return result, nil
+}
+
+// add decode resources of a file, errors are returned with the name of file.
+func (s *FileSource) add(name, content string) error {
+	var resources []objects.StructuredObject
+	if strings.HasSuffix(strings.ToLower(name), ".json") {
+		resource, err := objects.FromJSON(content)
+		if err != nil {
+			return fmt.Errorf("%v: %w", name, err)
+		}
+		resources = append(resources, resource)
+	} else {
+		decoded, skipped, err := objects.FromYAMLsWithOptions(content, s.options)
+		if err != nil {
+			return fmt.Errorf("%v: %w", name, err)
+		}
+		for _, documentError := range skipped {
+			s.Skipped = append(s.Skipped, fmt.Errorf("%v: %w", name, documentError))
+		}
+		resources = decoded
+	}
+
+	for _, resource := range resources {
+		items, err := listItems(resource)
+		if err != nil {
+			return fmt.Errorf("%v: %w", name, err)
+		}
+		s.resources = append(s.resources, items...)
+	}
+	return nil
+}
+
+// listItems returns items of list like: kind: List, kind: DeploymentList, other resources are returned as is
+func listItems(resource objects.StructuredObject) ([]objects.StructuredObject, error) {
+	kind, _ := resource.GetString("kind")
+	if !strings.HasSuffix(kind, "List") {
+		return []objects.StructuredObject{resource}, nil
+	}
+	if _, err := resource.Get("items"); err != nil {
+		return []objects.StructuredObject{resource}, nil
+	}
+	return resource.GetObjects("items")
+}
+
+func untar(path string, data []byte) (map[string]string, error) {
+	var reader io.Reader = bytes.NewReader(data)
+	if lower := strings.ToLower(path); strings.HasSuffix(lower, ".gz") || strings.HasSuffix(lower, ".tgz") {
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = gzipReader.Close() }()
+		reader = gzipReader
+	}
+
+	result := map[string]string{}
+	tarReader := tar.NewReader(reader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		fileBytes, err := io.ReadAll(tarReader)
+		if err != nil {
+			return nil, err
+		}
+		result[header.Name] = string(fileBytes)
+	}
+}
+
+func isArchive(path string) bool {
+	lower := strings.ToLower(path)
+	for _, suffix := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isResourceFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,88 @@
+package source
+
+import (
+	"context"
+	"strings"
+
+	"github.com/storm-blue/rubick/pkg/modifier/objects"
+)
+
+// Source provides resources for exec and modify.
+type Source interface {
+	// GetResources get resources of resourceType like: deployment, deployments, deploy, deployments.apps,
+	// blank resourceType means all resources.
+	GetResources(ctx context.Context, resourceType string) ([]objects.StructuredObject, error)
+	// GetAllResources get resources of all resource types which are selected by options.
+	GetAllResources(ctx context.Context, options DiscoveryOptions) ([]objects.StructuredObject, error)
+}
+
+// shortNames are the short names of builtin resource types, like kubectl
+var shortNames = map[string]string{
+	"cj":     "cronjob",
+	"cm":     "configmap",
+	"crd":    "customresourcedefinition",
+	"csr":    "certificatesigningrequest",
+	"deploy": "deployment",
+	"ds":     "daemonset",
+	"ep":     "endpoints",
+	"ev":     "event",
+	"hpa":    "horizontalpodautoscaler",
+	"ing":    "ingress",
+	"limits": "limitrange",
+	"netpol": "networkpolicy",
+	"no":     "node",
+	"ns":     "namespace",
+	"pc":     "priorityclass",
+	"pdb":    "poddisruptionbudget",
+	"po":     "pod",
+	"pv":     "persistentvolume",
+	"pvc":    "persistentvolumeclaim",
+	"quota":  "resourcequota",
+	"rc":     "replicationcontroller",
+	"rs":     "replicaset",
+	"sa":     "serviceaccount",
+	"sc":     "storageclass",
+	"sts":    "statefulset",
+	"svc":    "service",
+}
+
+// MatchResourceType check whether object is a resource of resourceType by kind and apiVersion of object,
+// resourceType is the singular, plural or short name of kind, with an optional group like: deployments.apps
+func MatchResourceType(object objects.StructuredObject, resourceType string) bool {
+	if resourceType == "" {
+		return true
+	}
+
+	name, group, _ := strings.Cut(strings.ToLower(resourceType), ".")
+	if group != "" {
+		apiVersion, _ := object.GetString("apiVersion")
+		objectGroup, _, found := strings.Cut(apiVersion, "/")
+		if !found {
+			// core group like: v1
+			objectGroup = ""
+		}
+		if group != strings.ToLower(objectGroup) {
+			return false
+		}
+	}
+
+	kind, err := object.GetString("kind")
+	if err != nil {
+		return false
+	}
+	kind = strings.ToLower(kind)
+	return name == kind || name == plural(kind) || shortNames[name] == kind
+}
+
+// plural returns plural of lower case kind like: services, ingresses, networkpolicies
+func plural(kind string) string {
+	switch {
+	case strings.HasSuffix(kind, "s"), strings.HasSuffix(kind, "x"), strings.HasSuffix(kind, "ch"),
+		strings.HasSuffix(kind, "sh"):
+		return kind + "es"
+	case len(kind) > 1 && strings.HasSuffix(kind, "y") && !strings.ContainsRune("aeiou", rune(kind[len(kind)-2])):
+		return kind[:len(kind)-1] + "ies"
+	default:
+		return kind + "s"
+	}
+}
@@ -0,0 +1,270 @@
+package source
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/storm-blue/rubick/pkg/common"
+	"github.com/storm-blue/rubick/pkg/modifier/objects"
+)
+
+var testFiles = map[string]string{
+	"a/deployments.yaml": "kind: Deployment\napiVersion: apps/v1\nmetadata: {name: app-a}\n---\nkind: Deployment\napiVersion: apps/v1\nmetadata: {name: app-b}\n",
+	"a/b/services.yml":   "kind: List\nitems:\n- kind: Service\n  apiVersion: v1\n  metadata: {name: svc-a}\n",
+	"c/ingress.json":     `{"kind": "Ingress", "apiVersion": "networking.k8s.io/v1", "metadata": {"name": "ing-a"}}`,
+	"c/README.md":        "# not resources",
+}
+
+func resourceNames(t *testing.T, source Source, resourceType string) []string {
+	resources, err := source.GetResources(context.Background(), resourceType)
+	if err != nil {
+		t.Fatalf("GetResources() error = %v", err)
+	}
+	var names []string
+	for _, resource := range resources {
+		name, _ := resource.GetString("metadata.name")
+		names = append(names, name)
+	}
+	return names
+}
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func writeTarGz(t *testing.T, files map[string]string) string {
+	buf := &bytes.Buffer{}
+	gzipWriter := gzip.NewWriter(buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for name, content := range files {
+		if err := tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tarWriter.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "dump.tar.gz")
+	if err := os.WriteFile(path, buf.Bytes(), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func writeZip(t *testing.T, files map[string]string) string {
+	data, err := common.Zip(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "dump.zip")
+	if err := os.WriteFile(path, data, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name string
+		path func(t *testing.T) string
+		want map[string][]string
+	}{
+		{
+			name: "TEST_FILE",
+			path: func(t *testing.T) string {
+				return filepath.Join(writeFiles(t, testFiles), "a/deployments.yaml")
+			},
+			want: map[string][]string{"": {"app-a", "app-b"}, "deploy": {"app-a", "app-b"}, "service": nil},
+		},
+		{
+			name: "TEST_DIR",
+			path: func(t *testing.T) string {
+				return writeFiles(t, testFiles)
+			},
+			want: map[string][]string{
+				"":                          {"svc-a", "app-a", "app-b", "ing-a"},
+				"deployments.apps":          {"app-a", "app-b"},
+				"svc":                       {"svc-a"},
+				"ingresses":                 {"ing-a"},
+				"ingress.networking.k8s.io": {"ing-a"},
+				"services.apps":             nil,
+			},
+		},
+		{
+			name: "TEST_ZIP",
+			path: func(t *testing.T) string {
+				return writeZip(t, testFiles)
+			},
+			want: map[string][]string{"": {"svc-a", "app-a", "app-b", "ing-a"}, "service": {"svc-a"}},
+		},
+		{
+			name: "TEST_TAR",
+			path: func(t *testing.T) string {
+				return writeTarGz(t, testFiles)
+			},
+			want: map[string][]string{"": {"svc-a", "app-a", "app-b", "ing-a"}, "deployment": {"app-a", "app-b"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := Open(tt.path(t), objects.YAMLsOptions{})
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			for resourceType, want := range tt.want {
+				if got := resourceNames(t, source, resourceType); !reflect.DeepEqual(got, want) {
+					t.Errorf("GetResources(%v) got = %v, want %v", resourceType, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewStdinSource(t *testing.T) {
+	input := "kind: Service\nmetadata: {name: svc-a}\n---\nkind: Service\nmetadata: {name: [svc-b}\n"
+	if _, err := NewStdinSource(strings.NewReader(input), objects.YAMLsOptions{}); err == nil ||
+		!strings.HasPrefix(err.Error(), "stdin: document 1 (line 4)") {
+		t.Errorf("NewStdinSource() error = %v, want error of document 1", err)
+	}
+
+	source, err := NewStdinSource(strings.NewReader(input), objects.YAMLsOptions{SkipInvalid: true})
+	if err != nil {
+		t.Fatalf("NewStdinSource() error = %v", err)
+	}
+	if got := resourceNames(t, source, "services"); !reflect.DeepEqual(got, []string{"svc-a"}) {
+		t.Errorf("GetResources() got = %v, want [svc-a]", got)
+	}
+	if len(source.Skipped) != 1 {
+		t.Errorf("NewStdinSource() skipped = %v, want 1 error", source.Skipped)
+	}
+}
+
+func TestMatchResourceType(t *testing.T) {
+	tests := []struct {
+		name         string
+		yaml         string
+		resourceType string
+		want         bool
+	}{
+		{name: "TEST_KIND", yaml: "kind: NetworkPolicy", resourceType: "networkpolicy", want: true},
+		{name: "TEST_PLURAL", yaml: "kind: NetworkPolicy", resourceType: "NetworkPolicies", want: true},
+		{name: "TEST_SHORT_NAME", yaml: "kind: NetworkPolicy", resourceType: "netpol", want: true},
+		{name: "TEST_GATEWAY", yaml: "kind: Gateway", resourceType: "gateways", want: true},
+		{name: "TEST_ENDPOINTS", yaml: "kind: Endpoints\napiVersion: v1", resourceType: "endpoints", want: true},
+		{name: "TEST_GROUP", yaml: "kind: Deployment\napiVersion: apps/v1", resourceType: "deployments.apps", want: true},
+		{name: "TEST_CORE_GROUP", yaml: "kind: Service\napiVersion: v1", resourceType: "services.apps", want: false},
+		{name: "TEST_OTHER_KIND", yaml: "kind: Service", resourceType: "deployment", want: false},
+		{name: "TEST_NO_KIND", yaml: "a: b", resourceType: "deployment", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			object, err := objects.FromYAML(tt.yaml)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := MatchResourceType(object, tt.resourceType); got != tt.want {
+				t.Errorf("MatchResourceType() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileSource_GetAllResources(t *testing.T) {
+	input := `kind: Deployment
+apiVersion: apps/v1
+metadata: {name: app-a, namespace: dev}
+---
+kind: Event
+apiVersion: v1
+metadata: {name: event-a, namespace: dev}
+---
+kind: PodMetrics
+apiVersion: metrics.k8s.io/v1beta1
+metadata: {name: pod-a, namespace: dev}
+---
+kind: Namespace
+apiVersion: v1
+metadata: {name: dev}
+`
+	source, err := NewStdinSource(strings.NewReader(input), objects.YAMLsOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		options DiscoveryOptions
+		want    []string
+	}{
+		{
+			name: "TEST_DEFAULT",
+			want: []string{"app-a"},
+		},
+		{
+			name:    "TEST_CLUSTER_SCOPED",
+			options: DiscoveryOptions{ClusterScoped: true},
+			want:    []string{"app-a", "dev"},
+		},
+		{
+			name:    "TEST_INCLUDE",
+			options: DiscoveryOptions{Include: []string{"events", "metrics.k8s.io"}},
+			want:    []string{"event-a", "pod-a"},
+		},
+		{
+			name:    "TEST_EXCLUDE",
+			options: DiscoveryOptions{Exclude: []string{"deploy"}},
+			want:    nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resources, err := source.GetAllResources(context.Background(), tt.options)
+			if err != nil {
+				t.Fatalf("GetAllResources() error = %v", err)
+			}
+			var got []string
+			for _, resource := range resources {
+				name, _ := resource.GetString("metadata.name")
+				got = append(got, name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetAllResources() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResourceKey(t *testing.T) {
+	a, _ := objects.FromYAML("apiVersion: apps/v1\nkind: Deployment\nmetadata: {name: a, namespace: dev}")
+	b, _ := objects.FromYAML("apiVersion: apps/v1beta1\nkind: Deployment\nmetadata: {name: a, namespace: dev}")
+	c, _ := objects.FromYAML("apiVersion: apps/v1\nkind: Deployment\nmetadata: {name: a, namespace: prod}")
+	if ResourceKey(a) != ResourceKey(b) {
+		t.Errorf("ResourceKey() of different versions should be the same: %v, %v", ResourceKey(a), ResourceKey(b))
+	}
+	if ResourceKey(a) == ResourceKey(c) {
+		t.Errorf("ResourceKey() of different namespaces should not be the same: %v", ResourceKey(a))
+	}
+}
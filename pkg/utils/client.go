@@ -0,0 +1,232 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/storm-blue/rubick/pkg/common"
+	"github.com/storm-blue/rubick/pkg/log"
+	"github.com/storm-blue/rubick/pkg/modifier/objects"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// listPageSize is the number of resources got by a list request, large lists are got page by page.
+const listPageSize = 500
+
+var namespacesResource = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+
+// clientSource get resources by client-go dynamic client, resource types are resolved by api discovery,
+// so that short names (like: deploy, svc) and custom resources can be used like kubectl.
+type clientSource struct {
+	client    dynamic.Interface
+	discovery discovery.DiscoveryInterface
+	mapper    meta.RESTMapper
+	namespace string
+	workers   int
+	requests  *common.Semaphore
+}
+
+func newClientSource(options ClusterOptions) (*clientSource, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if kubeconfig := strings.TrimSpace(options.Kubeconfig); kubeconfig != "" {
+		loadingRules.ExplicitPath = kubeconfig
+	}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: options.Context}
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("load kubeconfig error: %w", err)
+	}
+	if options.QPS > 0 {
+		// dynamic and discovery clients share the token bucket, so that QPS is the limit of the cluster
+		config.RateLimiter = flowcontrol.NewTokenBucketRateLimiter(options.QPS, options.burst())
+	}
+
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("create dynamic client error: %w", err)
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("create discovery client error: %w", err)
+	}
+	cachedClient := memory.NewMemCacheClient(discoveryClient)
+	mapper := restmapper.NewShortcutExpander(restmapper.NewDeferredDiscoveryRESTMapper(cachedClient), cachedClient, nil)
+
+	return &clientSource{
+		client:    client,
+		discovery: cachedClient,
+		mapper:    mapper,
+		namespace: options.Namespace,
+		workers:   options.Workers,
+		requests:  options.Requests,
+	}, nil
+}
+
+func (s *clientSource) GetResources(ctx context.Context, namespaces []string, resourceType string) ([]objects.StructuredObject, error) {
+	mapping, err := s.mapping(ctx, resourceType)
+	if err != nil {
+		return nil, err
+	}
+
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		// namespaces are ignored for cluster scoped resources like kubectl
+		return s.list(ctx, s.client.Resource(mapping.Resource), resourceType)
+	}
+
+	if len(namespaces) == 0 {
+		namespaces = []string{s.namespace}
+	}
+	results, err := common.Parallel(ctx, s.workers, len(namespaces), func(ctx context.Context, i int) ([]objects.StructuredObject, error) {
+		return s.list(ctx, s.client.Resource(mapping.Resource).Namespace(namespaces[i]), resourceType)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return slices.Concat(results...), nil
+}
+
+func (s *clientSource) GetAllNamespaces(ctx context.Context) ([]string, error) {
+	resources, err := s.list(ctx, s.client.Resource(namespacesResource), "namespaces")
+	if err != nil {
+		return nil, err
+	}
+
+	var namespaces []string
+	for _, resource := range resources {
+		name, err := resource.GetString("metadata.name")
+		if err != nil {
+			return nil, err
+		}
+		namespaces = append(namespaces, name)
+	}
+	return namespaces, nil
+}
+
+func (s *clientSource) GetYAML(ctx context.Context, namespace, resourceType, resourceName string) (string, error) {
+	mapping, err := s.mapping(ctx, resourceType)
+	if err != nil {
+		return "", err
+	}
+
+	var resource dynamic.ResourceInterface = s.client.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		resource = s.client.Resource(mapping.Resource).Namespace(namespace)
+	}
+	var item *unstructured.Unstructured
+	err = s.requests.Do(ctx, func() (err error) {
+		item, err = resource.Get(ctx, resourceName, metav1.GetOptions{})
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("get %v %v error: %w", resourceType, resourceName, err)
+	}
+
+	object, err := fromUnstructured(item)
+	if err != nil {
+		return "", err
+	}
+	return object.ToYAML()
+}
+
+func (s *clientSource) GetResourceTypes(ctx context.Context, clusterScoped bool) ([]string, error) {
+	var lists []*metav1.APIResourceList
+	err := s.requests.Do(ctx, func() (err error) {
+		lists, err = discovery.ServerPreferredResources(s.discovery)
+		return err
+	})
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		if !discovery.IsGroupDiscoveryFailedError(err) {
+			return nil, fmt.Errorf("discover resource types error: %w", err)
+		}
+		// unavailable aggregated apis (like: metrics.k8s.io) should not stop discovering other resource types
+		log.Warnf("discover resource types error: %v", err)
+	}
+
+	var resourceTypes []string
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			return nil, fmt.Errorf("discover resource types error: %w", err)
+		}
+		for _, resource := range list.APIResources {
+			if !slices.Contains(resource.Verbs, "list") || (!resource.Namespaced && !clusterScoped) {
+				continue
+			}
+			resourceType := resource.Name
+			if gv.Group != "" {
+				resourceType += "." + gv.Group
+			}
+			resourceTypes = append(resourceTypes, resourceType)
+		}
+	}
+	sort.Strings(resourceTypes)
+	return slices.Compact(resourceTypes), nil
+}
+
+// mapping resolve resource type like: deployment, deployments, deploy, deployments.apps, the mapper may
+// discover resource types from cluster, so it is a request too.
+func (s *clientSource) mapping(ctx context.Context, resourceType string) (mapping *meta.RESTMapping, err error) {
+	err = s.requests.Do(ctx, func() error {
+		gvr, err := s.mapper.ResourceFor(schema.ParseGroupResource(resourceType).WithVersion(""))
+		if err != nil {
+			return fmt.Errorf("unknown resource type %v: %w", resourceType, err)
+		}
+		gvk, err := s.mapper.KindFor(gvr)
+		if err != nil {
+			return fmt.Errorf("unknown resource type %v: %w", resourceType, err)
+		}
+		mapping, err = s.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		return err
+	})
+	return mapping, err
+}
+
+func (s *clientSource) list(ctx context.Context, resource dynamic.ResourceInterface, resourceType string) ([]objects.StructuredObject, error) {
+	var result []objects.StructuredObject
+	options := metav1.ListOptions{Limit: listPageSize}
+	for {
+		var list *unstructured.UnstructuredList
+		err := s.requests.Do(ctx, func() (err error) {
+			list, err = resource.List(ctx, options)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("list %v error: %w", resourceType, err)
+		}
+		for i := range list.Items {
+			object, err := fromUnstructured(&list.Items[i])
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, object)
+		}
+		if list.GetContinue() == "" {
+			return result, nil
+		}
+		options.Continue = list.GetContinue()
+	}
+}
+
+func fromUnstructured(item *unstructured.Unstructured) (objects.StructuredObject, error) {
+	jsonBytes, err := json.Marshal(item.Object)
+	if err != nil {
+		return nil, err
+	}
+	return objects.FromJSON(string(jsonBytes))
+}
@@ -0,0 +1,195 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var (
+	deploymentKind = schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	namespaceKind  = schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}
+)
+
+func newFakeClientSource(namespace string, resources ...runtime.Object) (*clientSource, *dynamicfake.FakeDynamicClient) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(deploymentKind, meta.RESTScopeNamespace)
+	mapper.Add(namespaceKind, meta.RESTScopeRoot)
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		{Group: "apps", Version: "v1", Resource: "deployments"}: "DeploymentList",
+		namespacesResource: "NamespaceList",
+	}, resources...)
+	discovery := &fakediscovery.FakeDiscovery{Fake: &k8stesting.Fake{Resources: []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "namespaces", Namespaced: false, Kind: "Namespace", Verbs: []string{"get", "list"}},
+				{Name: "services", Namespaced: true, Kind: "Service", Verbs: []string{"get", "list"}},
+				{Name: "services/status", Namespaced: true, Kind: "Service", Verbs: []string{"get"}},
+				{Name: "bindings", Namespaced: true, Kind: "Binding", Verbs: []string{"create"}},
+			},
+		},
+		{
+			GroupVersion: "apps/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "deployments", Namespaced: true, Kind: "Deployment", Verbs: []string{"get", "list"}},
+			},
+		},
+		{
+			GroupVersion: "apps/v1beta1",
+			APIResources: []metav1.APIResource{
+				{Name: "deployments", Namespaced: true, Kind: "Deployment", Verbs: []string{"get", "list"}},
+			},
+		},
+	}}}
+	return &clientSource{client: client, discovery: discovery, mapper: mapper, namespace: namespace}, client
+}
+
+func newFakeResource(kind schema.GroupVersionKind, namespace, name string) *unstructured.Unstructured {
+	resource := &unstructured.Unstructured{}
+	resource.SetGroupVersionKind(kind)
+	resource.SetNamespace(namespace)
+	resource.SetName(name)
+	return resource
+}
+
+func resourceNames(t *testing.T, source ResourceSource, namespaces []string, resourceType string) []string {
+	resources, err := source.GetResources(context.Background(), namespaces, resourceType)
+	if err != nil {
+		t.Fatalf("GetResources() error = %v", err)
+	}
+	var names []string
+	for _, resource := range resources {
+		namespace, _ := resource.GetString("metadata.namespace")
+		name, _ := resource.GetString("metadata.name")
+		names = append(names, namespace+"/"+name)
+	}
+	return names
+}
+
+func Test_clientSource_GetResources(t *testing.T) {
+	resources := []runtime.Object{
+		newFakeResource(deploymentKind, "dev", "app-a"),
+		newFakeResource(deploymentKind, "dev", "app-b"),
+		newFakeResource(deploymentKind, "prod", "app-a"),
+		newFakeResource(namespaceKind, "", "dev"),
+		newFakeResource(namespaceKind, "", "prod"),
+	}
+
+	tests := []struct {
+		name             string
+		defaultNamespace string
+		namespaces       []string
+		resourceType     string
+		want             []string
+	}{
+		{
+			name:         "TEST_ALL_NAMESPACES",
+			resourceType: "deployment",
+			want:         []string{"dev/app-a", "dev/app-b", "prod/app-a"},
+		},
+		{
+			name:         "TEST_NAMESPACES",
+			namespaces:   []string{"prod", "dev"},
+			resourceType: "deployments.apps",
+			want:         []string{"prod/app-a", "dev/app-a", "dev/app-b"},
+		},
+		{
+			name:             "TEST_DEFAULT_NAMESPACE",
+			defaultNamespace: "prod",
+			resourceType:     "deployments",
+			want:             []string{"prod/app-a"},
+		},
+		{
+			name:         "TEST_CLUSTER_SCOPED",
+			namespaces:   []string{"dev"},
+			resourceType: "namespace",
+			want:         []string{"/dev", "/prod"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, _ := newFakeClientSource(tt.defaultNamespace, resources...)
+			if got := resourceNames(t, source, tt.namespaces, tt.resourceType); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetResources() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_clientSource_Errors(t *testing.T) {
+	source, client := newFakeClientSource("")
+	if _, err := source.GetResources(context.Background(), nil, "unknown"); err == nil {
+		t.Errorf("GetResources() of unknown resource type should return error")
+	}
+
+	client.PrependReactor("list", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("forbidden")
+	})
+	if _, err := source.GetResources(context.Background(), nil, "deployment"); err == nil || err.Error() != "list deployment error: forbidden" {
+		t.Errorf("GetResources() error = %v, want list deployment error: forbidden", err)
+	}
+}
+
+func Test_clientSource_GetAllNamespaces(t *testing.T) {
+	source, _ := newFakeClientSource("", newFakeResource(namespaceKind, "", "dev"), newFakeResource(namespaceKind, "", "prod"))
+	got, err := source.GetAllNamespaces(context.Background())
+	if err != nil {
+		t.Fatalf("GetAllNamespaces() error = %v", err)
+	}
+	if want := []string{"dev", "prod"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetAllNamespaces() got = %v, want %v", got, want)
+	}
+}
+
+func Test_clientSource_GetYAML(t *testing.T) {
+	source, _ := newFakeClientSource("", newFakeResource(deploymentKind, "dev", "app-a"))
+	got, err := source.GetYAML(context.Background(), "dev", "deployment", "app-a")
+	if err != nil {
+		t.Fatalf("GetYAML() error = %v", err)
+	}
+	if want := "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: app-a\n  namespace: dev\n"; got != want {
+		t.Errorf("GetYAML() got = %v, want %v", got, want)
+	}
+}
+
+func Test_clientSource_GetResourceTypes(t *testing.T) {
+	source, _ := newFakeClientSource("")
+	tests := []struct {
+		name          string
+		clusterScoped bool
+		want          []string
+	}{
+		{
+			name: "TEST_NAMESPACED",
+			want: []string{"deployments.apps", "services"},
+		},
+		{
+			name:          "TEST_CLUSTER_SCOPED",
+			clusterScoped: true,
+			want:          []string{"deployments.apps", "namespaces", "services"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := source.GetResourceTypes(context.Background(), tt.clusterScoped)
+			if err != nil {
+				t.Fatalf("GetResourceTypes() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetResourceTypes() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/storm-blue/rubick/pkg/common"
+	"github.com/storm-blue/rubick/pkg/modifier/objects"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// kubectlSource get resources by executing kubectl, errors are reported by the exit code and stderr of kubectl.
+type kubectlSource struct {
+	command string
+	options ClusterOptions
+	// limiter limits the executions of kubectl by options.QPS, it is nil if there is no limit
+	limiter flowcontrol.RateLimiter
+}
+
+func newKubectlSource(options ClusterOptions) *kubectlSource {
+	s := &kubectlSource{command: "kubectl", options: options}
+	if options.QPS > 0 {
+		s.limiter = flowcontrol.NewTokenBucketRateLimiter(options.QPS, options.burst())
+	}
+	return s
+}
+
+func (s *kubectlSource) GetResources(ctx context.Context, namespaces []string, resourceType string) ([]objects.StructuredObject, error) {
+	if len(namespaces) == 0 && s.options.Namespace == "" {
+		return s.getItems(ctx, "get", resourceType, "-A", "-o", "yaml")
+	}
+
+	if len(namespaces) == 0 {
+		namespaces = []string{s.options.Namespace}
+	}
+	results, err := common.Parallel(ctx, s.options.Workers, len(namespaces), func(ctx context.Context, i int) ([]objects.StructuredObject, error) {
+		return s.getItems(ctx, "-n", namespaces[i], "get", resourceType, "-o", "yaml")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return slices.Concat(results...), nil
+}
+
+func (s *kubectlSource) GetAllNamespaces(ctx context.Context) ([]string, error) {
+	output, err := s.run(ctx, "get", "namespaces", "-o", "name")
+	if err != nil {
+		return nil, err
+	}
+
+	var namespaces []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			namespaces = append(namespaces, strings.TrimPrefix(line, "namespace/"))
+		}
+	}
+	return namespaces, nil
+}
+
+func (s *kubectlSource) GetYAML(ctx context.Context, namespace, resourceType, resourceName string) (string, error) {
+	var arguments []string
+	if namespace != "" {
+		arguments = append(arguments, "-n", namespace)
+	}
+	output, err := s.run(ctx, append(arguments, "get", resourceType, resourceName, "-o", "yaml")...)
+	if err != nil {
+		return "", err
+	}
+	return string(output), nil
+}
+
+func (s *kubectlSource) GetResourceTypes(ctx context.Context, clusterScoped bool) ([]string, error) {
+	arguments := []string{"api-resources", "--verbs=list", "-o", "name"}
+	if !clusterScoped {
+		arguments = append(arguments, "--namespaced=true")
+	}
+	output, err := s.run(ctx, arguments...)
+	if err != nil {
+		return nil, err
+	}
+
+	var resourceTypes []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			resourceTypes = append(resourceTypes, line)
+		}
+	}
+	sort.Strings(resourceTypes)
+	return resourceTypes, nil
+}
+
+// getItems get the items of list which is output by kubectl
+func (s *kubectlSource) getItems(ctx context.Context, arguments ...string) ([]objects.StructuredObject, error) {
+	output, err := s.run(ctx, arguments...)
+	if err != nil {
+		return nil, err
+	}
+
+	o, err := objects.FromYAML(string(output))
+	if err != nil {
+		return nil, fmt.Errorf("parse output of kubectl %v error: %w", strings.Join(arguments, " "), err)
+	}
+	return o.GetObjects("items")
+}
+
+func (s *kubectlSource) run(ctx context.Context, arguments ...string) ([]byte, error) {
+	if s.limiter != nil {
+		if err := s.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	var globalArguments []string
+	if kubeconfig := strings.TrimSpace(s.options.Kubeconfig); kubeconfig != "" {
+		globalArguments = append(globalArguments, "--kubeconfig="+kubeconfig)
+	}
+	if s.options.Context != "" {
+		globalArguments = append(globalArguments, "--context="+s.options.Context)
+	}
+
+	cmd := exec.CommandContext(ctx, s.command, append(globalArguments, arguments...)...)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	var output []byte
+	err := s.options.Requests.Do(ctx, func() (err error) {
+		output, err = cmd.Output()
+		return err
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			// kubectl is killed
+			return nil, ctx.Err()
+		}
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("kubectl %v error: %v", strings.Join(arguments, " "), message)
+		}
+		return nil, fmt.Errorf("kubectl %v error: %w", strings.Join(arguments, " "), err)
+	}
+	return output, nil
+}
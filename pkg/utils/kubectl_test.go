@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/storm-blue/rubick/pkg/common"
+)
+
+// newFakeKubectlSource create a source which executes a shell script as kubectl
+func newFakeKubectlSource(t *testing.T, script string, options ClusterOptions) *kubectlSource {
+	command := filepath.Join(t.TempDir(), "kubectl")
+	if err := os.WriteFile(command, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return &kubectlSource{command: command, options: options}
+}
+
+func Test_kubectlSource_GetResources(t *testing.T) {
+	tests := []struct {
+		name       string
+		script     string
+		options    ClusterOptions
+		namespaces []string
+		want       []string
+		wantErr    string
+	}{
+		{
+			name: "TEST_ALL_NAMESPACES",
+			script: `[ "$*" = "--kubeconfig=/tmp/config --context=dev get deployment -A -o yaml" ] || exit 1
+echo "kind: List
+items:
+- metadata: {namespace: dev, name: app-a}
+- metadata: {namespace: prod, name: app-a}"`,
+			options: ClusterOptions{Kubeconfig: "/tmp/config", Context: "dev"},
+			want:    []string{"dev/app-a", "prod/app-a"},
+		},
+		{
+			name: "TEST_DEFAULT_NAMESPACE",
+			script: `[ "$*" = "-n prod get deployment -o yaml" ] || exit 1
+echo "items: [{metadata: {namespace: prod, name: app-a}}]"`,
+			options: ClusterOptions{Namespace: "prod"},
+			want:    []string{"prod/app-a"},
+		},
+		{
+			name: "TEST_WORKERS",
+			script: `[ "$2" = "dev" ] && sleep 0.2
+echo "items: [{metadata: {namespace: $2, name: app-a}}]"`,
+			options:    ClusterOptions{Workers: 3},
+			namespaces: []string{"dev", "prod", "test"},
+			want:       []string{"dev/app-a", "prod/app-a", "test/app-a"},
+		},
+		{
+			name: "TEST_REQUESTS",
+			script: `mkdir "$(dirname "$0")/lock" || exit 1
+sleep 0.05
+rmdir "$(dirname "$0")/lock"
+echo "items: [{metadata: {namespace: $2, name: app-a}}]"`,
+			options:    ClusterOptions{Workers: 3, Requests: common.NewSemaphore(1)},
+			namespaces: []string{"dev", "prod", "test"},
+			want:       []string{"dev/app-a", "prod/app-a", "test/app-a"},
+		},
+		{
+			name: "TEST_ERROR",
+			script: `echo 'Error from server (Forbidden): deployments.apps is forbidden' >&2
+exit 1`,
+			namespaces: []string{"dev"},
+			wantErr:    "kubectl -n dev get deployment -o yaml error: Error from server (Forbidden): deployments.apps is forbidden",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := newFakeKubectlSource(t, tt.script, tt.options)
+			if tt.wantErr != "" {
+				if _, err := source.GetResources(context.Background(), tt.namespaces, "deployment"); err == nil || err.Error() != tt.wantErr {
+					t.Errorf("GetResources() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if got := resourceNames(t, source, tt.namespaces, "deployment"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetResources() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_kubectlSource_Canceled(t *testing.T) {
+	source := newFakeKubectlSource(t, `exec sleep 10`, ClusterOptions{Workers: 2})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := source.GetResources(ctx, []string{"dev", "prod"}, "deployment"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetResources() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func Test_kubectlSource_GetAllNamespaces(t *testing.T) {
+	source := newFakeKubectlSource(t, `printf 'namespace/dev\nnamespace/prod\n'`, ClusterOptions{})
+	got, err := source.GetAllNamespaces(context.Background())
+	if err != nil {
+		t.Fatalf("GetAllNamespaces() error = %v", err)
+	}
+	if want := []string{"dev", "prod"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetAllNamespaces() got = %v, want %v", got, want)
+	}
+}
+
+func Test_kubectlSource_GetResourceTypes(t *testing.T) {
+	source := newFakeKubectlSource(t, `[ "$*" = "api-resources --verbs=list -o name --namespaced=true" ] || exit 1
+printf 'services\ndeployments.apps\n'`, ClusterOptions{})
+	got, err := source.GetResourceTypes(context.Background(), false)
+	if err != nil {
+		t.Fatalf("GetResourceTypes() error = %v", err)
+	}
+	if want := []string{"deployments.apps", "services"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetResourceTypes() got = %v, want %v", got, want)
+	}
+}
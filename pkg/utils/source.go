@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"context"
+	"os/exec"
+
+	"github.com/storm-blue/rubick/pkg/common"
+	"github.com/storm-blue/rubick/pkg/log"
+	"github.com/storm-blue/rubick/pkg/modifier/objects"
+)
+
+// ResourceSource get resources from a k8s cluster.
+type ResourceSource interface {
+	// GetResources get resources of resourceType from namespaces, blank namespaces means the default namespace
+	// of source, or all namespaces if the source has no default namespace.
+	GetResources(ctx context.Context, namespaces []string, resourceType string) ([]objects.StructuredObject, error)
+	// GetAllNamespaces get names of all namespaces.
+	GetAllNamespaces(ctx context.Context) ([]string, error)
+	// GetYAML get yaml of a resource, blank namespace means the resource is cluster scoped.
+	GetYAML(ctx context.Context, namespace, resourceType, resourceName string) (string, error)
+	// GetResourceTypes discover all listable resource types like: services, deployments.apps, the namespaced
+	// resource types are returned, and cluster scoped resource types if clusterScoped is true.
+	GetResourceTypes(ctx context.Context, clusterScoped bool) ([]string, error)
+}
+
+// ClusterOptions are options to connect a k8s cluster, blank options are the defaults of kubectl.
+type ClusterOptions struct {
+	// Kubeconfig is the path of kubeconfig file, default: ${KUBECONFIG} or ${HOME}/.kube/config
+	Kubeconfig string
+	// Context is the context in kubeconfig, default: the current context
+	Context string
+	// Namespace is the default namespace of source, default: all namespaces
+	Namespace string
+	// Kubectl get resources by kubectl instead of client-go
+	Kubectl bool
+	// QPS is the max requests per second to the cluster, bursts are allowed up to twice of it,
+	// default: 5 for client-go, no limit for kubectl
+	QPS float32
+	// Workers is the number of goroutines getting resources from several namespaces, default: 1
+	Workers int
+	// Requests limits the concurrent requests, sources sharing it are limited together, default: Workers requests
+	Requests *common.Semaphore
+}
+
+// burst returns the max burst requests of options.QPS
+func (options ClusterOptions) burst() int {
+	return max(1, int(options.QPS*2))
+}
+
+// NewResourceSource create a source by client-go, kubectl is used as a fallback if client-go can not be
+// configured and kubectl is installed.
+func NewResourceSource(options ClusterOptions) (ResourceSource, error) {
+	if options.Requests == nil {
+		options.Requests = common.NewSemaphore(options.Workers)
+	}
+	if options.Kubectl {
+		return newKubectlSource(options), nil
+	}
+
+	source, err := newClientSource(options)
+	if err != nil {
+		if _, lookErr := exec.LookPath("kubectl"); lookErr != nil {
+			return nil, err
+		}
+		log.Warnf("create client error, fallback to kubectl: %v", err)
+		return newKubectlSource(options), nil
+	}
+	return source, nil
+}